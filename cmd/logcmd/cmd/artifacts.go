@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aliancn/logcmd/internal/artifact"
+	"github.com/aliancn/logcmd/internal/executor"
+	"github.com/aliancn/logcmd/internal/history"
+	"github.com/aliancn/logcmd/internal/model"
+	"github.com/spf13/cobra"
+)
+
+var artifactsExtract string
+
+var artifactsCmd = &cobra.Command{
+	Use:   "artifacts <runID|日志路径> [name...]",
+	Short: "查看或取出 run --artifact 收集的产物",
+	Long: `列出一次运行收集的产物及其大小和 SHA-256 校验和。
+
+使用 --extract 将产物复制到指定目录，可通过 name 参数（支持 glob，如 'coverage/*'）
+只取出部分产物；复制前会校验内容，文件已被修改时报错。`,
+	Example: `  logcmd artifacts 42
+  logcmd artifacts 42 --extract ./out
+  logcmd artifacts 42 'dist/*.tar.gz' --extract /tmp/release`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runArtifacts(args[0], args[1:])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(artifactsCmd)
+
+	artifactsCmd.Flags().StringVar(&artifactsExtract, "extract", "", "将产物复制到该目录")
+}
+
+func runArtifacts(arg string, names []string) error {
+	services, err := newCLIServices()
+	if err != nil {
+		return err
+	}
+	defer services.Close()
+
+	manager := history.NewManager(services.Registry().GetDB())
+	record, err := resolveRun(manager, arg)
+	if err != nil {
+		return err
+	}
+
+	list, err := manager.GetArtifacts(record.ID)
+	if err != nil {
+		return err
+	}
+	list = filterArtifacts(list, names)
+	if len(list) == 0 {
+		if len(names) > 0 {
+			return newExitErrorf(1, "运行记录 #%d 没有匹配的产物", record.ID)
+		}
+		fmt.Printf("运行记录 #%d 没有产物（需要以 logcmd run --artifact 运行）\n", record.ID)
+		return nil
+	}
+
+	if artifactsExtract != "" {
+		return extractArtifacts(list, artifactsExtract)
+	}
+
+	var total int64
+	fmt.Printf("运行记录 #%d 的产物 (共%d个):\n\n", record.ID, len(list))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "名称\t大小\tSHA-256\t状态")
+	for _, a := range list {
+		total += a.SizeBytes
+		status := "正常"
+		if _, err := os.Stat(a.StoredPath); err != nil {
+			status = "缺失"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", a.Name, executor.FormatBytes(a.SizeBytes), shortHash(a.SHA256), status)
+	}
+	w.Flush()
+	fmt.Printf("\n总大小: %s\n", executor.FormatBytes(total))
+	return nil
+}
+
+// filterArtifacts 按名称或 glob 筛选产物，names 为空时返回全部
+func filterArtifacts(list []model.Artifact, names []string) []model.Artifact {
+	if len(names) == 0 {
+		return list
+	}
+	var out []model.Artifact
+	for _, a := range list {
+		for _, name := range names {
+			if ok, _ := path.Match(name, a.Name); ok || a.Name == name || strings.HasPrefix(a.Name, strings.TrimSuffix(name, "/")+"/") {
+				out = append(out, a)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func extractArtifacts(list []model.Artifact, outDir string) error {
+	var failed int
+	for _, a := range list {
+		dest, err := artifact.Extract(artifact.Artifact{
+			Name:   a.Name,
+			Path:   a.StoredPath,
+			Size:   a.SizeBytes,
+			SHA256: a.SHA256,
+		}, outDir)
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			continue
+		}
+		fmt.Printf("✓ %s -> %s\n", a.Name, dest)
+	}
+	if failed > 0 {
+		return newExitErrorf(1, "%d 个产物取出失败", failed)
+	}
+	return nil
+}
+
+// shortHash 返回校验和的前 12 位用于显示
+func shortHash(sum string) string {
+	if len(sum) > 12 {
+		return sum[:12]
+	}
+	return sum
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aliancn/logcmd/internal/config"
+	"github.com/aliancn/logcmd/internal/logger"
+	"github.com/aliancn/logcmd/internal/persistence"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// defaultExitCodeEnv --exit-code-from-env 未指定变量名时读取的环境变量
+const defaultExitCodeEnv = "LOGCMD_EXIT_CODE"
+
+var (
+	captureName        string
+	captureExitCodeEnv string
+)
+
+var captureCmd = &cobra.Command{
+	Use:   "capture",
+	Short: "记录通过管道传入的输出",
+	Long: `从标准输入读取输出并记录日志，适用于无法由 logcmd 直接执行的命令，
+如远程 ssh 会话或已有的管道。输出同时回显到标准输出，日志头尾、命令历史和
+项目统计与 run 相同。
+
+上游命令的退出码无法从管道获知，可通过 --exit-code-from-env 从环境变量读取
+（默认 LOGCMD_EXIT_CODE），logcmd capture 会以该退出码结束。`,
+	Example: `  ssh prod ./deploy.sh 2>&1 | logcmd capture --name deploy
+  ./build.sh > build.out 2>&1; LOGCMD_EXIT_CODE=$? logcmd capture --name build --exit-code-from-env < build.out`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCapture(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(captureCmd)
+
+	captureCmd.Flags().StringVar(&captureName, "name", "capture", "记录使用的命令名称，用于日志文件名、命令历史和统计")
+	captureCmd.Flags().StringVar(&captureExitCodeEnv, "exit-code-from-env", "", "从环境变量读取上游命令的退出码（默认 "+defaultExitCodeEnv+"）")
+	captureCmd.Flags().Lookup("exit-code-from-env").NoOptDefVal = defaultExitCodeEnv
+}
+
+func runCapture(cmd *cobra.Command) error {
+	name := strings.TrimSpace(captureName)
+	if name == "" {
+		return newExitErrorf(1, "--name 不能为空")
+	}
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return newExitErrorf(1, "capture 需要通过管道或重定向传入输出，如: some-cmd 2>&1 | logcmd capture --name %s", name)
+	}
+
+	exitCode := 0
+	if captureExitCodeEnv != "" {
+		code, err := exitCodeFromEnv(captureExitCodeEnv)
+		if err != nil {
+			return newExitError(err, 1)
+		}
+		exitCode = code
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	if logDirFlag != "" {
+		cfg.LogDir = logDirFlag
+	}
+
+	services, err := newCLIServices()
+	if err != nil {
+		return err
+	}
+	defer services.Close()
+
+	reg := services.Registry()
+	log, err := logger.New(cfg, persistence.NewRunRepository(reg), persistence.NewStatsUpdater(reg))
+	if err != nil {
+		return fmt.Errorf("创建日志记录器失败: %w", err)
+	}
+
+	// 以上游命令的退出码结束，便于在 set -o pipefail 的脚本中继续判断
+	result, _, err := log.Capture(cmd.Context(), name, os.Stdin, exitCode)
+	if result != nil && !result.Success {
+		return newExitError(nil, result.ShellExitCode())
+	}
+	if err != nil {
+		return fmt.Errorf("记录输出失败: %w", err)
+	}
+	return nil
+}
+
+// exitCodeFromEnv 从指定的环境变量读取退出码
+func exitCodeFromEnv(name string) (int, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return 0, fmt.Errorf("环境变量 %s 未设置", name)
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || code < 0 || code > 255 {
+		return 0, fmt.Errorf("环境变量 %s 不是有效的退出码: %q", name, value)
+	}
+	return code, nil
+}
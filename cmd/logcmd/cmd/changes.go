@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aliancn/logcmd/internal/executor"
+	"github.com/aliancn/logcmd/internal/filetrack"
+	"github.com/aliancn/logcmd/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var changesType string
+
+var changesCmd = &cobra.Command{
+	Use:   "changes <runID|日志路径>",
+	Short: "查看 run --track-files 记录的文件变化",
+	Long: `列出一次运行前后工作目录中被创建、修改和删除的文件。
+
+需要以 logcmd run --track-files[=glob] 运行命令，运行前后会对匹配的文件记录
+大小、修改时间和内容哈希，内容未变化的文件即使修改时间变化也不会列出。`,
+	Example: `  logcmd changes 42
+  logcmd changes 42 --type created`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runChanges(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(changesCmd)
+
+	changesCmd.Flags().StringVar(&changesType, "type", "", "只列出指定类型的变化 (created/modified/deleted)")
+}
+
+func runChanges(arg string) error {
+	switch changesType {
+	case "", filetrack.ChangeCreated, filetrack.ChangeModified, filetrack.ChangeDeleted:
+	default:
+		return newExitErrorf(1, "无效的 --type: %s（可选 created、modified、deleted）", changesType)
+	}
+
+	services, err := newCLIServices()
+	if err != nil {
+		return err
+	}
+	defer services.Close()
+
+	manager := history.NewManager(services.Registry().GetDB())
+	record, err := resolveRun(manager, arg)
+	if err != nil {
+		return err
+	}
+
+	list, err := manager.GetFileChanges(record.ID)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		fmt.Printf("运行记录 #%d 没有文件变化（需要以 logcmd run --track-files 运行）\n", record.ID)
+		return nil
+	}
+
+	changes := make([]filetrack.Change, 0, len(list))
+	for _, item := range list {
+		changes = append(changes, filetrack.Change{Path: item.Path, Type: item.Type})
+	}
+	fmt.Printf("运行记录 #%d 的文件变化 (%s):\n\n", record.ID, filetrack.Summary(changes))
+
+	for _, item := range list {
+		if changesType != "" && item.Type != changesType {
+			continue
+		}
+		var size string
+		switch item.Type {
+		case filetrack.ChangeCreated:
+			size = executor.FormatBytes(item.SizeAfter)
+		case filetrack.ChangeModified:
+			size = fmt.Sprintf("%s -> %s", executor.FormatBytes(item.SizeBefore), executor.FormatBytes(item.SizeAfter))
+		case filetrack.ChangeDeleted:
+			size = executor.FormatBytes(item.SizeBefore)
+		}
+		fmt.Printf("  %s  %s  (%s)\n", filetrack.TypeLabel(item.Type), item.Path, size)
+	}
+	return nil
+}
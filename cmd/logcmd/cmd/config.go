@@ -2,14 +2,21 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
+	"github.com/aliancn/logcmd/internal/charset"
 	"github.com/aliancn/logcmd/internal/config"
+	"github.com/aliancn/logcmd/internal/executor"
+	"github.com/aliancn/logcmd/internal/shellcmd"
 	"github.com/aliancn/logcmd/internal/template"
+	"github.com/aliancn/logcmd/internal/transform"
 	"github.com/spf13/cobra"
 )
 
@@ -29,7 +36,23 @@ var configSetCmd = &cobra.Command{
 	Short: "设置配置项",
 	Example: `  logcmd config set buffer_size 10240
   logcmd config set auto_compress true --global
-  logcmd config set time_format compact`,
+  logcmd config set time_format compact
+  logcmd config set pty true
+  logcmd config set record true
+  logcmd config set raw_log true
+  logcmd config set events true
+  logcmd config set kill_grace_period 30s
+  logcmd config set timeout 30m
+  logcmd config set idle_timeout 5m
+  logcmd config set sample_interval 5s
+  logcmd config set env_deny 'AWS_*,*_TOKEN'
+  logcmd config set redact_patterns '["internal-[0-9a-f]{32}"]'
+  logcmd config set log_transforms strip_ansi,collapse_cr,compact_repeats
+  logcmd config set drop_patterns '["^Downloading "]'
+  logcmd config set input_encoding auto
+  logcmd config set max_log_size 100MB
+  logcmd config set shell /bin/bash
+  logcmd config set output on-failure`,
 	Args: cobra.RangeArgs(1, 2),
 	RunE: runConfigSet,
 }
@@ -170,6 +193,112 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 		cfg.AutoCompress = boolPtr(v)
 	case "time_format":
 		cfg.TimeFormat = val
+	case "pty":
+		v, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("pty 必须是 boolean (true/false): %w", err)
+		}
+		cfg.PTY = boolPtr(v)
+	case "structured_log":
+		v, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("structured_log 必须是 boolean (true/false): %w", err)
+		}
+		cfg.StructuredLog = boolPtr(v)
+	case "record":
+		v, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("record 必须是 boolean (true/false): %w", err)
+		}
+		cfg.Record = boolPtr(v)
+	case "raw_log":
+		v, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("raw_log 必须是 boolean (true/false): %w", err)
+		}
+		cfg.RawLog = boolPtr(v)
+	case "events":
+		v, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("events 必须是 boolean (true/false): %w", err)
+		}
+		cfg.Events = boolPtr(v)
+	case "kill_grace_period":
+		d, err := time.ParseDuration(val)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("kill_grace_period 必须是正的时长 (如 10s, 1m)")
+		}
+		cfg.GracePeriod = d.String()
+	case "timeout":
+		d, err := time.ParseDuration(val)
+		if err != nil || d < 0 {
+			return fmt.Errorf("timeout 必须是时长 (如 30m, 2h)，0 表示不限制")
+		}
+		cfg.Timeout = d.String()
+	case "idle_timeout":
+		d, err := time.ParseDuration(val)
+		if err != nil || d < 0 {
+			return fmt.Errorf("idle_timeout 必须是时长 (如 5m)，0 表示不限制")
+		}
+		cfg.IdleTimeout = d.String()
+	case "sample_interval":
+		d, err := time.ParseDuration(val)
+		if err != nil || d < 0 {
+			return fmt.Errorf("sample_interval 必须是时长 (如 10s)，0 表示前台运行不采样")
+		}
+		cfg.SampleInterval = d.String()
+	case "env_allow":
+		cfg.EnvAllow = splitList(val)
+	case "env_deny":
+		cfg.EnvDeny = splitList(val)
+	case "redact":
+		v, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("redact 必须是 boolean (true/false): %w", err)
+		}
+		cfg.Redact = boolPtr(v)
+	case "redact_patterns":
+		patterns, err := parsePatterns(val)
+		if err != nil {
+			return err
+		}
+		cfg.RedactPatterns = patterns
+	case "log_transforms":
+		names := splitList(val)
+		if _, err := transform.Chain(names, transform.Options{}); err != nil {
+			return err
+		}
+		cfg.LogTransforms = names
+	case "max_log_size", "log_tail_size":
+		n, err := config.ParseSize(val)
+		if err != nil {
+			return fmt.Errorf("%s 参数无效: %w", key, err)
+		}
+		if key == "max_log_size" {
+			cfg.MaxLogSize = formatSize(n)
+		} else {
+			cfg.LogTailSize = formatSize(n)
+		}
+	case "shell":
+		cfg.Shell = val
+	case "input_encoding":
+		name, err := charset.Normalize(val)
+		if err != nil {
+			return err
+		}
+		cfg.InputEncoding = name
+	case "drop_patterns":
+		patterns, err := parsePatterns(val)
+		if err != nil {
+			return err
+		}
+		cfg.DropPatterns = patterns
+	case "output":
+		mode, err := executor.ParseOutputMode(val)
+		if err != nil {
+			return err
+		}
+		cfg.Output = outputModeName(mode)
 	default:
 		return fmt.Errorf("未知配置项: %s", key)
 	}
@@ -206,6 +335,46 @@ func runConfigGet(cmd *cobra.Command, args []string) error {
 		fmt.Println(cfg.AutoCompress)
 	case "time_format":
 		fmt.Println(cfg.TimeFormat)
+	case "pty":
+		fmt.Println(cfg.Run.PTY)
+	case "structured_log":
+		fmt.Println(cfg.Run.StructuredLog)
+	case "record":
+		fmt.Println(cfg.Run.Record)
+	case "raw_log":
+		fmt.Println(cfg.Run.RawLog)
+	case "events":
+		fmt.Println(cfg.Run.Events)
+	case "kill_grace_period":
+		fmt.Println(gracePeriodOrDefault(cfg.Run.GracePeriod))
+	case "timeout":
+		fmt.Println(cfg.Run.Timeout)
+	case "idle_timeout":
+		fmt.Println(cfg.Run.IdleTimeout)
+	case "sample_interval":
+		fmt.Println(cfg.Run.SampleInterval)
+	case "env_allow":
+		fmt.Println(strings.Join(cfg.EnvAllow, ","))
+	case "env_deny":
+		fmt.Println(strings.Join(cfg.EnvDeny, ","))
+	case "redact":
+		fmt.Println(cfg.Redact)
+	case "redact_patterns":
+		fmt.Println(formatPatterns(cfg.RedactPatterns))
+	case "log_transforms":
+		fmt.Println(strings.Join(cfg.LogTransforms, ","))
+	case "drop_patterns":
+		fmt.Println(formatPatterns(cfg.DropPatterns))
+	case "input_encoding":
+		fmt.Println(cfg.Run.InputEncoding)
+	case "shell":
+		fmt.Println(shellcmd.Resolve(cfg.Shell))
+	case "max_log_size":
+		fmt.Println(formatSize(cfg.Run.MaxLogSize))
+	case "log_tail_size":
+		fmt.Println(formatSize(cfg.Run.LogTailSize))
+	case "output":
+		fmt.Println(outputModeName(cfg.Run.Output))
 	default:
 		return fmt.Errorf("未知配置项: %s", key)
 	}
@@ -224,11 +393,90 @@ func runConfigList(cmd *cobra.Command, args []string) error {
 	fmt.Fprintf(w, "buffer_size\t%d\n", cfg.BufferSize)
 	fmt.Fprintf(w, "auto_compress\t%v\n", cfg.AutoCompress)
 	fmt.Fprintf(w, "time_format\t%s\n", cfg.TimeFormat)
+	fmt.Fprintf(w, "pty\t%v\n", cfg.Run.PTY)
+	fmt.Fprintf(w, "structured_log\t%v\n", cfg.Run.StructuredLog)
+	fmt.Fprintf(w, "record\t%v\n", cfg.Run.Record)
+	fmt.Fprintf(w, "raw_log\t%v\n", cfg.Run.RawLog)
+	fmt.Fprintf(w, "events\t%v\n", cfg.Run.Events)
+	fmt.Fprintf(w, "kill_grace_period\t%v\n", gracePeriodOrDefault(cfg.Run.GracePeriod))
+	fmt.Fprintf(w, "timeout\t%v\n", cfg.Run.Timeout)
+	fmt.Fprintf(w, "idle_timeout\t%v\n", cfg.Run.IdleTimeout)
+	fmt.Fprintf(w, "sample_interval\t%v\n", cfg.Run.SampleInterval)
+	fmt.Fprintf(w, "env_allow\t%s\n", strings.Join(cfg.EnvAllow, ","))
+	fmt.Fprintf(w, "env_deny\t%s\n", strings.Join(cfg.EnvDeny, ","))
+	fmt.Fprintf(w, "redact\t%v\n", cfg.Redact)
+	fmt.Fprintf(w, "redact_patterns\t%s\n", formatPatterns(cfg.RedactPatterns))
+	fmt.Fprintf(w, "log_transforms\t%s\n", strings.Join(cfg.LogTransforms, ","))
+	fmt.Fprintf(w, "drop_patterns\t%s\n", formatPatterns(cfg.DropPatterns))
+	fmt.Fprintf(w, "input_encoding\t%s\n", cfg.Run.InputEncoding)
+	fmt.Fprintf(w, "shell\t%s\n", shellcmd.Resolve(cfg.Shell))
+	fmt.Fprintf(w, "max_log_size\t%s\n", formatSize(cfg.Run.MaxLogSize))
+	fmt.Fprintf(w, "log_tail_size\t%s\n", formatSize(cfg.Run.LogTailSize))
+	fmt.Fprintf(w, "output\t%s\n", outputModeName(cfg.Run.Output))
 	w.Flush()
 
 	return nil
 }
 
+// outputModeName 返回输出模式的显示名称，实时回显显示为 normal
+func outputModeName(mode string) string {
+	if mode == executor.OutputNormal {
+		return "normal"
+	}
+	return mode
+}
+
+// gracePeriodOrDefault 未配置宽限期时显示执行器的默认值
+func gracePeriodOrDefault(d time.Duration) time.Duration {
+	if d <= 0 {
+		return executor.DefaultGracePeriod
+	}
+	return d
+}
+
+// splitList 解析逗号分隔的配置值，空字符串表示清空列表
+func splitList(val string) []string {
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parsePatterns 解析正则列表配置值，支持 JSON 数组或单个正则（正则中可能包含逗号），空字符串表示清空
+func parsePatterns(val string) ([]string, error) {
+	var patterns []string
+	if strings.HasPrefix(strings.TrimSpace(val), "[") {
+		if err := json.Unmarshal([]byte(val), &patterns); err != nil {
+			return nil, fmt.Errorf("正则列表必须是 JSON 字符串数组: %w", err)
+		}
+	} else if val != "" {
+		patterns = []string{val}
+	}
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("正则表达式 %q 无效: %w", pattern, err)
+		}
+	}
+	return patterns, nil
+}
+
+// formatSize 以字节数显示大小，0 表示未设置
+func formatSize(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+// formatPatterns 以 JSON 数组显示正则列表
+func formatPatterns(patterns []string) string {
+	if len(patterns) == 0 {
+		return "[]"
+	}
+	data, _ := json.Marshal(patterns)
+	return string(data)
+}
+
 func boolPtr(v bool) *bool {
 	return &v
 }
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aliancn/logcmd/internal/envsnap"
+	"github.com/aliancn/logcmd/internal/history"
+	"github.com/aliancn/logcmd/internal/model"
+	"github.com/spf13/cobra"
+)
+
+var showEnvCmd = &cobra.Command{
+	Use:   "show-env <runID|日志路径>",
+	Short: "查看运行时的环境变量快照",
+	Long: `查看某次运行记录下来的环境变量。
+
+快照在命令执行时采集，敏感变量的值已脱敏。可通过配置项 env_allow / env_deny
+控制记录哪些变量，变量名支持通配符（如 GO*、*_PROXY）。`,
+	Example: `  logcmd show-env 42
+  logcmd show-env .logcmd/2024-01-15/build_20240115_103000.log`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runShowEnv(args[0])
+	},
+}
+
+var envDiffCmd = &cobra.Command{
+	Use:     "env-diff <runA> <runB>",
+	Short:   "对比两次运行的环境变量",
+	Long:    "对比两次运行的环境变量快照，列出新增(+)、缺失(-)和变化(~)的变量，用于排查同一命令在不同环境下表现不一致的问题。",
+	Example: `  logcmd env-diff 41 42`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runEnvDiff(args[0], args[1])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(showEnvCmd)
+	rootCmd.AddCommand(envDiffCmd)
+}
+
+func runShowEnv(arg string) error {
+	services, err := newCLIServices()
+	if err != nil {
+		return err
+	}
+	defer services.Close()
+
+	manager := history.NewManager(services.Registry().GetDB())
+	record, snapshot, err := loadRunEnvironment(manager, arg)
+	if err != nil {
+		return err
+	}
+
+	if len(snapshot) == 0 {
+		fmt.Printf("运行记录 #%d 没有环境变量快照\n", record.ID)
+		return nil
+	}
+
+	for _, name := range envsnap.SortedNames(snapshot) {
+		fmt.Printf("%s=%s\n", name, snapshot[name])
+	}
+	return nil
+}
+
+func runEnvDiff(argA, argB string) error {
+	services, err := newCLIServices()
+	if err != nil {
+		return err
+	}
+	defer services.Close()
+
+	manager := history.NewManager(services.Registry().GetDB())
+	recordA, envA, err := loadRunEnvironment(manager, argA)
+	if err != nil {
+		return err
+	}
+	recordB, envB, err := loadRunEnvironment(manager, argB)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("--- #%d %s (%s)\n", recordA.ID, recordA.Command, recordA.StartTime.Format("2006-01-02 15:04:05"))
+	fmt.Printf("+++ #%d %s (%s)\n", recordB.ID, recordB.Command, recordB.StartTime.Format("2006-01-02 15:04:05"))
+
+	changes := envsnap.Diff(envA, envB)
+	if len(changes) == 0 {
+		fmt.Println("环境变量完全一致")
+		return nil
+	}
+
+	for _, change := range changes {
+		switch change.Kind {
+		case envsnap.Added:
+			fmt.Printf("+ %s=%s\n", change.Name, change.New)
+		case envsnap.Removed:
+			fmt.Printf("- %s=%s\n", change.Name, change.Old)
+		case envsnap.Changed:
+			fmt.Printf("~ %s: %s → %s\n", change.Name, change.Old, change.New)
+		}
+	}
+	return nil
+}
+
+// loadRunEnvironment 查找运行记录并解析其环境变量快照
+func loadRunEnvironment(manager *history.Manager, arg string) (*model.CommandHistory, map[string]string, error) {
+	record, err := resolveRun(manager, arg)
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshot, err := envsnap.Decode(record.EnvironmentJSON)
+	if err != nil {
+		return nil, nil, fmt.Errorf("运行记录 #%d: %w", record.ID, err)
+	}
+	return record, snapshot, nil
+}
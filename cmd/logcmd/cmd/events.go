@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aliancn/logcmd/internal/events"
+	"github.com/aliancn/logcmd/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var (
+	markWarning bool
+	markMetric  string
+)
+
+var markCmd = &cobra.Command{
+	Use:   "mark [text]",
+	Short: "向正在记录的运行发送事件",
+	Long: `在 logcmd run 执行的命令内部发送事件（阶段标记、指标或警告）。
+
+logcmd run --events 会把接收事件的 socket 路径导出为环境变量 LOGCMD_EVENTS，事件按
+到达时间插入日志，并保存到命令历史中，可通过 logcmd events 查看。也可以直接向该
+socket 写入一行文本或 JSON（如 {"type":"metric","key":"tests","value":"42"}）。
+
+不在 logcmd run --events 中执行时只输出警告，不会使调用它的脚本失败。`,
+	Example: `  logcmd mark "compile started"
+  logcmd mark --warning "缓存未命中，重新下载依赖"
+  logcmd mark --metric tests=42`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMark(args)
+	},
+}
+
+var eventsCmd = &cobra.Command{
+	Use:     "events <runID|日志路径>",
+	Short:   "查看运行期间命令发送的事件",
+	Example: `  logcmd events 42`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runEvents(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(markCmd)
+	rootCmd.AddCommand(eventsCmd)
+
+	markCmd.Flags().BoolVar(&markWarning, "warning", false, "作为警告发送")
+	markCmd.Flags().StringVar(&markMetric, "metric", "", "发送键值指标（如 tests=42），text 作为说明")
+}
+
+func runMark(args []string) error {
+	ev := events.Event{Type: events.TypeMark}
+	if len(args) > 0 {
+		ev.Text = args[0]
+	}
+	switch {
+	case markMetric != "" && markWarning:
+		return newExitErrorf(1, "--metric 与 --warning 不能同时使用")
+	case markMetric != "":
+		key, value, ok := strings.Cut(markMetric, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return newExitErrorf(1, "--metric 格式应为 key=value")
+		}
+		ev.Type = events.TypeMetric
+		ev.Key, ev.Value = strings.TrimSpace(key), strings.TrimSpace(value)
+	case markWarning:
+		ev.Type = events.TypeWarning
+	}
+	if err := ev.Validate(); err != nil {
+		return newExitError(err, 1)
+	}
+
+	path := os.Getenv(events.EnvVar)
+	if path == "" {
+		fmt.Fprintf(os.Stderr, "警告: 未设置 %s，当前不在 logcmd run --events 中执行，事件已忽略\n", events.EnvVar)
+		return nil
+	}
+	if err := events.Send(path, ev); err != nil {
+		fmt.Fprintf(os.Stderr, "警告: %v\n", err)
+	}
+	return nil
+}
+
+func runEvents(arg string) error {
+	services, err := newCLIServices()
+	if err != nil {
+		return err
+	}
+	defer services.Close()
+
+	manager := history.NewManager(services.Registry().GetDB())
+	record, err := resolveRun(manager, arg)
+	if err != nil {
+		return err
+	}
+
+	list, err := manager.GetEvents(record.ID)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		fmt.Printf("运行记录 #%d 没有事件\n", record.ID)
+		return nil
+	}
+
+	fmt.Printf("运行记录 #%d 的事件 (共%d条):\n\n", record.ID, len(list))
+	for _, item := range list {
+		ev := events.Event{Type: item.Type, Text: item.Text, Key: item.Key, Value: item.Value}
+		offset := time.Duration(item.OffsetMs) * time.Millisecond
+		fmt.Printf("+%-10s %s\n", formatOffset(offset), ev)
+	}
+	return nil
+}
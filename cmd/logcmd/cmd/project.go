@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/aliancn/logcmd/internal/history"
 	"github.com/aliancn/logcmd/internal/model"
 	"github.com/aliancn/logcmd/internal/template"
 	"github.com/spf13/cobra"
@@ -81,8 +82,10 @@ func listProjects() error {
 		SuccessRate:   "成功率",
 		TotalCommands: "命令数",
 		Exists:        "存在",
+		LastError:     "最近失败原因",
 	}
 	widths.update(header)
+	historyManager := history.NewManager(reg.GetDB())
 
 	for _, entry := range entries {
 		exists := "✓"
@@ -105,6 +108,7 @@ func listProjects() error {
 			SuccessRate:   fmt.Sprintf("%.1f%%", entry.GetSuccessRate()),
 			TotalCommands: strconv.Itoa(entry.TotalCommands),
 			Exists:        exists,
+			LastError:     lastFailureExcerpt(historyManager, entry.ID),
 		}
 		widths.update(row)
 		rows = append(rows, row)
@@ -134,10 +138,38 @@ func formatProjectRow(row projectRow, widths columnWidths) string {
 		padRight(row.SuccessRate, widths.SuccessRate),
 		padRight(row.TotalCommands, widths.TotalCommands),
 		padRight(row.Exists, widths.Exists),
+		row.LastError,
 	}
 	return strings.Join(cells, " ")
 }
 
+// lastFailureExcerpt 返回项目最近一次失败运行的错误摘要，截断到表格列宽
+func lastFailureExcerpt(manager *history.Manager, projectID int) string {
+	failed, err := manager.GetFailed(projectID, 1)
+	if err != nil || len(failed) == 0 || failed[0].ErrorExcerpt == "" {
+		return "-"
+	}
+	return truncateDisplay(failed[0].ErrorExcerpt, projectErrorWidth)
+}
+
+// truncateDisplay 按显示宽度截断文本，超出部分以 … 结尾
+func truncateDisplay(text string, width int) string {
+	if displayWidth(text) <= width {
+		return text
+	}
+	var b strings.Builder
+	used := 0
+	for _, r := range text {
+		w := runeDisplayWidth(r)
+		if used+w > width-1 {
+			break
+		}
+		b.WriteRune(r)
+		used += w
+	}
+	return b.String() + "…"
+}
+
 func padRight(text string, width int) string {
 	padding := width - displayWidth(text)
 	if padding <= 0 {
@@ -188,6 +220,7 @@ func baseProjectColumnWidths() columnWidths {
 		SuccessRate:   8,
 		TotalCommands: 10,
 		Exists:        2,
+		LastError:     12,
 	}
 }
 
@@ -199,6 +232,7 @@ type projectRow struct {
 	SuccessRate   string
 	TotalCommands string
 	Exists        string
+	LastError     string
 }
 
 type columnWidths struct {
@@ -209,6 +243,7 @@ type columnWidths struct {
 	SuccessRate   int
 	TotalCommands int
 	Exists        int
+	LastError     int
 }
 
 func (w *columnWidths) update(row projectRow) {
@@ -219,10 +254,11 @@ func (w *columnWidths) update(row projectRow) {
 	w.SuccessRate = maxInt(w.SuccessRate, displayWidth(row.SuccessRate))
 	w.TotalCommands = maxInt(w.TotalCommands, displayWidth(row.TotalCommands))
 	w.Exists = maxInt(w.Exists, displayWidth(row.Exists))
+	w.LastError = maxInt(w.LastError, displayWidth(row.LastError))
 }
 
 func (w columnWidths) total() int {
-	return w.ID + w.Name + w.Path + w.LastRun + w.SuccessRate + w.TotalCommands + w.Exists
+	return w.ID + w.Name + w.Path + w.LastRun + w.SuccessRate + w.TotalCommands + w.Exists + w.LastError
 }
 
 func maxInt(a, b int) int {
@@ -233,8 +269,9 @@ func maxInt(a, b int) int {
 }
 
 const (
-	projectColumnCount   = 7
+	projectColumnCount   = 8
 	projectColumnSpacing = projectColumnCount - 1
+	projectErrorWidth    = 40 // 最近失败原因列的最大显示宽度
 )
 
 func cleanProjects() error {
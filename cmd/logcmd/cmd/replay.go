@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/aliancn/logcmd/internal/asciicast"
+	"github.com/aliancn/logcmd/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var (
+	replaySpeed     float64
+	replayIdleLimit time.Duration
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <runID|日志路径|录制文件>",
+	Short: "在终端中回放 run --record 录制的输出",
+	Long: `按原始时间间隔回放 logcmd run --record 录制的终端输出，颜色、进度条等
+终端控制序列会原样输出。录制文件为 asciicast v2 格式，也可以用 asciinema play
+回放或上传到 asciinema.org。
+
+回放期间按 Ctrl+C 结束。`,
+	Example: `  logcmd replay 42
+  logcmd replay 42 --speed 2
+  logcmd replay .logcmd/2024-01-01/build_20240101_120000.log --idle-limit 1s`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runReplay(cmd.Context(), args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	replayCmd.Flags().Float64Var(&replaySpeed, "speed", 1, "回放速度倍数（如 2 表示两倍速）")
+	replayCmd.Flags().DurationVar(&replayIdleLimit, "idle-limit", 0, "两次输出之间的最长等待（如 2s），0 表示按原始间隔")
+}
+
+func runReplay(ctx context.Context, arg string) error {
+	if replaySpeed <= 0 {
+		return newExitErrorf(1, "--speed 必须大于 0")
+	}
+
+	path, err := resolveRecording(arg)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("打开录制文件失败: %w", err)
+	}
+	defer file.Close()
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	header, err := asciicast.Play(ctx, file, os.Stdout, asciicast.PlayOptions{
+		Speed:     replaySpeed,
+		IdleLimit: replayIdleLimit,
+	})
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, "\n回放已中断")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if width, height := asciicast.TerminalSize(); width < header.Width || height < header.Height {
+		fmt.Fprintf(os.Stderr, "\n提示: 录制时终端大小为 %dx%d，当前终端较小，显示可能错位\n", header.Width, header.Height)
+	}
+	return nil
+}
+
+// resolveRecording 返回参数对应的录制文件：直接给出的 .cast 文件，或运行记录关联的录制
+func resolveRecording(arg string) (string, error) {
+	if strings.HasSuffix(arg, ".cast") {
+		if _, err := os.Stat(arg); err != nil {
+			return "", fmt.Errorf("录制文件不存在: %s", arg)
+		}
+		return arg, nil
+	}
+
+	services, err := newCLIServices()
+	if err != nil {
+		return "", err
+	}
+	defer services.Close()
+
+	record, err := resolveRun(history.NewManager(services.Registry().GetDB()), arg)
+	if err != nil {
+		return "", err
+	}
+	if record.Recording == "" {
+		return "", newExitErrorf(1, "运行记录 #%d 没有终端录制，使用 logcmd run --record 录制", record.ID)
+	}
+	if _, err := os.Stat(record.Recording); err != nil {
+		return "", fmt.Errorf("录制文件不存在: %s", record.Recording)
+	}
+	return record.Recording, nil
+}
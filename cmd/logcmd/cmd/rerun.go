@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aliancn/logcmd/internal/config"
+	"github.com/aliancn/logcmd/internal/history"
+	"github.com/aliancn/logcmd/internal/logger"
+	"github.com/aliancn/logcmd/internal/model"
+	"github.com/aliancn/logcmd/internal/persistence"
+	"github.com/spf13/cobra"
+)
+
+var rerunReplayStdin bool
+
+var rerunCmd = &cobra.Command{
+	Use:   "rerun <runID|日志路径>",
+	Short: "在原工作目录中重新执行一次历史运行",
+	Long: `按运行记录中的命令和参数在原工作目录中重新执行，shell 模式的运行使用原来的 shell
+执行完整脚本。新的运行单独写入日志和历史。
+
+以 logcmd run --record-stdin 运行时，可以用 --replay-stdin 将保存的标准输入
+再次提供给命令，而不是转发当前终端的输入。`,
+	Example: `  logcmd rerun 42
+  logcmd rerun 42 --replay-stdin
+  logcmd rerun .logcmd/2024-01-01/psql_20240101_120000.log --replay-stdin`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRerun(cmd, args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rerunCmd)
+
+	rerunCmd.Flags().BoolVar(&rerunReplayStdin, "replay-stdin", false, "将 run --record-stdin 保存的标准输入重放给命令")
+}
+
+func runRerun(cmd *cobra.Command, arg string) error {
+	services, err := newCLIServices()
+	if err != nil {
+		return err
+	}
+	defer services.Close()
+
+	record, err := resolveRun(history.NewManager(services.Registry().GetDB()), arg)
+	if err != nil {
+		return err
+	}
+
+	var stdinPath string
+	if rerunReplayStdin {
+		stdinPath = logger.StdinPath(record.LogFilePath)
+		if _, err := os.Stat(stdinPath); err != nil {
+			return newExitErrorf(1, "运行记录 #%d 没有保存标准输入（需要以 logcmd run --record-stdin 运行）", record.ID)
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	if logDirFlag != "" {
+		cfg.LogDir = logDirFlag
+	}
+	command, args := rerunCommand(record)
+	cfg.Run.Shell = record.Shell
+
+	if dir := record.WorkingDirectory; dir != "" {
+		if err := os.Chdir(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "警告: 无法进入原工作目录 %s，在当前目录执行: %v\n", dir, err)
+		}
+	}
+
+	reg := services.Registry()
+	log, err := logger.New(cfg, persistence.NewRunRepository(reg), persistence.NewStatsUpdater(reg))
+	if err != nil {
+		return fmt.Errorf("创建日志记录器失败: %w", err)
+	}
+	if stdinPath != "" {
+		log.SetStdinReplay(stdinPath)
+	}
+
+	result, logPath, err := log.Run(cmd.Context(), command, args...)
+	return finishRun(cfg, result, logPath, err)
+}
+
+// rerunCommand 从运行记录还原要执行的命令和参数
+// shell 模式下 Command 为完整脚本；其余情况下 Command 为程序与参数拼接而成的字符串
+func rerunCommand(record *model.CommandHistory) (string, []string) {
+	if record.Shell != "" || len(record.CommandArgs) == 0 {
+		return record.Command, nil
+	}
+	program := strings.TrimSuffix(record.Command, " "+strings.Join(record.CommandArgs, " "))
+	return program, record.CommandArgs
+}
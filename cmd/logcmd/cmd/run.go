@@ -1,24 +1,54 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"os"
 	"os/exec"
-	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/aliancn/logcmd/internal/charset"
 	"github.com/aliancn/logcmd/internal/config"
+	"github.com/aliancn/logcmd/internal/execctx"
+	"github.com/aliancn/logcmd/internal/executor"
+	"github.com/aliancn/logcmd/internal/filetrack"
 	"github.com/aliancn/logcmd/internal/logger"
 	"github.com/aliancn/logcmd/internal/model"
 	"github.com/aliancn/logcmd/internal/persistence"
+	"github.com/aliancn/logcmd/internal/retry"
+	"github.com/aliancn/logcmd/internal/sandbox"
+	"github.com/aliancn/logcmd/internal/shellcmd"
 	"github.com/spf13/cobra"
 )
 
 var (
-	runDetached bool
+	runDetached   bool
+	runPTY        bool
+	runStructured bool
+	runRecord     bool
+	runRaw        bool
+	runEvent      bool
+	runTrackFiles string
+	runArtifact   []string
+	runRecStdin   bool
+	runSandbox    string
+	runGrace      time.Duration
+	runTimeout    time.Duration
+	runIdle       time.Duration
+	runRetries    int
+	runRetryOn    string
+	runBackoff    string
+	runSample     time.Duration
+	runEncoding   string
+	runMaxLog     string
+	runLogTail    string
+	runShell      bool
+	runQuiet      bool
+	runOnFailure  bool
+	runSummary    bool
 )
 
 var runCmd = &cobra.Command{
@@ -35,6 +65,31 @@ var runCmd = &cobra.Command{
 func init() {
 	runCmd.Flags().SetInterspersed(false)
 	runCmd.Flags().BoolVarP(&runDetached, "detached", "d", false, "后台运行命令并交由 task 管理")
+	runCmd.Flags().BoolVarP(&runShell, "shell", "c", false, "通过 shell 执行命令（支持管道、&& 等），使用配置的 shell 或 $SHELL")
+	runCmd.Flags().BoolVarP(&runQuiet, "quiet", "q", false, "不回显命令输出，只写入日志")
+	runCmd.Flags().BoolVar(&runOnFailure, "on-failure", false, "缓存命令输出，仅在命令失败时回显")
+	runCmd.Flags().BoolVar(&runSummary, "summary", false, "不回显命令输出，结束时只打印一行状态、耗时和日志路径")
+	runCmd.Flags().BoolVar(&runPTY, "pty", false, "在伪终端中运行命令（保留颜色、进度条与交互提示）")
+	runCmd.Flags().BoolVar(&runStructured, "structured", false, "额外写入带输出流和时间偏移的逐行记录 (.log.jsonl)")
+	runCmd.Flags().BoolVar(&runRecord, "record", false, "将终端输出录制为 asciicast v2 文件 (.log.cast)，可用 logcmd replay 回放")
+	runCmd.Flags().BoolVar(&runRaw, "raw", false, "日志只包含命令的原始输出（不经过编码转换和日志转换，仍按配置脱敏），头部和尾部元数据写入日志旁的 .log.meta（可通过 logcmd config set raw_log true 设为默认）")
+	runCmd.Flags().BoolVar(&runEvent, "events", false, "导出 LOGCMD_EVENTS，命令可通过 logcmd mark 发送阶段标记和指标并写入日志（可通过 logcmd config set events true 设为默认）")
+	runCmd.Flags().StringVar(&runTrackFiles, "track-files", "", "记录命令创建、修改和删除的文件，可指定 glob（逗号分隔，如 'dist/**,*.go'），默认跟踪全部文件")
+	runCmd.Flags().Lookup("track-files").NoOptDefVal = filetrack.DefaultPattern
+	runCmd.Flags().BoolVar(&runRecStdin, "record-stdin", false, "将转发给命令的标准输入保存到日志旁 (.log.stdin)，可用 logcmd rerun --replay-stdin 重放")
+	runCmd.Flags().StringVar(&runSandbox, "sandbox", "", "在沙箱中运行命令（仅 Linux）：文件系统只读，工作目录和配置允许的路径可写；可指定 config.json 中 sandbox_profiles 定义的配置，内置 default 与 offline（禁止网络）；不限制连接 docker.sock、D-Bus 等 unix 套接字")
+	runCmd.Flags().Lookup("sandbox").NoOptDefVal = sandbox.DefaultProfile
+	runCmd.Flags().StringArrayVar(&runArtifact, "artifact", nil, "命令结束后收集匹配的文件到产物目录（glob 或目录，可重复指定，如 'coverage/*.html'、dist/）")
+	runCmd.Flags().DurationVar(&runTimeout, "timeout", 0, "命令最长运行时间（如 30m），超时后终止命令并记录为 timeout")
+	runCmd.Flags().DurationVar(&runIdle, "idle-timeout", 0, "命令持续无输出的最长时间（如 5m），超过后由看门狗终止命令")
+	runCmd.Flags().IntVar(&runRetries, "retry", 0, "命令失败后最多重试的次数，每次尝试单独记录日志和历史")
+	runCmd.Flags().StringVar(&runRetryOn, "retry-on", "", "仅在这些退出码时重试（逗号分隔，如 1,137），默认任何失败都重试")
+	runCmd.Flags().StringVar(&runBackoff, "backoff", retry.DefaultBackoff, "重试前的等待策略：固定时长（如 5s）或指数退避（如 exp:2s..1m）")
+	runCmd.Flags().DurationVar(&runSample, "sample-interval", 0, "运行期间从 /proc 采样 CPU 与内存的间隔（后台任务默认 10s）")
+	runCmd.Flags().StringVar(&runEncoding, "input-encoding", "", "命令输出的字符编码（如 gbk、gb18030、latin1，auto 表示自动识别），写入日志前转换为 UTF-8")
+	runCmd.Flags().StringVar(&runMaxLog, "max-log-size", "", "写入日志的输出上限（如 100MB），超过后只保留开头和末尾，结构化记录和录制停止写入，0 表示不限制")
+	runCmd.Flags().StringVar(&runLogTail, "log-tail-size", "", "超过上限时末尾保留的大小（如 10MB），默认为上限的一半")
+	runCmd.Flags().DurationVar(&runGrace, "grace-period", 0, "转发终止信号后等待命令退出的时长，超时发送 SIGKILL（默认 10s）")
 	rootCmd.AddCommand(runCmd)
 }
 
@@ -46,6 +101,9 @@ func runCommand(cmd *cobra.Command, args []string) error {
 	if logDirFlag != "" {
 		cfg.LogDir = logDirFlag
 	}
+	if err := applyRunFlags(cmd, cfg); err != nil {
+		return newExitError(err, 1)
+	}
 
 	services, err := newCLIServices()
 	if err != nil {
@@ -66,20 +124,216 @@ func runCommand(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("创建日志记录器失败: %w", err)
 	}
 
-	ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
-	defer cancel()
+	// 执行期间收到的中断/终止信号由执行器转发给命令所在的进程组
+	result, logPath, err := log.Run(cmd.Context(), args[0], args[1:]...)
+	return finishRun(cfg, result, logPath, err)
+}
 
-	if _, _, err := log.Run(ctx, args[0], args[1:]...); err != nil {
-		if ctx.Err() == context.Canceled {
-			fmt.Println("\n命令已由用户中断")
-			return newExitError(nil, 130)
-		}
+// finishRun 按运行结果输出收尾提示并返回与命令一致的退出码
+func finishRun(cfg *config.Config, result *executor.Result, logPath string, err error) error {
+	if cfg.Run.Output == executor.OutputSummary {
+		return printRunSummary(result, logPath, err)
+	}
+	if result != nil && !result.Success && cfg.Run.Output == executor.OutputOnFailure {
+		// 开始时没有显示日志路径，失败后补充输出
+		fmt.Fprintf(os.Stderr, "日志: %s\n", logPath)
+	}
+	if result != nil && result.Reason == executor.ReasonIdleTimeout {
+		fmt.Printf("\n命令超过 %v 无输出，已被看门狗终止\n", cfg.Run.IdleTimeout)
+		return newExitError(nil, result.ShellExitCode())
+	}
+	if result != nil && result.Status == executor.StatusTimeout {
+		fmt.Printf("\n命令执行超时 (%v)\n", cfg.Run.Timeout)
+		return newExitError(nil, result.ShellExitCode())
+	}
+	if result != nil && !result.Success && result.Signal != "" {
+		fmt.Printf("\n命令已被信号 %s 终止\n", result.Signal)
+		return newExitError(nil, result.ShellExitCode())
+	}
+	if err != nil {
 		return fmt.Errorf("执行失败: %w", err)
 	}
 
 	return nil
 }
 
+// printRunSummary 在 --summary 模式下打印一行最终状态，并按命令结果返回退出码
+func printRunSummary(result *executor.Result, logPath string, err error) error {
+	if result == nil {
+		if err != nil {
+			return fmt.Errorf("执行失败: %w", err)
+		}
+		return nil
+	}
+
+	status := "成功"
+	if !result.Success {
+		status = fmt.Sprintf("失败 (%s, 退出码 %d)", result.Status, result.ShellExitCode())
+		if result.Signal != "" {
+			status = fmt.Sprintf("失败 (%s, 信号 %s)", result.Status, result.Signal)
+		}
+	}
+	fmt.Printf("%s 耗时 %v 日志: %s\n", status, result.Duration.Round(time.Millisecond), logPath)
+
+	if !result.Success {
+		return newExitError(nil, result.ShellExitCode())
+	}
+	return nil
+}
+
+// applyRunFlags 用显式指定的 run 参数覆盖配置文件中的运行选项
+func applyRunFlags(cmd *cobra.Command, cfg *config.Config) error {
+	flags := cmd.Flags()
+	if err := applyOutputFlags(cmd, cfg); err != nil {
+		return err
+	}
+	if flags.Changed("pty") {
+		cfg.Run.PTY = runPTY
+	}
+	if flags.Changed("structured") {
+		cfg.Run.StructuredLog = runStructured
+	}
+	if flags.Changed("record") {
+		cfg.Run.Record = runRecord
+	}
+	if flags.Changed("raw") {
+		cfg.Run.RawLog = runRaw
+	}
+	if flags.Changed("events") {
+		cfg.Run.Events = runEvent
+	}
+	if flags.Changed("track-files") {
+		patterns := filetrack.ParsePatterns(runTrackFiles)
+		if _, err := filetrack.New(".", patterns); err != nil {
+			return err
+		}
+		cfg.Run.TrackFiles = strings.Join(patterns, ",")
+	}
+	if flags.Changed("record-stdin") {
+		cfg.Run.RecordStdin = runRecStdin
+	}
+	if flags.Changed("sandbox") {
+		if err := sandbox.Supported(); err != nil {
+			return fmt.Errorf("--sandbox 不可用: %w", err)
+		}
+		profile, err := sandbox.Resolve(runSandbox, cfg.SandboxProfiles)
+		if err != nil {
+			return fmt.Errorf("--sandbox 参数无效: %w", err)
+		}
+		cfg.Run.Sandbox = &profile
+	}
+	if flags.Changed("artifact") {
+		for _, spec := range runArtifact {
+			if _, err := filepath.Match(spec, ""); err != nil {
+				return fmt.Errorf("无效的产物规则 %q: %w", spec, err)
+			}
+		}
+		cfg.Run.Artifacts = runArtifact
+	}
+	if flags.Changed("timeout") {
+		cfg.Run.Timeout = runTimeout
+	}
+	if flags.Changed("idle-timeout") {
+		cfg.Run.IdleTimeout = runIdle
+	}
+	if flags.Changed("grace-period") {
+		cfg.Run.GracePeriod = runGrace
+	}
+	if flags.Changed("sample-interval") {
+		cfg.Run.SampleInterval = runSample
+	}
+	if flags.Changed("retry") {
+		if runRetries < 0 {
+			return fmt.Errorf("--retry 不能为负数")
+		}
+		cfg.Run.Retries = runRetries
+	}
+	if flags.Changed("retry-on") {
+		codes, err := retry.ParseExitCodes(runRetryOn)
+		if err != nil {
+			return fmt.Errorf("--retry-on 参数无效: %w", err)
+		}
+		cfg.Run.RetryOn = codes
+	}
+	if flags.Changed("backoff") {
+		if _, err := retry.ParseBackoff(runBackoff); err != nil {
+			return fmt.Errorf("--backoff 参数无效: %w", err)
+		}
+		cfg.Run.Backoff = runBackoff
+	}
+	if flags.Changed("shell") {
+		cfg.Run.Shell = ""
+		if runShell {
+			cfg.Run.Shell = shellcmd.Resolve(cfg.Shell)
+		}
+	}
+	if flags.Changed("max-log-size") {
+		n, err := config.ParseSize(runMaxLog)
+		if err != nil {
+			return fmt.Errorf("--max-log-size 参数无效: %w", err)
+		}
+		cfg.Run.MaxLogSize = n
+	}
+	if flags.Changed("log-tail-size") {
+		n, err := config.ParseSize(runLogTail)
+		if err != nil {
+			return fmt.Errorf("--log-tail-size 参数无效: %w", err)
+		}
+		cfg.Run.LogTailSize = n
+	}
+	if cfg.Run.MaxLogSize > 0 && cfg.Run.LogTailSize > cfg.Run.MaxLogSize {
+		return fmt.Errorf("--log-tail-size 不能超过日志大小上限")
+	}
+	if flags.Changed("input-encoding") {
+		name, err := charset.Normalize(runEncoding)
+		if err != nil {
+			return fmt.Errorf("--input-encoding 参数无效: %w", err)
+		}
+		cfg.Run.InputEncoding = name
+	}
+	return nil
+}
+
+// applyOutputFlags 处理互斥的终端输出模式参数，并校验配置文件中的输出模式
+func applyOutputFlags(cmd *cobra.Command, cfg *config.Config) error {
+	flags := cmd.Flags()
+	modes := []struct {
+		flag string
+		set  bool
+		mode string
+	}{
+		{"quiet", runQuiet, executor.OutputQuiet},
+		{"on-failure", runOnFailure, executor.OutputOnFailure},
+		{"summary", runSummary, executor.OutputSummary},
+	}
+
+	mode, err := executor.ParseOutputMode(cfg.Run.Output)
+	if err != nil {
+		return fmt.Errorf("配置项 output 无效: %w", err)
+	}
+	cfg.Run.Output = mode
+
+	var chosen string
+	for _, m := range modes {
+		if !flags.Changed(m.flag) {
+			continue
+		}
+		if !m.set {
+			// 如 --quiet=false 可以关闭配置文件中设置的默认模式
+			if cfg.Run.Output == m.mode {
+				cfg.Run.Output = executor.OutputNormal
+			}
+			continue
+		}
+		if chosen != "" {
+			return fmt.Errorf("--%s 与 --%s 不能同时使用", chosen, m.flag)
+		}
+		chosen = m.flag
+		cfg.Run.Output = m.mode
+	}
+	return nil
+}
+
 func startDetachedTask(cfg *config.Config, services *cliServices, args []string) error {
 	manager, err := services.TaskManager()
 	if err != nil {
@@ -91,11 +345,30 @@ func startDetachedTask(cfg *config.Config, services *cliServices, args []string)
 		return fmt.Errorf("获取工作目录失败: %w", err)
 	}
 
+	runOptions, err := config.EncodeRunOptions(cfg.Run)
+	if err != nil {
+		return err
+	}
+
+	// 在前台采集执行上下文，后台 worker 脱离了终端和父 shell
+	execContext, err := execctx.Encode(execctx.Capture(workingDir))
+	if err != nil {
+		return err
+	}
+
+	command, commandArgs := args[0], args[1:]
+	if cfg.Run.Shell != "" {
+		// 后台任务保存完整的脚本，worker 按运行选项中的 shell 执行
+		command, commandArgs = shellcmd.Script(args[0], args[1:]), nil
+	}
+
 	task := &model.Task{
-		Command:     args[0],
-		CommandArgs: args[1:],
+		Command:     command,
+		CommandArgs: commandArgs,
 		WorkingDir:  workingDir,
 		LogDir:      cfg.LogDir,
+		OptionsJSON: runOptions,
+		ContextJSON: execContext,
 		Status:      model.TaskStatusPending,
 	}
 
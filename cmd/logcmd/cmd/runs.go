@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/aliancn/logcmd/internal/history"
+	"github.com/aliancn/logcmd/internal/model"
+)
+
+// resolveRun 根据运行记录ID或日志文件路径查找命令历史
+func resolveRun(manager *history.Manager, arg string) (*model.CommandHistory, error) {
+	if id, err := strconv.Atoi(arg); err == nil {
+		return manager.GetByID(id)
+	}
+
+	logPath, err := filepath.Abs(arg)
+	if err != nil {
+		return nil, fmt.Errorf("无效的日志路径: %s", arg)
+	}
+	return manager.GetByLogPath(logPath)
+}
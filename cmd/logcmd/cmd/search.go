@@ -5,12 +5,17 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"regexp"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/aliancn/logcmd/internal/config"
+	"github.com/aliancn/logcmd/internal/execctx"
+	"github.com/aliancn/logcmd/internal/executor"
+	"github.com/aliancn/logcmd/internal/history"
+	"github.com/aliancn/logcmd/internal/logrecord"
 	"github.com/aliancn/logcmd/internal/model"
 	"github.com/aliancn/logcmd/internal/search"
 	"github.com/spf13/cobra"
@@ -25,6 +30,11 @@ var (
 	searchEnd     string
 	searchAll     bool
 	searchDir     string
+	searchStream  string
+	searchTimes   bool
+	searchUser    string
+	searchHost    string
+	searchBranch  string
 )
 
 var searchCmd = &cobra.Command{
@@ -58,6 +68,11 @@ func init() {
 	searchCmd.Flags().StringVar(&searchEnd, "end", "", "搜索结束日期 (YYYY-MM-DD)")
 	searchCmd.Flags().BoolVar(&searchAll, "all", false, "搜索所有项目")
 	searchCmd.Flags().StringVar(&searchDir, "dir", "", "日志目录路径")
+	searchCmd.Flags().StringVar(&searchStream, "stream", "", "仅搜索指定输出流 (stdout/stderr)，需要结构化记录")
+	searchCmd.Flags().BoolVar(&searchTimes, "timestamps", false, "显示匹配行的输出流与时间偏移")
+	searchCmd.Flags().StringVar(&searchUser, "user", "", "仅搜索指定用户执行的命令")
+	searchCmd.Flags().StringVar(&searchHost, "host", "", "仅搜索在指定主机上执行的命令")
+	searchCmd.Flags().StringVar(&searchBranch, "branch", "", "仅搜索在指定 git 分支上执行的命令")
 }
 
 func runSearch(cmd *cobra.Command) error {
@@ -65,6 +80,10 @@ func runSearch(cmd *cobra.Command) error {
 		return fmt.Errorf("错误: 请使用 --keyword 参数指定搜索关键词")
 	}
 
+	if err := validateStream(searchStream); err != nil {
+		return err
+	}
+
 	ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
@@ -92,13 +111,20 @@ func runSearch(cmd *cobra.Command) error {
 		return fmt.Errorf("创建搜索器失败: %w", err)
 	}
 
+	// 命令历史只用于补充失败原因，数据库不可用时照常输出搜索结果
+	var notes *failureNotes
+	if services, err := newCLIServices(); err == nil {
+		defer services.Close()
+		notes = newFailureNotes(history.NewManager(services.Registry().GetDB()))
+	}
+
 	var count int
 	err = searcher.Search(ctx, func(result *search.SearchResult) error {
 		if count == 0 {
 			fmt.Println("匹配结果:")
 			fmt.Println()
 		}
-		printSearchResult(result)
+		printSearchResult(result, notes)
 		count++
 		return nil
 	})
@@ -121,6 +147,9 @@ func buildSearchOptions(dir string, compiled *regexp.Regexp) (*search.SearchOpti
 		UseRegex:      searchRegex,
 		CaseSensitive: searchCase,
 		ShowContext:   searchContext,
+		Stream:        searchStream,
+		Timestamps:    searchTimes,
+		Context:       execctx.Filter{User: searchUser, Host: searchHost, Branch: searchBranch},
 		CompiledRegex: compiled,
 	}
 
@@ -238,6 +267,7 @@ dispatchLoop:
 		return ctx.Err()
 	}
 
+	notes := newFailureNotes(history.NewManager(reg.GetDB()))
 	totalResults := 0
 	for i, entry := range scheduledEntries {
 		fmt.Printf("[%d/%d] 搜索: %s\n", i+1, len(entries), entry.Path)
@@ -250,7 +280,7 @@ dispatchLoop:
 		if len(result.matches) > 0 {
 			fmt.Printf("  找到 %d 条结果\n", len(result.matches))
 			for _, match := range result.matches {
-				printSearchResult(match)
+				printSearchResult(match, notes)
 			}
 			totalResults += len(result.matches)
 		} else {
@@ -282,8 +312,15 @@ func compileSearchRegex() (*regexp.Regexp, error) {
 	return regex, nil
 }
 
-func printSearchResult(result *search.SearchResult) {
-	fmt.Printf("文件: %s:%d\n", result.FilePath, result.LineNum)
+func printSearchResult(result *search.SearchResult, notes *failureNotes) {
+	if result.Stream != "" {
+		fmt.Printf("文件: %s#%d [%s +%s]\n", result.FilePath, result.LineNum, result.Stream, formatOffset(result.Offset))
+	} else {
+		fmt.Printf("文件: %s:%d\n", result.FilePath, result.LineNum)
+	}
+	if excerpt := notes.first(result.FilePath); excerpt != "" {
+		fmt.Printf("失败原因: %s\n", excerpt)
+	}
 	if len(result.Context) > 0 {
 		fmt.Println("上下文:")
 		for _, line := range result.Context {
@@ -294,3 +331,45 @@ func printSearchResult(result *search.SearchResult) {
 	}
 	fmt.Println()
 }
+
+// failureNotes 按日志文件查询命令历史中记录的错误摘要，每个文件只返回一次
+type failureNotes struct {
+	history *history.Manager
+	seen    map[string]bool
+}
+
+func newFailureNotes(manager *history.Manager) *failureNotes {
+	return &failureNotes{history: manager, seen: make(map[string]bool)}
+}
+
+// first 返回日志文件对应运行的错误摘要，同一文件第二次查询或没有记录时返回空字符串
+func (n *failureNotes) first(logPath string) string {
+	if n == nil || n.history == nil || n.seen[logPath] {
+		return ""
+	}
+	n.seen[logPath] = true
+
+	if abs, err := filepath.Abs(logPath); err == nil {
+		logPath = abs
+	}
+	record, err := n.history.GetByLogPath(logPath)
+	if err != nil || record.Status == executor.StatusSuccess {
+		return ""
+	}
+	return record.ErrorExcerpt
+}
+
+// validateStream 校验输出流参数
+func validateStream(stream string) error {
+	switch stream {
+	case "", logrecord.StreamStdout, logrecord.StreamStderr, logrecord.StreamEvent:
+		return nil
+	default:
+		return fmt.Errorf("错误: 无效的输出流 %q，可选值: stdout, stderr, event", stream)
+	}
+}
+
+// formatOffset 格式化相对命令开始的时间偏移
+func formatOffset(d time.Duration) string {
+	return fmt.Sprintf("%.3fs", d.Seconds())
+}
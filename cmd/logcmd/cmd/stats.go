@@ -10,6 +10,7 @@ import (
 	"syscall"
 
 	"github.com/aliancn/logcmd/internal/config"
+	"github.com/aliancn/logcmd/internal/execctx"
 	"github.com/aliancn/logcmd/internal/model"
 	"github.com/aliancn/logcmd/internal/services"
 	"github.com/aliancn/logcmd/internal/stats"
@@ -20,6 +21,10 @@ import (
 var (
 	statsAllFlag bool
 	statsDirFlag string
+	statsLogs    bool
+	statsUser    string
+	statsHost    string
+	statsBranch  string
 )
 
 var statsCmd = &cobra.Command{
@@ -47,6 +52,10 @@ func init() {
 
 	statsCmd.Flags().BoolVar(&statsAllFlag, "all", false, "统计所有已注册项目")
 	statsCmd.Flags().StringVar(&statsDirFlag, "dir", "", "日志目录路径")
+	statsCmd.Flags().BoolVar(&statsLogs, "logs", false, "直接扫描日志文件统计（包含输出流行数等仅记录在日志中的数据）")
+	statsCmd.Flags().StringVar(&statsUser, "user", "", "仅统计指定用户执行的命令")
+	statsCmd.Flags().StringVar(&statsHost, "host", "", "仅统计在指定主机上执行的命令")
+	statsCmd.Flags().StringVar(&statsBranch, "branch", "", "仅统计在指定 git 分支上执行的命令")
 }
 
 func runStats(cmd *cobra.Command) error {
@@ -59,10 +68,13 @@ func runStats(cmd *cobra.Command) error {
 	ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
+	filter := execctx.Filter{User: statsUser, Host: statsHost, Branch: statsBranch}
+
 	cliServices, svcErr := newCLIServices()
 	var statsSvc *services.StatsService
 	if svcErr == nil {
 		statsSvc = services.NewStatsService(cliServices.Registry())
+		statsSvc.SetFilter(filter)
 		defer cliServices.Close()
 	}
 
@@ -76,9 +88,17 @@ func runStats(cmd *cobra.Command) error {
 		return analyzeAllProjects(ctx, cliServices, statsSvc)
 	}
 
+	if statsLogs {
+		var lookup stats.HistoryLookup
+		if statsSvc != nil {
+			lookup = statsSvc.HistoryLookup()
+		}
+		return analyzeLogDir(ctx, logDirPath, filter, lookup)
+	}
+
 	if statsSvc == nil {
 		fmt.Fprintf(os.Stderr, "警告: 统计服务未初始化，直接扫描日志目录\n")
-		return analyzeLogDir(ctx, logDirPath)
+		return analyzeLogDir(ctx, logDirPath, filter, nil)
 	}
 
 	report, statErr := statsSvc.StatsForPath(ctx, logDirPath)
@@ -176,13 +196,16 @@ dispatchLoop:
 	return nil
 }
 
-func analyzeLogDir(ctx context.Context, logDirPath string) error {
+func analyzeLogDir(ctx context.Context, logDirPath string, filter execctx.Filter, lookup stats.HistoryLookup) error {
 	analyzer := stats.New(logDirPath)
+	analyzer.SetFilter(filter)
+	analyzer.SetHistory(lookup)
 	statistics, err := analyzer.Analyze(ctx)
 	if err != nil {
 		return fmt.Errorf("统计分析失败: %w", err)
 	}
 	statistics.ProjectName = template.GetProjectName(logDirPath)
+	statistics.RangeLabel = filter.String()
 
 	stats.PrintStats(statistics)
 	return nil
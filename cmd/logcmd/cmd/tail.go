@@ -1,17 +1,31 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"strconv"
+	"syscall"
+	"time"
 
+	"github.com/aliancn/logcmd/internal/logrecord"
 	"github.com/spf13/cobra"
 )
 
+// tailPollInterval 结构化记录跟踪模式的轮询间隔
+const tailPollInterval = 200 * time.Millisecond
+
 var (
-	tailFollow bool
-	tailLines  int
+	tailFollow     bool
+	tailLines      int
+	tailStream     string
+	tailTimestamps bool
 )
 
 var tailCmd = &cobra.Command{
@@ -20,17 +34,19 @@ var tailCmd = &cobra.Command{
 	Long:  "查看指定任务的日志输出。支持查看最后几行以及实时跟踪日志。",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runTail(args[0])
+		return runTail(cmd, args[0])
 	},
 }
 
 func init() {
 	tailCmd.Flags().BoolVarP(&tailFollow, "follow", "f", false, "实时跟踪日志输出")
 	tailCmd.Flags().IntVarP(&tailLines, "lines", "n", 20, "显示最后几行日志")
+	tailCmd.Flags().StringVar(&tailStream, "stream", "", "仅显示指定输出流 (stdout/stderr/event)，需要结构化记录")
+	tailCmd.Flags().BoolVar(&tailTimestamps, "timestamps", false, "显示每行的输出流与时间偏移，需要结构化记录")
 	rootCmd.AddCommand(tailCmd)
 }
 
-func runTail(idArg string) error {
+func runTail(cmd *cobra.Command, idArg string) error {
 	taskID, err := strconv.Atoi(idArg)
 	if err != nil {
 		return fmt.Errorf("无效的任务ID: %s", idArg)
@@ -60,6 +76,18 @@ func runTail(idArg string) error {
 		return fmt.Errorf("日志文件不存在: %s", task.LogFilePath)
 	}
 
+	if tailStream != "" || tailTimestamps {
+		if err := validateStream(tailStream); err != nil {
+			return err
+		}
+		if !logrecord.Exists(task.LogFilePath) {
+			return fmt.Errorf("任务 #%d 没有结构化记录，请使用 run --structured 运行", task.ID)
+		}
+		ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+		return tailRecords(ctx, logrecord.PathFor(task.LogFilePath))
+	}
+
 	tailArgs := []string{"-n", strconv.Itoa(tailLines)}
 	if tailFollow {
 		tailArgs = append(tailArgs, "-f")
@@ -93,3 +121,82 @@ func runTail(idArg string) error {
 
 	return nil
 }
+
+// tailRecords 显示结构化记录的最后几行，并可持续跟踪新记录
+func tailRecords(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("打开结构化记录失败: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var recent []*logrecord.Record
+	var partial []byte
+
+	// readAvailable 读取当前可用的完整记录，不完整的行留待下次读取
+	readAvailable := func(emit func(*logrecord.Record)) error {
+		for {
+			chunk, err := reader.ReadBytes('\n')
+			partial = append(partial, chunk...)
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return err
+			}
+
+			line := bytes.TrimSpace(partial)
+			partial = partial[:0]
+			if len(line) == 0 {
+				continue
+			}
+			record, err := logrecord.Decode(line)
+			if err != nil {
+				return err
+			}
+			if tailStream != "" && record.Stream != tailStream {
+				continue
+			}
+			emit(record)
+		}
+	}
+
+	err = readAvailable(func(record *logrecord.Record) {
+		recent = append(recent, record)
+		if tailLines >= 0 && len(recent) > tailLines {
+			recent = recent[1:]
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("读取结构化记录失败: %w", err)
+	}
+	for _, record := range recent {
+		printTailRecord(record)
+	}
+
+	if !tailFollow {
+		return nil
+	}
+
+	ticker := time.NewTicker(tailPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := readAvailable(printTailRecord); err != nil {
+				return fmt.Errorf("读取结构化记录失败: %w", err)
+			}
+		}
+	}
+}
+
+func printTailRecord(record *logrecord.Record) {
+	if tailTimestamps {
+		fmt.Printf("[+%s %s] %s\n", formatOffset(record.Offset()), record.Stream, record.Line)
+		return
+	}
+	fmt.Println(record.Line)
+}
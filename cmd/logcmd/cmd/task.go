@@ -5,12 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/signal"
 	"strconv"
 	"strings"
 	"syscall"
 
 	"github.com/aliancn/logcmd/internal/config"
+	"github.com/aliancn/logcmd/internal/execctx"
+	"github.com/aliancn/logcmd/internal/executor"
 	"github.com/aliancn/logcmd/internal/logger"
 	"github.com/aliancn/logcmd/internal/model"
 	"github.com/aliancn/logcmd/internal/persistence"
@@ -199,18 +200,24 @@ func stopTask(idArg string, force bool) error {
 		return nil
 	}
 
-	if task.PID != nil && *task.PID > 0 {
-		proc, findErr := os.FindProcess(int(*task.PID))
-		if findErr == nil {
-			if force {
-				_ = proc.Kill()
-			} else {
-				termErr := proc.Signal(os.Interrupt)
-				if termErr != nil && !errors.Is(termErr, os.ErrProcessDone) {
-					_ = proc.Kill()
-				}
-			}
+	if force {
+		// 强制终止：直接结束命令进程组以及后台 worker，避免遗留子进程
+		signalTaskGroup(task, os.Kill)
+		if worker := findTaskWorker(task); worker != nil {
+			_ = worker.Kill()
+		}
+	} else if worker := findTaskWorker(task); worker != nil {
+		// 优雅停止：由 worker 将 SIGTERM 转发给命令进程组，宽限期后再强制结束
+		switch termErr := worker.Signal(syscall.SIGTERM); {
+		case termErr == nil:
+		case errors.Is(termErr, os.ErrProcessDone):
+			// worker 已不存在，直接通知命令进程组
+			signalTaskGroup(task, syscall.SIGTERM)
+		default:
+			signalTaskGroup(task, os.Kill)
 		}
+	} else {
+		signalTaskGroup(task, syscall.SIGTERM)
 	}
 
 	action := "停止"
@@ -228,6 +235,28 @@ func stopTask(idArg string, force bool) error {
 	return nil
 }
 
+// findTaskWorker 返回任务的后台 worker 进程，没有记录 PID 时返回 nil
+func findTaskWorker(task *model.Task) *os.Process {
+	if task.PID == nil || *task.PID <= 0 {
+		return nil
+	}
+	process, err := os.FindProcess(int(*task.PID))
+	if err != nil {
+		return nil
+	}
+	return process
+}
+
+// signalTaskGroup 向任务命令所在的进程组发送信号
+func signalTaskGroup(task *model.Task, sig os.Signal) {
+	if task.ProcessGroup == nil || *task.ProcessGroup <= 0 {
+		return
+	}
+	if err := executor.SignalProcessGroup(int(*task.ProcessGroup), sig); err != nil {
+		fmt.Fprintf(os.Stderr, "向进程组 %d 发送信号失败: %v\n", *task.ProcessGroup, err)
+	}
+}
+
 func runTaskWorker(idArg string) (retErr error) {
 	taskID, err := strconv.Atoi(idArg)
 	if err != nil {
@@ -272,12 +301,18 @@ func runTaskWorker(idArg string) (retErr error) {
 		exitCode = -1
 		logPath  = ""
 		errMsg   = ""
+		reason   = ""
 	)
 
 	defer func() {
 		if retErr != nil && errMsg == "" {
 			errMsg = retErr.Error()
 		}
+		if reason != "" {
+			if err := manager.UpdateTerminationReason(task.ID, reason); err != nil {
+				fmt.Fprintf(os.Stderr, "警告: 记录终止原因失败: %v\n", err)
+			}
+		}
 		_ = manager.MarkCompletion(task.ID, status, exitCode, logPath, errMsg)
 	}()
 
@@ -288,6 +323,15 @@ func runTaskWorker(idArg string) (retErr error) {
 	if task.LogDir != "" {
 		cfg.LogDir = task.LogDir
 	}
+	runOptions, err := config.DecodeRunOptions(task.OptionsJSON)
+	if err != nil {
+		return err
+	}
+	cfg.Run = runOptions
+	// 后台任务默认采样资源占用，便于事后查看长时间运行命令的 CPU 与内存变化
+	if cfg.Run.SampleInterval == 0 {
+		cfg.Run.SampleInterval = executor.DefaultSampleInterval
+	}
 
 	// 预先生成并记录日志路径，以便 tail 命令可以立即查看
 	cfg.Command = task.Command
@@ -308,21 +352,43 @@ func runTaskWorker(idArg string) (retErr error) {
 	if err != nil {
 		return fmt.Errorf("创建日志记录器失败: %w", err)
 	}
+	execContext, err := execctx.Decode(task.ContextJSON)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "警告: %v\n", err)
+	}
+	log.SetContext(execContext)
 	log.SetLogPath(preLogPath)
+	log.SetOnAttempt(func(attempt int, path string) {
+		if attempt > 1 {
+			if err := manager.UpdateLogFilePath(task.ID, path); err != nil {
+				fmt.Fprintf(os.Stderr, "警告: 更新日志路径失败: %v\n", err)
+			}
+		}
+	})
+	log.SetOnStart(func(pgid int) {
+		if err := manager.UpdateProcessGroup(task.ID, pgid); err != nil {
+			fmt.Fprintf(os.Stderr, "警告: 记录进程组失败: %v\n", err)
+		}
+	})
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer cancel()
-
-	result, path, runErr := log.Run(ctx, task.Command, task.CommandArgs...)
+	// worker 收到的信号由执行器转发给命令进程组
+	result, path, runErr := log.Run(context.Background(), task.Command, task.CommandArgs...)
 	logPath = path
 	if result != nil {
 		exitCode = result.ExitCode
+		reason = result.Reason
 	}
 
 	if runErr != nil {
-		if ctx.Err() == context.Canceled {
+		if result != nil && result.Reason == executor.ReasonIdleTimeout {
+			status = model.TaskStatusTimeout
+			errMsg = fmt.Sprintf("任务超过 %v 无输出，已被看门狗终止", cfg.Run.IdleTimeout)
+		} else if result != nil && result.Status == executor.StatusTimeout {
+			status = model.TaskStatusTimeout
+			errMsg = fmt.Sprintf("任务执行超时 (%v)", cfg.Run.Timeout)
+		} else if result != nil && result.Signal != "" {
 			status = model.TaskStatusStopped
-			errMsg = "任务已被终止"
+			errMsg = fmt.Sprintf("任务已被信号 %s 终止", result.Signal)
 		} else {
 			status = model.TaskStatusFailed
 			errMsg = runErr.Error()
@@ -5,9 +5,13 @@ import (
 	"os"
 
 	"github.com/aliancn/logcmd/cmd/logcmd/cmd"
+	"github.com/aliancn/logcmd/internal/sandbox"
 )
 
 func main() {
+	// 作为沙箱辅助进程启动时应用限制并执行目标命令，不会返回
+	sandbox.Init()
+
 	if err := cmd.Execute(); err != nil {
 		if exitErr, ok := err.(interface{ ExitCode() int }); ok {
 			if err.Error() != "" {
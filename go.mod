@@ -3,8 +3,12 @@ module github.com/aliancn/logcmd
 go 1.25.0
 
 require (
+	github.com/creack/pty v1.1.24
 	github.com/mattn/go-sqlite3 v1.14.32
 	github.com/spf13/cobra v1.8.0
+	golang.org/x/sys v0.41.0
+	golang.org/x/term v0.40.0
+	golang.org/x/text v0.34.0
 )
 
 require (
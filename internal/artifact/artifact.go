@@ -0,0 +1,250 @@
+// Package artifact 在命令结束后收集其产出的文件（覆盖率报告、构建产物等），与运行记录一起保存
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// externalDir 工作目录之外的文件在产物目录中的上级目录，其下按源目录的哈希区分同名文件
+const externalDir = "_external"
+
+// DirName 日期目录下保存产物的目录名，统计和搜索日志时跳过其中的文件
+const DirName = "artifacts"
+
+// Artifact 收集到的一个产物
+type Artifact struct {
+	Name   string `json:"name"`   // 产物目录中的相对路径，通常与相对工作目录的源路径相同
+	Source string `json:"source"` // 收集时的源文件路径
+	Path   string `json:"path"`   // 保存后的文件路径
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// DirFor 返回日志对应的产物目录，位于日志所在日期目录的 artifacts 下，
+// 如 2024-01-01/build_120000.log -> 2024-01-01/artifacts/build_120000
+func DirFor(logPath string) string {
+	base := strings.TrimSuffix(filepath.Base(logPath), ".log")
+	return filepath.Join(filepath.Dir(logPath), DirName, base)
+}
+
+// Collect 将 root 下与 specs 匹配的文件保存到 destDir
+// spec 可以是 glob（如 coverage/*.html）或目录（如 dist/），目录中的文件全部收集。
+// 文件系统支持时以 reflink 方式保存，否则复制；保存的产物都是收集时的快照。
+// exclude 中的目录（如日志目录）及其下的文件不会被收集；返回的 unmatched 为没有匹配任何文件的 spec。
+func Collect(root string, specs []string, destDir string, exclude ...string) (collected []Artifact, unmatched []string, err error) {
+	sources, unmatched, err := resolve(root, specs, exclude)
+	if err != nil {
+		return nil, unmatched, err
+	}
+	if len(sources) == 0 {
+		return nil, unmatched, nil
+	}
+
+	for _, src := range sources {
+		name := src.name
+		dest := filepath.Join(destDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return collected, unmatched, fmt.Errorf("创建产物目录失败: %w", err)
+		}
+		size, sum, err := store(src.path, dest)
+		if err != nil {
+			return collected, unmatched, fmt.Errorf("保存产物 %s 失败: %w", name, err)
+		}
+		collected = append(collected, Artifact{Name: name, Source: src.path, Path: dest, Size: size, SHA256: sum})
+	}
+	return collected, unmatched, nil
+}
+
+type source struct {
+	name string
+	path string
+	size int64
+}
+
+// resolve 展开 specs 为去重后的文件列表，按名称排序，跳过 .logcmd 和 exclude 中的目录
+func resolve(root string, specs []string, exclude []string) ([]source, []string, error) {
+	seen := make(map[string]bool)
+	var (
+		sources   []source
+		unmatched []string
+		skip      []string
+	)
+	for _, p := range exclude {
+		if p == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(p); err == nil {
+			skip = append(skip, abs)
+		}
+	}
+	excluded := func(abs string) bool {
+		for _, dir := range skip {
+			if abs == dir || strings.HasPrefix(abs, dir+string(filepath.Separator)) {
+				return true
+			}
+		}
+		return false
+	}
+	add := func(p string, info fs.FileInfo) {
+		abs, err := filepath.Abs(p)
+		if err != nil || seen[abs] || excluded(abs) {
+			return
+		}
+		seen[abs] = true
+		sources = append(sources, source{name: artifactName(root, abs), path: abs, size: info.Size()})
+	}
+
+	for _, spec := range specs {
+		pattern := spec
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(root, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("无效的产物规则 %q: %w", spec, err)
+		}
+
+		before := len(sources)
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				continue
+			}
+			if !info.IsDir() {
+				if info.Mode().IsRegular() {
+					add(match, info)
+				}
+				continue
+			}
+			_ = filepath.WalkDir(match, func(p string, d fs.DirEntry, err error) error {
+				switch {
+				case err != nil:
+				case d.IsDir() && (d.Name() == ".logcmd" || excluded(p)):
+					// 不收集日志目录自身
+					return filepath.SkipDir
+				case d.Type().IsRegular():
+					if info, err := d.Info(); err == nil {
+						add(p, info)
+					}
+				}
+				return nil
+			})
+		}
+		if len(sources) == before {
+			unmatched = append(unmatched, spec)
+		}
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].name < sources[j].name
+	})
+	return sources, unmatched, nil
+}
+
+// artifactName 返回产物在产物目录中的相对路径
+// 工作目录之外的文件放在 _external/<源目录哈希>/ 下，不同目录中的同名文件不会互相覆盖
+func artifactName(root, abs string) string {
+	if absRoot, err := filepath.Abs(root); err == nil {
+		if rel, err := filepath.Rel(absRoot, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	sum := sha256.Sum256([]byte(filepath.Dir(abs)))
+	return externalDir + "/" + hex.EncodeToString(sum[:4]) + "/" + filepath.Base(abs)
+}
+
+// store 将 src 以 reflink 方式或复制保存到 dest，返回大小和 SHA-256
+// 不使用硬链接：硬链接与源文件共享 inode，命令之后原地修改源文件会改变已保存的产物
+func store(src, dest string) (int64, string, error) {
+	_ = os.Remove(dest)
+	if err := cloneFile(src, dest); err == nil {
+		return checksum(dest)
+	}
+	return copyFile(src, dest)
+}
+
+func copyFile(src, dest string) (int64, string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, "", err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return 0, "", err
+	}
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return 0, "", err
+	}
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, h), in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func checksum(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Verify 检查保存的产物是否仍然存在且内容未被修改
+func Verify(a Artifact) error {
+	size, sum, err := checksum(a.Path)
+	if err != nil {
+		return fmt.Errorf("读取产物失败: %w", err)
+	}
+	if size != a.Size || sum != a.SHA256 {
+		return fmt.Errorf("产物 %s 的校验和不一致，文件可能已被修改", a.Name)
+	}
+	return nil
+}
+
+// Extract 将产物复制到 outDir 下的同名路径，复制前校验内容
+func Extract(a Artifact, outDir string) (string, error) {
+	if err := Verify(a); err != nil {
+		return "", err
+	}
+	dest := filepath.Join(outDir, filepath.FromSlash(a.Name))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", fmt.Errorf("创建目录失败: %w", err)
+	}
+	if _, _, err := copyFile(a.Path, dest); err != nil {
+		return "", fmt.Errorf("复制产物 %s 失败: %w", a.Name, err)
+	}
+	return dest, nil
+}
+
+// TotalSize 返回产物的总大小
+func TotalSize(list []Artifact) int64 {
+	var total int64
+	for _, a := range list {
+		total += a.Size
+	}
+	return total
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package artifact
+
+import "errors"
+
+// cloneFile 当前平台不支持 reflink，调用方改为复制
+func cloneFile(src, dest string) error {
+	return errors.New("reflink 不受支持")
+}
@@ -0,0 +1,302 @@
+// Package asciicast 以 asciicast v2 格式录制和回放终端输出
+// 格式说明见 https://docs.asciinema.org/manual/asciicast/v2/
+package asciicast
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// 事件类型
+const (
+	EventOutput = "o" // 终端输出
+	EventMarker = "m" // 标记（如命令通过 LOGCMD_EVENTS 发送的阶段）
+)
+
+// 未能获取终端大小时使用的默认值
+const (
+	DefaultWidth  = 80
+	DefaultHeight = 24
+)
+
+// Header 录制文件的第一行
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Command   string            `json:"command,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// PathFor 返回日志文件对应的录制文件路径
+func PathFor(logPath string) string {
+	return logPath + ".cast"
+}
+
+// TerminalSize 返回当前终端的列数和行数，标准输出、标准错误和标准输入都不是终端时返回默认值
+func TerminalSize() (int, int) {
+	for _, f := range []*os.File{os.Stdout, os.Stderr, os.Stdin} {
+		if width, height, err := term.GetSize(int(f.Fd())); err == nil && width > 0 && height > 0 {
+			return width, height
+		}
+	}
+	return DefaultWidth, DefaultHeight
+}
+
+// Writer 写入 asciicast v2 录制，可被多个输出流并发使用
+type Writer struct {
+	mu     sync.Mutex
+	buf    *bufio.Writer
+	header Header
+	start  time.Time
+	err    error
+
+	limit     int64 // 录制的输出字节数上限，0 表示不限制
+	written   int64 // 已录制的输出字节数
+	truncated bool  // 已超过上限，之后的输出不再录制
+}
+
+// NewWriter 创建录制写入器，header 中的 Version 与 Timestamp 在 Start 时填写
+func NewWriter(w io.Writer, header Header) *Writer {
+	if header.Width <= 0 {
+		header.Width = DefaultWidth
+	}
+	if header.Height <= 0 {
+		header.Height = DefaultHeight
+	}
+	return &Writer{buf: bufio.NewWriter(w), header: header}
+}
+
+// Start 写入文件头，start 为命令开始时间，之后事件的时间均相对于它
+func (w *Writer) Start(start time.Time) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.start = start
+	w.header.Version = 2
+	w.header.Timestamp = start.Unix()
+	data, err := marshal(w.header)
+	if err != nil {
+		return fmt.Errorf("编码录制文件头失败: %w", err)
+	}
+	return w.write(append(data, '\n'))
+}
+
+// StreamWriter 单个输出流的写入器
+type StreamWriter interface {
+	io.Writer
+	Flush() error
+}
+
+// Stream 返回单个输出流的写入器，跨写入切开的 UTF-8 字符会合并到下一次写入
+func (w *Writer) Stream() StreamWriter {
+	return &streamWriter{parent: w}
+}
+
+// Limit 限制录制的输出字节数，超过后写入一行提示并丢弃之后的输出，标记不受限制
+func (w *Writer) Limit(max int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.limit = max
+}
+
+// Marker 写入一个标记事件
+func (w *Writer) Marker(label string) error {
+	return w.event(EventMarker, label)
+}
+
+// Flush 将缓冲的事件写入底层文件
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	return w.buf.Flush()
+}
+
+func (w *Writer) event(kind, data string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.limit > 0 && kind == EventOutput {
+		if w.truncated {
+			return nil
+		}
+		if w.written+int64(len(data)) > w.limit {
+			w.truncated = true
+			data = "\r\n[logcmd] 输出超过大小上限，之后的输出不再录制\r\n"
+		} else {
+			w.written += int64(len(data))
+		}
+	}
+
+	encoded, err := marshal(data)
+	if err != nil {
+		return err
+	}
+	offset := time.Since(w.start).Seconds()
+	return w.write([]byte(fmt.Sprintf("[%.6f, %q, %s]\n", offset, kind, encoded)))
+}
+
+// marshal 编码为 JSON，不转义 <、>、&，使录制文件中的命令和输出保持可读
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// write 记录第一次写入错误，之后的事件直接丢弃
+func (w *Writer) write(p []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+	if _, err := w.buf.Write(p); err != nil {
+		w.err = fmt.Errorf("写入录制文件失败: %w", err)
+	}
+	return w.err
+}
+
+type streamWriter struct {
+	parent  *Writer
+	pending []byte
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	data := append(s.pending, p...)
+	s.pending = nil
+
+	// 末尾不完整的 UTF-8 字符留到下一次写入，最多保留 3 个字节
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax+1; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	s.pending = append(s.pending, data[cut:]...)
+	if cut == 0 {
+		return len(p), nil
+	}
+	if err := s.parent.event(EventOutput, string(data[:cut])); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush 写出残留的不完整字符
+func (s *streamWriter) Flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	data := string(s.pending)
+	s.pending = nil
+	return s.parent.event(EventOutput, data)
+}
+
+// PlayOptions 回放选项
+type PlayOptions struct {
+	Speed     float64       // 回放速度倍数，<= 0 表示 1 倍
+	IdleLimit time.Duration // 两次输出之间的最长等待，0 表示按原始间隔
+}
+
+// Play 按原始时间间隔将录制中的输出写入 out，返回录制文件头
+// ctx 取消时停止回放并返回 ctx.Err()
+func Play(ctx context.Context, r io.Reader, out io.Writer, opts PlayOptions) (*Header, error) {
+	speed := opts.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+
+	reader := bufio.NewReader(r)
+	line, err := reader.ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return nil, fmt.Errorf("读取录制文件头失败: %w", err)
+	}
+	var header Header
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, fmt.Errorf("解析录制文件头失败: %w", err)
+	}
+	if header.Version != 2 {
+		return &header, fmt.Errorf("不支持的录制格式版本: %d", header.Version)
+	}
+
+	var last float64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			offset, kind, data, decodeErr := decodeEvent(line)
+			if decodeErr != nil {
+				return &header, decodeErr
+			}
+
+			delay := time.Duration((offset - last) / speed * float64(time.Second))
+			if opts.IdleLimit > 0 && delay > opts.IdleLimit {
+				delay = opts.IdleLimit
+			}
+			last = offset
+			if delay > 0 {
+				timer := time.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return &header, ctx.Err()
+				case <-timer.C:
+				}
+			}
+
+			if kind == EventOutput {
+				if _, err := io.WriteString(out, data); err != nil {
+					return &header, err
+				}
+			}
+		}
+		if err == io.EOF {
+			return &header, nil
+		}
+		if err != nil {
+			return &header, fmt.Errorf("读取录制文件失败: %w", err)
+		}
+	}
+}
+
+// decodeEvent 解析 [time, type, data] 形式的事件
+func decodeEvent(line []byte) (float64, string, string, error) {
+	var fields []json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil || len(fields) != 3 {
+		return 0, "", "", fmt.Errorf("解析录制事件失败: %s", bytes.TrimSpace(line))
+	}
+	var (
+		offset float64
+		kind   string
+		data   string
+	)
+	if err := json.Unmarshal(fields[0], &offset); err != nil {
+		return 0, "", "", fmt.Errorf("解析录制事件时间失败: %w", err)
+	}
+	if err := json.Unmarshal(fields[1], &kind); err != nil {
+		return 0, "", "", fmt.Errorf("解析录制事件类型失败: %w", err)
+	}
+	if err := json.Unmarshal(fields[2], &data); err != nil {
+		return 0, "", "", fmt.Errorf("解析录制事件内容失败: %w", err)
+	}
+	return offset, kind, data, nil
+}
@@ -0,0 +1,288 @@
+package charset
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/transform"
+)
+
+// 支持的输入编码
+const (
+	Auto        = "auto" // 根据输出内容自动识别
+	UTF8        = "utf-8"
+	GBK         = "gbk"
+	GB18030     = "gb18030"
+	Big5        = "big5"
+	ShiftJIS    = "shift_jis"
+	EUCKR       = "euc-kr"
+	Latin1      = "latin1"
+	Windows1252 = "windows-1252"
+)
+
+var encodings = map[string]encoding.Encoding{
+	UTF8:        encoding.Nop,
+	GBK:         simplifiedchinese.GBK,
+	GB18030:     simplifiedchinese.GB18030,
+	Big5:        traditionalchinese.Big5,
+	ShiftJIS:    japanese.ShiftJIS,
+	EUCKR:       korean.EUCKR,
+	Latin1:      charmap.ISO8859_1,
+	Windows1252: charmap.Windows1252,
+}
+
+var aliases = map[string]string{
+	"utf8":       UTF8,
+	"cp936":      GBK,
+	"gb2312":     GBK,
+	"sjis":       ShiftJIS,
+	"shift-jis":  ShiftJIS,
+	"iso-8859-1": Latin1,
+	"latin-1":    Latin1,
+	"cp1252":     Windows1252,
+}
+
+// Names 返回所有可用的编码名称
+func Names() []string {
+	return []string{Auto, UTF8, GBK, GB18030, Big5, ShiftJIS, EUCKR, Latin1, Windows1252}
+}
+
+// Normalize 返回编码的规范名称，不支持的编码返回错误
+func Normalize(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+	if _, ok := encodings[name]; ok || name == Auto {
+		return name, nil
+	}
+	return "", fmt.Errorf("不支持的编码 %q，可选值: %s", name, strings.Join(Names(), ", "))
+}
+
+// Detect 根据样本猜测编码：合法的 UTF-8 优先，其次是 GBK/GB18030，都不符合时按 Latin-1 处理
+// 样本末尾被截断的多字节字符不影响判断
+func Detect(sample []byte) string {
+	if validUTF8(sample) {
+		return UTF8
+	}
+	if ok, fourByte := validGB18030(sample); ok {
+		if fourByte {
+			return GB18030
+		}
+		return GBK
+	}
+	return Latin1
+}
+
+// validUTF8 判断样本是否为 UTF-8，允许末尾有不完整的字符
+func validUTF8(p []byte) bool {
+	for len(p) > 0 {
+		r, size := utf8.DecodeRune(p)
+		if r == utf8.RuneError && size <= 1 {
+			return !utf8.FullRune(p)
+		}
+		p = p[size:]
+	}
+	return true
+}
+
+// validGB18030 判断样本是否符合 GB18030 的字节结构，fourByte 表示出现了 GBK 之外的四字节字符
+func validGB18030(p []byte) (ok, fourByte bool) {
+	for i := 0; i < len(p); {
+		b := p[i]
+		switch {
+		case b < 0x80:
+			i++
+			continue
+		case b == 0x80 || b == 0xff:
+			return false, false
+		}
+		if i+1 >= len(p) {
+			return true, fourByte
+		}
+		c := p[i+1]
+		switch {
+		case c >= 0x40 && c <= 0xfe && c != 0x7f:
+			i += 2
+		case c >= 0x30 && c <= 0x39:
+			if i+3 >= len(p) {
+				return true, true
+			}
+			if p[i+2] < 0x81 || p[i+2] > 0xfe || p[i+3] < 0x30 || p[i+3] > 0x39 {
+				return false, false
+			}
+			fourByte = true
+			i += 4
+		default:
+			return false, false
+		}
+	}
+	return true, fourByte
+}
+
+// 自动识别时至少观察到这么多非 ASCII 字节才做判断，或者缓存达到上限
+const (
+	minSample = 64
+	maxSample = 16 * 1024
+)
+
+// Decoder 将命令输出转换为 UTF-8，同一次运行的 stdout/stderr 共享识别结果
+type Decoder struct {
+	mu   sync.Mutex
+	name string // 配置的编码，可能为 auto
+	used string // 实际使用的编码，自动识别前为空
+}
+
+// NewDecoder 创建指定编码的转换器，name 为 auto 时根据输出内容识别
+func NewDecoder(name string) (*Decoder, error) {
+	name, err := Normalize(name)
+	if err != nil {
+		return nil, err
+	}
+	d := &Decoder{name: name}
+	if name != Auto {
+		d.used = name
+	}
+	return d, nil
+}
+
+// Encoding 返回输出的原始编码，自动识别时若输出全是 ASCII 则返回空字符串
+func (d *Decoder) Encoding() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.used
+}
+
+// resolve 返回已确定的编码，尚未确定时以 sample 识别（sample 为 nil 时不识别）
+func (d *Decoder) resolve(sample []byte) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.used == "" && sample != nil {
+		d.used = Detect(sample)
+	}
+	return d.used
+}
+
+// Writer 将写入的内容转换为 UTF-8 后转发给下游
+// 跨越多次 Write 的多字节字符会缓存到下次写入
+type Writer struct {
+	d       *Decoder
+	w       io.Writer
+	t       transform.Transformer // 编码确定前为 nil
+	pending []byte
+}
+
+// NewWriter 创建转换写入器，输出结束后需调用 Flush 写出残留内容
+func (d *Decoder) NewWriter(w io.Writer) *Writer {
+	return &Writer{d: d, w: w}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	if w.t == nil {
+		ok, err := w.ready(false)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			return len(p), nil
+		}
+	}
+	if err := w.convert(false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush 写出残留内容并刷新下游
+func (w *Writer) Flush() error {
+	if len(w.pending) > 0 {
+		ok := w.t != nil
+		if !ok {
+			var err error
+			if ok, err = w.ready(true); err != nil {
+				return err
+			}
+		}
+		if ok {
+			if err := w.convert(true); err != nil {
+				return err
+			}
+		}
+	}
+	if f, ok := w.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Sync 输出中途同步下游；Write 已经写出全部完整的字符，不完整的多字节字符和识别编码的样本继续缓存
+func (w *Writer) Sync() error {
+	if s, ok := w.w.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// ready 确定编码并准备转换器，自动识别的样本不足时返回 false
+// ASCII 在所有支持的编码中含义相同，识别前的 ASCII 内容直接写出
+func (w *Writer) ready(final bool) (bool, error) {
+	name := w.d.resolve(nil)
+	if name == "" {
+		i := bytes.IndexFunc(w.pending, func(r rune) bool { return r >= utf8.RuneSelf })
+		if i < 0 {
+			_, err := w.w.Write(w.pending)
+			w.pending = w.pending[:0]
+			return false, err
+		}
+		nonASCII := 0
+		for _, b := range w.pending[i:] {
+			if b >= utf8.RuneSelf {
+				nonASCII++
+			}
+		}
+		if !final && nonASCII < minSample && len(w.pending) < maxSample {
+			return false, nil
+		}
+		name = w.d.resolve(w.pending[i:])
+	}
+	w.t = encodings[name].NewDecoder()
+	return true, nil
+}
+
+// convert 转换缓存的内容，非 final 时保留末尾不完整的字符
+func (w *Writer) convert(final bool) error {
+	out := make([]byte, 0, len(w.pending)*3/2+16)
+	buf := make([]byte, 32*1024)
+	src := w.pending
+	for {
+		nDst, nSrc, err := w.t.Transform(buf, src, final)
+		out = append(out, buf[:nDst]...)
+		src = src[nSrc:]
+		if errors.Is(err, transform.ErrShortDst) {
+			continue
+		}
+		if err != nil && !errors.Is(err, transform.ErrShortSrc) {
+			return fmt.Errorf("转换输出编码失败: %w", err)
+		}
+		break
+	}
+	w.pending = append(w.pending[:0], src...)
+
+	if len(out) == 0 {
+		return nil
+	}
+	_, err := w.w.Write(out)
+	return err
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aliancn/logcmd/internal/sandbox"
 	"github.com/aliancn/logcmd/internal/template"
 )
 
@@ -20,6 +21,17 @@ type Config struct {
 	TimeFormat   string         // 时间格式
 	Command      string         // 当前执行的命令
 	CommandArgs  []string       // 命令参数
+	Run          RunOptions     // 运行选项
+
+	EnvAllow       []string // 环境快照只记录匹配的变量，为空表示全部记录
+	EnvDeny        []string // 环境快照不记录匹配的变量
+	Redact         bool     // 是否对写入日志的输出脱敏
+	RedactPatterns []string // 用户自定义的脱敏正则
+	LogTransforms  []string // 写入日志前依次应用的转换，终端输出不受影响
+	DropPatterns   []string // drop_lines 转换丢弃的行
+	Shell          string   // run --shell 使用的 shell，为空时使用 $SHELL
+
+	SandboxProfiles map[string]sandbox.Profile // 配置文件中定义的沙箱配置，局部配置覆盖全局配置中的同名配置
 }
 
 // Load 加载配置
@@ -60,6 +72,83 @@ func mergeConfig(dst *Config, src *PersistentConfig) {
 	if src.TimeFormat != "" {
 		dst.TimeFormat = src.TimeFormat
 	}
+	if src.PTY != nil {
+		dst.Run.PTY = *src.PTY
+	}
+	if src.StructuredLog != nil {
+		dst.Run.StructuredLog = *src.StructuredLog
+	}
+	if src.Record != nil {
+		dst.Run.Record = *src.Record
+	}
+	if src.RawLog != nil {
+		dst.Run.RawLog = *src.RawLog
+	}
+	if src.Events != nil {
+		dst.Run.Events = *src.Events
+	}
+	if src.GracePeriod != "" {
+		if d, err := time.ParseDuration(src.GracePeriod); err == nil && d > 0 {
+			dst.Run.GracePeriod = d
+		}
+	}
+	if src.Timeout != "" {
+		if d, err := time.ParseDuration(src.Timeout); err == nil && d >= 0 {
+			dst.Run.Timeout = d
+		}
+	}
+	if src.IdleTimeout != "" {
+		if d, err := time.ParseDuration(src.IdleTimeout); err == nil && d >= 0 {
+			dst.Run.IdleTimeout = d
+		}
+	}
+	if src.SampleInterval != "" {
+		if d, err := time.ParseDuration(src.SampleInterval); err == nil && d >= 0 {
+			dst.Run.SampleInterval = d
+		}
+	}
+	if src.EnvAllow != nil {
+		dst.EnvAllow = src.EnvAllow
+	}
+	if src.EnvDeny != nil {
+		dst.EnvDeny = src.EnvDeny
+	}
+	if src.Redact != nil {
+		dst.Redact = *src.Redact
+	}
+	// 脱敏规则累加，局部配置不能取消全局配置要求隐藏的内容
+	dst.RedactPatterns = append(dst.RedactPatterns, src.RedactPatterns...)
+	if src.MaxLogSize != "" {
+		if n, err := ParseSize(src.MaxLogSize); err == nil {
+			dst.Run.MaxLogSize = n
+		}
+	}
+	if src.LogTailSize != "" {
+		if n, err := ParseSize(src.LogTailSize); err == nil {
+			dst.Run.LogTailSize = n
+		}
+	}
+	if src.Shell != "" {
+		dst.Shell = src.Shell
+	}
+	if src.InputEncoding != "" {
+		dst.Run.InputEncoding = src.InputEncoding
+	}
+	if src.Output != "" {
+		dst.Run.Output = src.Output
+	}
+	if src.LogTransforms != nil {
+		dst.LogTransforms = src.LogTransforms
+	}
+	if src.DropPatterns != nil {
+		dst.DropPatterns = src.DropPatterns
+	}
+	for name, profile := range src.SandboxProfiles {
+		if dst.SandboxProfiles == nil {
+			dst.SandboxProfiles = make(map[string]sandbox.Profile)
+		}
+		dst.SandboxProfiles[name] = profile
+	}
 }
 
 // DefaultConfig 返回默认配置
@@ -76,6 +165,7 @@ func DefaultConfig() *Config {
 		BufferSize:   8192,
 		AutoCompress: false,
 		TimeFormat:   "20060102_150405",
+		Redact:       true,
 	}
 }
 
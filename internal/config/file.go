@@ -5,13 +5,37 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/aliancn/logcmd/internal/sandbox"
 )
 
 // PersistentConfig 定义可持久化的配置项
 type PersistentConfig struct {
-	BufferSize   int    `json:"buffer_size,omitempty"`   // 缓冲区大小
-	AutoCompress *bool  `json:"auto_compress,omitempty"` // 是否自动压缩
-	TimeFormat   string `json:"time_format,omitempty"`   // 时间格式
+	BufferSize     int      `json:"buffer_size,omitempty"`       // 缓冲区大小
+	AutoCompress   *bool    `json:"auto_compress,omitempty"`     // 是否自动压缩
+	TimeFormat     string   `json:"time_format,omitempty"`       // 时间格式
+	PTY            *bool    `json:"pty,omitempty"`               // 是否在伪终端中运行命令
+	StructuredLog  *bool    `json:"structured_log,omitempty"`    // 是否写入结构化逐行记录
+	Record         *bool    `json:"record,omitempty"`            // 是否录制终端输出（asciicast）
+	RawLog         *bool    `json:"raw_log,omitempty"`           // 日志是否只包含命令输出（元数据写入 .meta 文件）
+	Events         *bool    `json:"events,omitempty"`            // 是否导出 LOGCMD_EVENTS 接收命令发送的事件
+	GracePeriod    string   `json:"kill_grace_period,omitempty"` // 终止信号到 SIGKILL 的宽限期（如 10s）
+	Timeout        string   `json:"timeout,omitempty"`           // 命令最长运行时间（如 30m），0 表示不限制
+	IdleTimeout    string   `json:"idle_timeout,omitempty"`      // 命令无输出的最长时间（如 5m），0 表示不限制
+	SampleInterval string   `json:"sample_interval,omitempty"`   // 运行期间资源采样间隔（如 10s）
+	EnvAllow       []string `json:"env_allow,omitempty"`         // 环境快照只记录匹配的变量（支持通配符）
+	EnvDeny        []string `json:"env_deny,omitempty"`          // 环境快照不记录匹配的变量（支持通配符）
+	Redact         *bool    `json:"redact,omitempty"`            // 是否对写入日志的输出脱敏（默认开启）
+	RedactPatterns []string `json:"redact_patterns,omitempty"`   // 额外的脱敏正则，全局与局部配置的规则都会生效
+	LogTransforms  []string `json:"log_transforms,omitempty"`    // 写入日志前依次应用的转换（如 strip_ansi、collapse_cr）
+	DropPatterns   []string `json:"drop_patterns,omitempty"`     // drop_lines 转换丢弃的行（正则）
+	InputEncoding  string   `json:"input_encoding,omitempty"`    // 命令输出的字符编码（如 gbk、auto），写入日志前转换为 UTF-8
+	Shell          string   `json:"shell,omitempty"`             // run --shell 使用的 shell，未设置时使用 $SHELL
+	MaxLogSize     string   `json:"max_log_size,omitempty"`      // 单次运行写入日志的输出上限（如 100MB），0 表示不限制
+	LogTailSize    string   `json:"log_tail_size,omitempty"`     // 超过上限时末尾保留的大小（如 10MB），默认为上限的一半
+	Output         string   `json:"output,omitempty"`            // run 的终端输出模式：normal、quiet、on-failure、summary
+
+	SandboxProfiles map[string]sandbox.Profile `json:"sandbox_profiles,omitempty"` // run --sandbox=<名称> 可用的沙箱配置
 }
 
 // DefaultPersistentConfig 返回默认持久化配置
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aliancn/logcmd/internal/sandbox"
+)
+
+// RunOptions 描述单次命令运行的执行选项
+// 默认值来自配置文件，可被 run 子命令的参数覆盖，后台任务会随任务一起持久化
+type RunOptions struct {
+	PTY            bool             `json:"pty,omitempty"`             // 在伪终端中运行命令
+	StructuredLog  bool             `json:"structured_log,omitempty"`  // 额外写入带输出流和时间偏移的逐行记录
+	Record         bool             `json:"record,omitempty"`          // 将终端输出录制为日志旁的 asciicast 文件
+	RawLog         bool             `json:"raw_log,omitempty"`         // 日志只包含命令输出，头部和尾部元数据写入日志旁的 .meta 文件
+	Events         bool             `json:"events,omitempty"`          // 导出 LOGCMD_EVENTS 并把命令发送的事件写入日志和命令历史
+	GracePeriod    time.Duration    `json:"grace_period,omitempty"`    // 转发终止信号后等待命令退出的时长
+	Timeout        time.Duration    `json:"timeout,omitempty"`         // 命令最长运行时间，0 表示不限制
+	IdleTimeout    time.Duration    `json:"idle_timeout,omitempty"`    // 命令无任何输出的最长时间，0 表示不限制
+	Retries        int              `json:"retries,omitempty"`         // 失败后最多重试的次数
+	RetryOn        []int            `json:"retry_on,omitempty"`        // 仅在这些退出码时重试，为空表示任何失败都重试
+	Backoff        string           `json:"backoff,omitempty"`         // 重试前的等待策略（如 5s、exp:2s..1m）
+	SampleInterval time.Duration    `json:"sample_interval,omitempty"` // 运行期间采样 CPU 与内存的间隔，0 表示不采样（后台任务使用默认间隔）
+	InputEncoding  string           `json:"input_encoding,omitempty"`  // 命令输出的字符编码（如 gbk、auto），写入日志前转换为 UTF-8；为空表示不转换
+	MaxLogSize     int64            `json:"max_log_size,omitempty"`    // 写入日志的输出上限（字节），超过后只保留开头和末尾；0 表示不限制
+	LogTailSize    int64            `json:"log_tail_size,omitempty"`   // 超过上限时末尾保留的字节数，0 表示上限的一半
+	Shell          string           `json:"shell,omitempty"`           // shell 模式下执行脚本的 shell，为空表示直接执行命令
+	Output         string           `json:"output,omitempty"`          // 终端输出模式（quiet、on-failure、summary），为空表示实时回显
+	TrackFiles     string           `json:"track_files,omitempty"`     // 运行前后对工作目录做快照的 glob 规则（逗号分隔），为空表示不跟踪
+	Artifacts      []string         `json:"artifacts,omitempty"`       // 运行结束后收集到产物目录的文件（glob 或目录）
+	RecordStdin    bool             `json:"record_stdin,omitempty"`    // 将转发给命令的标准输入保存到日志旁的 .stdin 文件
+	Sandbox        *sandbox.Profile `json:"sandbox,omitempty"`         // 在沙箱中运行命令使用的配置（可写路径已展开），nil 表示不限制
+}
+
+// EncodeRunOptions 将运行选项序列化为 JSON，便于随后台任务持久化
+func EncodeRunOptions(opts RunOptions) (string, error) {
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return "", fmt.Errorf("序列化运行选项失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// DecodeRunOptions 从 JSON 恢复运行选项，空字符串返回零值
+func DecodeRunOptions(data string) (RunOptions, error) {
+	var opts RunOptions
+	if data == "" {
+		return opts, nil
+	}
+	if err := json.Unmarshal([]byte(data), &opts); err != nil {
+		return opts, fmt.Errorf("解析运行选项失败: %w", err)
+	}
+	return opts, nil
+}
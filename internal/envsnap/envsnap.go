@@ -0,0 +1,158 @@
+package envsnap
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Masked 替换敏感变量值的占位符
+const Masked = "******"
+
+// secretNameRegex 变量名中出现这些词时认为其值是敏感信息
+// auth 和 pwd 只作为完整的单词匹配（NPM_AUTH、DB_PWD），避免误伤 GIT_AUTHOR_NAME、PWD、XDG_SESSION_TYPE 等
+var secretNameRegex = regexp.MustCompile(`(?i)(secret|token|passw(or)?d|passphrase|api_?key|access_?key|private_?key|credential|cookie|authorization|(^|_)auth(_|$)|_pwd(_|$)|session_?(key|secret))`)
+
+// secretValueRegex 形如密钥的值（如 AWS Access Key、GitHub Token、JWT）
+var secretValueRegex = regexp.MustCompile(`^(AKIA[0-9A-Z]{16}|gh[pousr]_[A-Za-z0-9]{36,}|xox[abpr]-[A-Za-z0-9-]+|eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+)$`)
+
+// Options 控制记录哪些环境变量
+// 名称支持通配符（如 GO*、*_PROXY），Allow 为空表示记录全部变量
+type Options struct {
+	Allow []string // 仅记录匹配的变量
+	Deny  []string // 不记录匹配的变量，优先于 Allow
+}
+
+// Capture 按选项过滤环境变量（KEY=VALUE 形式）并对敏感值脱敏
+func Capture(environ []string, opts Options) map[string]string {
+	snapshot := make(map[string]string)
+	for _, entry := range environ {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			continue
+		}
+		if len(opts.Allow) > 0 && !matchAny(opts.Allow, name) {
+			continue
+		}
+		if matchAny(opts.Deny, name) {
+			continue
+		}
+		snapshot[name] = Mask(name, value)
+	}
+	return snapshot
+}
+
+// Mask 对看起来像密钥的变量值脱敏，URL 中的密码只替换密码部分
+func Mask(name, value string) string {
+	if value == "" {
+		return value
+	}
+	if secretNameRegex.MatchString(name) || secretValueRegex.MatchString(value) {
+		return Masked
+	}
+	if strings.Contains(value, "://") && strings.Contains(value, "@") {
+		if u, err := url.Parse(value); err == nil && u.User != nil {
+			if _, hasPassword := u.User.Password(); hasPassword {
+				// url.UserPassword 会把占位符转义成 %2A，这里手动拼接以便阅读
+				u.User = url.User(u.User.Username())
+				user := u.User.String() + "@"
+				return strings.Replace(u.String(), user, u.User.String()+":"+Masked+"@", 1)
+			}
+		}
+	}
+	return value
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Encode 将快照序列化为 JSON，空快照返回空字符串
+func Encode(snapshot map[string]string) (string, error) {
+	if len(snapshot) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return "", fmt.Errorf("序列化环境变量失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// Decode 从 JSON 恢复快照，空字符串返回空快照
+func Decode(data string) (map[string]string, error) {
+	snapshot := make(map[string]string)
+	if data == "" {
+		return snapshot, nil
+	}
+	if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+		return nil, fmt.Errorf("解析环境变量失败: %w", err)
+	}
+	return snapshot, nil
+}
+
+// 变更类型
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Change 两次快照之间一个变量的差异
+type Change struct {
+	Name string
+	Kind string // added/removed/changed
+	Old  string // 在 a 中的值
+	New  string // 在 b 中的值
+}
+
+// Diff 按变量名顺序返回从 a 到 b 的差异
+func Diff(a, b map[string]string) []Change {
+	names := make(map[string]struct{}, len(a)+len(b))
+	for name := range a {
+		names[name] = struct{}{}
+	}
+	for name := range b {
+		names[name] = struct{}{}
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var changes []Change
+	for _, name := range sorted {
+		oldValue, inA := a[name]
+		newValue, inB := b[name]
+		switch {
+		case inA && !inB:
+			changes = append(changes, Change{Name: name, Kind: Removed, Old: oldValue})
+		case !inA && inB:
+			changes = append(changes, Change{Name: name, Kind: Added, New: newValue})
+		case oldValue != newValue:
+			changes = append(changes, Change{Name: name, Kind: Changed, Old: oldValue, New: newValue})
+		}
+	}
+	return changes
+}
+
+// SortedNames 返回快照中按字母排序的变量名
+func SortedNames(snapshot map[string]string) []string {
+	names := make([]string, 0, len(snapshot))
+	for name := range snapshot {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
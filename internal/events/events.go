@@ -0,0 +1,223 @@
+// Package events 接收运行中的命令通过 LOGCMD_EVENTS 发送的结构化事件（阶段标记、指标、警告）
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnvVar 导出给命令的环境变量，值为接收事件的 Unix socket 路径
+const EnvVar = "LOGCMD_EVENTS"
+
+// 事件类型
+const (
+	TypeMark    = "mark"    // 阶段标记，如 "compile started"
+	TypeMetric  = "metric"  // 键值指标，如 tests=42
+	TypeWarning = "warning" // 警告
+)
+
+const (
+	maxEventSize = 64 * 1024             // 单条事件的最大字节数
+	sendTimeout  = 2 * time.Second       // 发送事件的连接与写入超时
+	drainTimeout = 20 * time.Millisecond // 关闭时等待已排队连接和未读完事件的时长
+)
+
+// Event 命令运行期间发送的一条事件
+type Event struct {
+	Type   string        `json:"type"`
+	Text   string        `json:"text,omitempty"`
+	Key    string        `json:"key,omitempty"`
+	Value  string        `json:"value,omitempty"`
+	Offset time.Duration `json:"offset_ns"` // 相对命令开始的时间偏移（纳秒），由接收方填写
+}
+
+// String 返回写入日志和终端显示的事件描述
+func (e Event) String() string {
+	switch e.Type {
+	case TypeMetric:
+		if e.Text != "" {
+			return fmt.Sprintf("指标: %s=%s (%s)", e.Key, e.Value, e.Text)
+		}
+		return fmt.Sprintf("指标: %s=%s", e.Key, e.Value)
+	case TypeWarning:
+		return "警告: " + e.Text
+	default:
+		return "标记: " + e.Text
+	}
+}
+
+// Parse 解析一行事件：JSON 对象按字段解析，其他文本视为阶段标记
+func Parse(line []byte) (Event, error) {
+	text := strings.TrimSpace(string(line))
+	if text == "" {
+		return Event{}, fmt.Errorf("事件为空")
+	}
+	if !strings.HasPrefix(text, "{") {
+		return Event{Type: TypeMark, Text: text}, nil
+	}
+
+	var ev Event
+	if err := json.Unmarshal([]byte(text), &ev); err != nil {
+		return Event{}, fmt.Errorf("解析事件失败: %w", err)
+	}
+	if ev.Type == "" {
+		ev.Type = TypeMark
+	}
+	return ev, ev.Validate()
+}
+
+// Validate 检查事件类型与必需字段
+func (e Event) Validate() error {
+	switch e.Type {
+	case TypeMark, TypeWarning:
+		if strings.TrimSpace(e.Text) == "" {
+			return fmt.Errorf("%s 事件需要 text", e.Type)
+		}
+	case TypeMetric:
+		if strings.TrimSpace(e.Key) == "" {
+			return fmt.Errorf("metric 事件需要 key")
+		}
+	default:
+		return fmt.Errorf("未知的事件类型: %s", e.Type)
+	}
+	return nil
+}
+
+// Send 将事件发送到 path 指向的 socket（通常为 $LOGCMD_EVENTS）
+// 等待接收方确认后返回，保证事件在之后的命令输出之前写入日志
+func Send(path string, ev Event) error {
+	if err := ev.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("编码事件失败: %w", err)
+	}
+
+	conn, err := net.DialTimeout("unix", path, sendTimeout)
+	if err != nil {
+		return fmt.Errorf("连接事件通道失败: %w", err)
+	}
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(sendTimeout))
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("发送事件失败: %w", err)
+	}
+	if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+		return fmt.Errorf("等待事件确认失败: %w", err)
+	}
+	return nil
+}
+
+// Listener 在临时目录的 Unix socket 上接收事件，每个连接可发送多行事件
+type Listener struct {
+	dir     string
+	path    string
+	ln      *net.UnixListener
+	start   time.Time
+	handler func(Event)
+
+	accepted chan struct{} // accept 循环退出时关闭
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+}
+
+// Listen 创建事件通道，handler 会被多个连接并发调用，start 为计算事件偏移的起点
+func Listen(start time.Time, handler func(Event)) (*Listener, error) {
+	dir, err := os.MkdirTemp("", "logcmd-events-")
+	if err != nil {
+		return nil, fmt.Errorf("创建事件目录失败: %w", err)
+	}
+	path := filepath.Join(dir, "events.sock")
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("创建事件通道失败: %w", err)
+	}
+
+	l := &Listener{
+		dir:     dir,
+		path:    path,
+		ln:      ln,
+		start:   start,
+		handler: handler,
+
+		accepted: make(chan struct{}),
+		conns:    make(map[net.Conn]struct{}),
+	}
+	go l.accept()
+	return l, nil
+}
+
+// Path 返回 socket 路径，作为 LOGCMD_EVENTS 导出给命令
+func (l *Listener) Path() string {
+	return l.path
+}
+
+func (l *Listener) accept() {
+	defer close(l.accepted)
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			return
+		}
+		l.mu.Lock()
+		l.conns[conn] = struct{}{}
+		l.wg.Add(1)
+		l.mu.Unlock()
+
+		go l.serve(conn)
+	}
+}
+
+func (l *Listener) serve(conn net.Conn) {
+	defer l.wg.Done()
+	defer func() {
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), maxEventSize)
+	for scanner.Scan() {
+		ev, err := Parse(scanner.Bytes())
+		if err != nil {
+			continue
+		}
+		ev.Offset = time.Since(l.start)
+		l.handler(ev)
+		// 逐条确认，直接写入 socket 而不读取确认的发送方不受影响
+		_, _ = conn.Write([]byte("ok\n"))
+	}
+}
+
+// Close 停止接收事件并删除 socket
+// 命令结束前已发出但尚未处理的连接仍会被接收，之后仍未关闭的连接在短暂等待后断开，
+// 避免遗留的子进程持有连接导致 logcmd 无法退出
+func (l *Listener) Close() error {
+	deadline := time.Now().Add(drainTimeout)
+	_ = l.ln.SetDeadline(deadline)
+	<-l.accepted
+	err := l.ln.Close()
+
+	l.mu.Lock()
+	for conn := range l.conns {
+		_ = conn.SetReadDeadline(deadline)
+	}
+	l.mu.Unlock()
+
+	l.wg.Wait()
+	os.RemoveAll(l.dir)
+	return err
+}
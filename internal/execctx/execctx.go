@@ -0,0 +1,281 @@
+package execctx
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// gitTimeout 查询 git 状态的最长时间，避免大仓库拖慢命令启动
+const gitTimeout = 2 * time.Second
+
+// maxHeaderLines 解析日志头部时最多读取的行数
+const maxHeaderLines = 32
+
+// Info 描述命令由谁、在哪里、基于哪份代码执行
+type Info struct {
+	User          string `json:"user,omitempty"`           // 操作系统用户名
+	UID           string `json:"uid,omitempty"`            // 用户 ID
+	Hostname      string `json:"hostname,omitempty"`       // 主机名
+	TTY           string `json:"tty,omitempty"`            // 控制终端，非交互执行（如 cron）时为空
+	ParentProcess string `json:"parent_process,omitempty"` // 启动 logcmd 的父进程名称（如 bash、cron）
+	GitBranch     string `json:"git_branch,omitempty"`     // 当前分支，分离 HEAD 时为空
+	GitCommit     string `json:"git_commit,omitempty"`     // HEAD 提交
+	GitDirty      bool   `json:"git_dirty,omitempty"`      // 工作区是否有未提交的修改
+}
+
+// Capture 采集当前进程的执行上下文，dir 用于判断是否处于 git 仓库中，为空时使用当前目录
+// 任何一项获取失败时留空，不影响命令执行
+func Capture(dir string) *Info {
+	info := &Info{}
+
+	if u, err := user.Current(); err == nil {
+		info.User = u.Username
+		info.UID = u.Uid
+	} else {
+		info.User = os.Getenv("USER")
+		info.UID = strconv.Itoa(os.Getuid())
+	}
+	info.Hostname, _ = os.Hostname()
+	info.TTY = terminalName()
+	info.ParentProcess = processName(os.Getppid())
+	captureGit(dir, info)
+
+	return info
+}
+
+// terminalName 返回标准输入输出所连接的终端设备
+func terminalName() string {
+	for fd := 0; fd <= 2; fd++ {
+		if !term.IsTerminal(fd) {
+			continue
+		}
+		for _, link := range []string{fmt.Sprintf("/proc/self/fd/%d", fd), fmt.Sprintf("/dev/fd/%d", fd)} {
+			if name, err := os.Readlink(link); err == nil {
+				return name
+			}
+		}
+		return "tty"
+	}
+	return ""
+}
+
+// processName 返回指定进程的名称，优先读取 /proc，其他平台使用 ps
+func processName(pid int) string {
+	if pid <= 0 {
+		return ""
+	}
+	if data, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid)); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	out, err := exec.Command("ps", "-o", "comm=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return ""
+	}
+	name := strings.TrimSpace(string(out))
+	// macOS 的 ps 会输出完整路径，登录 shell 还会带有前导 "-"
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimPrefix(name, "-")
+}
+
+// captureGit 通过一次 git status 获取分支、HEAD 与是否有未提交修改
+func captureGit(dir string, info *Info) {
+	ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "status", "--porcelain=v2", "--branch", "--untracked-files=no")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		// 不是 git 仓库或未安装 git
+		return
+	}
+	parseGitStatus(string(out), info)
+}
+
+// parseGitStatus 解析 git status --porcelain=v2 --branch 的输出
+func parseGitStatus(out string, info *Info) {
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "# branch.oid "):
+			if oid := strings.TrimPrefix(line, "# branch.oid "); oid != "(initial)" {
+				info.GitCommit = oid
+			}
+		case strings.HasPrefix(line, "# branch.head "):
+			if head := strings.TrimPrefix(line, "# branch.head "); head != "(detached)" {
+				info.GitBranch = head
+			}
+		case strings.HasPrefix(line, "#"):
+		default:
+			info.GitDirty = true
+		}
+	}
+}
+
+// Encode 将执行上下文序列化为 JSON，便于随后台任务持久化
+func Encode(info *Info) (string, error) {
+	if info == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return "", fmt.Errorf("序列化执行上下文失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// Decode 从 JSON 恢复执行上下文，空字符串返回 nil
+func Decode(data string) (*Info, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var info Info
+	if err := json.Unmarshal([]byte(data), &info); err != nil {
+		return nil, fmt.Errorf("解析执行上下文失败: %w", err)
+	}
+	return &info, nil
+}
+
+// 日志头部中的执行上下文行
+var (
+	userLineRegex   = regexp.MustCompile(`^# 用户:\s*(\S*) \(uid (\S*)\)$`)
+	hostLineRegex   = regexp.MustCompile(`^# 主机:\s*(\S+)$`)
+	ttyLineRegex    = regexp.MustCompile(`^# 终端:\s*(\S+)$`)
+	parentLineRegex = regexp.MustCompile(`^# 父进程:\s*(.+)$`)
+	branchLineRegex = regexp.MustCompile(`^# Git 分支:\s*(\S+)$`)
+	commitLineRegex = regexp.MustCompile(`^# Git 提交:\s*(\S+)( \(有未提交修改\))?$`)
+)
+
+// HeaderLines 返回写入日志头部的执行上下文（不含换行），空字段不输出
+func (i *Info) HeaderLines() []string {
+	if i == nil {
+		return nil
+	}
+	var lines []string
+	if i.User != "" {
+		lines = append(lines, fmt.Sprintf("# 用户: %s (uid %s)", i.User, i.UID))
+	}
+	if i.Hostname != "" {
+		lines = append(lines, "# 主机: "+i.Hostname)
+	}
+	if i.TTY != "" {
+		lines = append(lines, "# 终端: "+i.TTY)
+	}
+	if i.ParentProcess != "" {
+		lines = append(lines, "# 父进程: "+i.ParentProcess)
+	}
+	if i.GitBranch != "" {
+		lines = append(lines, "# Git 分支: "+i.GitBranch)
+	}
+	if i.GitCommit != "" {
+		line := "# Git 提交: " + i.GitCommit
+		if i.GitDirty {
+			line += " (有未提交修改)"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// ParseHeaderLine 解析 HeaderLines 生成的一行，识别成功时写入 info 并返回 true
+func ParseHeaderLine(line string, info *Info) bool {
+	if matches := userLineRegex.FindStringSubmatch(line); matches != nil {
+		info.User, info.UID = matches[1], matches[2]
+		return true
+	}
+	if matches := hostLineRegex.FindStringSubmatch(line); matches != nil {
+		info.Hostname = matches[1]
+		return true
+	}
+	if matches := ttyLineRegex.FindStringSubmatch(line); matches != nil {
+		info.TTY = matches[1]
+		return true
+	}
+	if matches := parentLineRegex.FindStringSubmatch(line); matches != nil {
+		info.ParentProcess = matches[1]
+		return true
+	}
+	if matches := branchLineRegex.FindStringSubmatch(line); matches != nil {
+		info.GitBranch = matches[1]
+		return true
+	}
+	if matches := commitLineRegex.FindStringSubmatch(line); matches != nil {
+		info.GitCommit = matches[1]
+		info.GitDirty = matches[2] != ""
+		return true
+	}
+	return false
+}
+
+// ReadHeader 从日志开头读取执行上下文，旧日志没有这些行时返回空的 Info
+func ReadHeader(r io.Reader) (*Info, error) {
+	info := &Info{}
+	scanner := bufio.NewScanner(r)
+	separators := 0
+	for lines := 0; lines < maxHeaderLines && scanner.Scan(); lines++ {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "########") {
+			// 头部以两行分隔线包围
+			if separators++; separators == 2 {
+				break
+			}
+			continue
+		}
+		ParseHeaderLine(line, info)
+	}
+	return info, scanner.Err()
+}
+
+// Filter 按执行上下文筛选运行记录，空字段表示不限制
+type Filter struct {
+	User   string
+	Host   string
+	Branch string
+}
+
+// IsZero 判断是否未设置任何筛选条件
+func (f Filter) IsZero() bool {
+	return f.User == "" && f.Host == "" && f.Branch == ""
+}
+
+// Match 判断执行上下文是否满足筛选条件，info 为 nil 时只匹配空条件
+func (f Filter) Match(info *Info) bool {
+	if f.IsZero() {
+		return true
+	}
+	if info == nil {
+		return false
+	}
+	return (f.User == "" || f.User == info.User) &&
+		(f.Host == "" || f.Host == info.Hostname) &&
+		(f.Branch == "" || f.Branch == info.GitBranch)
+}
+
+// String 返回筛选条件的描述，如 "用户 alice, 分支 main"
+func (f Filter) String() string {
+	var parts []string
+	if f.User != "" {
+		parts = append(parts, "用户 "+f.User)
+	}
+	if f.Host != "" {
+		parts = append(parts, "主机 "+f.Host)
+	}
+	if f.Branch != "" {
+		parts = append(parts, "分支 "+f.Branch)
+	}
+	return strings.Join(parts, ", ")
+}
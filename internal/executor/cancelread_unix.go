@@ -0,0 +1,92 @@
+//go:build !windows
+
+package executor
+
+import (
+	"errors"
+	"os"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// errReadCanceled 读取被 cancel 中断
+var errReadCanceled = errors.New("读取已取消")
+
+// cancelReader 可以中断的文件读取：用 poll 同时等待文件和一个自建管道，
+// cancel 向管道写入后阻塞中的 Read 立即返回，不需要修改文件（可能与 shell 共享的终端）的阻塞模式
+type cancelReader struct {
+	file   *os.File
+	fd     int32
+	wakeR  *os.File
+	wakeW  *os.File
+	wakeFd int32
+
+	cancelOnce sync.Once
+}
+
+func newCancelReader(file *os.File) (*cancelReader, error) {
+	fd, err := rawFd(file)
+	if err != nil {
+		return nil, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	wakeFd, err := rawFd(r)
+	if err != nil {
+		r.Close()
+		w.Close()
+		return nil, err
+	}
+	return &cancelReader{file: file, fd: fd, wakeR: r, wakeW: w, wakeFd: wakeFd}, nil
+}
+
+// rawFd 返回文件描述符，File.Fd 会把文件切换为阻塞模式，这里通过 SyscallConn 获取
+func rawFd(f *os.File) (int32, error) {
+	raw, err := f.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var fd int32
+	if err := raw.Control(func(p uintptr) { fd = int32(p) }); err != nil {
+		return 0, err
+	}
+	return fd, nil
+}
+
+func (c *cancelReader) Read(p []byte) (int, error) {
+	fds := []unix.PollFd{
+		{Fd: c.fd, Events: unix.POLLIN},
+		{Fd: c.wakeFd, Events: unix.POLLIN},
+	}
+	for {
+		if _, err := unix.Poll(fds, -1); err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			return 0, err
+		}
+		if fds[1].Revents != 0 {
+			return 0, errReadCanceled
+		}
+		if fds[0].Revents != 0 {
+			// 可读、已挂断或出错时都交给 Read 返回实际的结果
+			return c.file.Read(p)
+		}
+	}
+}
+
+// cancel 中断当前和之后的 Read
+func (c *cancelReader) cancel() {
+	c.cancelOnce.Do(func() {
+		_, _ = c.wakeW.Write([]byte{0})
+	})
+}
+
+// close 释放唤醒管道，需要在读取 goroutine 退出后调用
+func (c *cancelReader) close() {
+	c.wakeR.Close()
+	c.wakeW.Close()
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package executor
+
+import (
+	"errors"
+	"os"
+)
+
+// errReadCanceled 读取被 cancel 中断
+var errReadCanceled = errors.New("读取已取消")
+
+// cancelReader Windows 上不支持中断阻塞中的读取
+type cancelReader struct{}
+
+func newCancelReader(file *os.File) (*cancelReader, error) {
+	return nil, errors.New("不支持中断读取")
+}
+
+func (c *cancelReader) Read(p []byte) (int, error) { return 0, errReadCanceled }
+
+func (c *cancelReader) cancel() {}
+
+func (c *cancelReader) close() {}
@@ -0,0 +1,72 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/aliancn/logcmd/internal/envsnap"
+	"github.com/aliancn/logcmd/internal/logrecord"
+)
+
+// Capture 将已经在运行的命令输出（如通过管道传入的标准输入）当作 name 的输出记录
+// input 读到 EOF 时结束，exitCode 为上游命令的退出码（无法获知时为 0）。
+// 期间收到的中断信号只会被记录，上游命令通常会同时收到信号并关闭管道。
+func (e *Executor) Capture(ctx context.Context, name string, input io.Reader, exitCode int) (*Result, error) {
+	result := &Result{
+		Command:   name,
+		StartTime: time.Now(),
+	}
+	defer e.begin(result)()
+	if e.options.Environment != nil {
+		// 没有子进程，记录 logcmd 自身的环境变量
+		result.Environment = envsnap.Capture(os.Environ(), *e.options.Environment)
+	}
+
+	sigCh := make(chan os.Signal, len(forwardedSignals))
+	signal.Notify(sigCh, forwardedSignals...)
+	defer signal.Stop(sigCh)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.streamOutput(input, e.console.stdout, logrecord.StreamStdout)
+	}()
+
+	var received os.Signal
+	ctxDone := ctx.Done()
+wait:
+	for {
+		select {
+		case <-done:
+			break wait
+		case sig := <-sigCh:
+			received = sig
+		case <-ctxDone:
+			// 无法中止对输入的读取，只记录为被中断
+			ctxDone = nil
+			result.Interrupted = true
+		}
+	}
+
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+	e.finishLog(result)
+
+	result.ExitCode = exitCode
+	result.Success = exitCode == 0 && received == nil && !result.Interrupted
+	if received != nil {
+		result.Signal = signalName(received)
+		result.Interrupted = true
+	}
+	if result.Success {
+		result.Status = StatusSuccess
+	} else {
+		result.Status = StatusFailed
+	}
+
+	e.complete(result)
+	return result, nil
+}
@@ -0,0 +1,129 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// 终端输出模式，决定命令输出如何回显到终端；日志始终完整写入
+const (
+	OutputNormal    = ""           // 实时回显到终端
+	OutputQuiet     = "quiet"      // 不回显任何输出
+	OutputOnFailure = "on-failure" // 缓存输出，仅在命令失败时回显
+	OutputSummary   = "summary"    // 不回显输出，由调用方在结束时打印一行状态
+)
+
+// maxBufferedOutput on-failure 模式最多缓存的输出字节数，超过后丢弃最早的输出
+const maxBufferedOutput = 16 * 1024 * 1024
+
+// ParseOutputMode 校验并规范化输出模式，normal 与空字符串均表示实时回显
+func ParseOutputMode(mode string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", "normal":
+		return OutputNormal, nil
+	case OutputQuiet:
+		return OutputQuiet, nil
+	case OutputOnFailure, "on_failure":
+		return OutputOnFailure, nil
+	case OutputSummary:
+		return OutputSummary, nil
+	default:
+		return "", fmt.Errorf("未知的输出模式: %s（可选 normal、quiet、on-failure、summary）", mode)
+	}
+}
+
+// console 按输出模式包装终端的 stdout/stderr
+type console struct {
+	stdout io.Writer
+	stderr io.Writer
+	buffer *outputBuffer // on-failure 模式下缓存的输出
+}
+
+func newConsole(mode string, stdout, stderr io.Writer) *console {
+	switch mode {
+	case OutputQuiet, OutputSummary:
+		return &console{stdout: io.Discard, stderr: io.Discard}
+	case OutputOnFailure:
+		buffer := &outputBuffer{stdout: stdout, stderr: stderr}
+		return &console{
+			stdout: bufferedStream{buffer: buffer, stderr: false},
+			stderr: bufferedStream{buffer: buffer, stderr: true},
+			buffer: buffer,
+		}
+	default:
+		return &console{stdout: stdout, stderr: stderr}
+	}
+}
+
+// finish 在命令结束后调用，on-failure 模式下命令失败时按原顺序回显缓存的输出
+func (c *console) finish(success bool) {
+	if c.buffer == nil {
+		return
+	}
+	if !success {
+		c.buffer.replay()
+	}
+	c.buffer.reset()
+}
+
+// outputBuffer 按到达顺序缓存两个输出流的数据
+type outputBuffer struct {
+	stdout  io.Writer
+	stderr  io.Writer
+	mu      sync.Mutex
+	chunks  []bufferedChunk
+	size    int
+	dropped int64
+}
+
+type bufferedChunk struct {
+	stderr bool
+	data   []byte
+}
+
+type bufferedStream struct {
+	buffer *outputBuffer
+	stderr bool
+}
+
+func (s bufferedStream) Write(p []byte) (int, error) {
+	s.buffer.add(s.stderr, p)
+	return len(p), nil
+}
+
+func (b *outputBuffer) add(stderr bool, p []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.chunks = append(b.chunks, bufferedChunk{stderr: stderr, data: append([]byte(nil), p...)})
+	b.size += len(p)
+	for b.size > maxBufferedOutput && len(b.chunks) > 1 {
+		b.size -= len(b.chunks[0].data)
+		b.dropped += int64(len(b.chunks[0].data))
+		b.chunks = b.chunks[1:]
+	}
+}
+
+func (b *outputBuffer) replay() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.dropped > 0 {
+		fmt.Fprintf(b.stderr, "[logcmd] 省略了最早的 %d 字节输出，完整内容见日志\n", b.dropped)
+	}
+	for _, chunk := range b.chunks {
+		if chunk.stderr {
+			_, _ = b.stderr.Write(chunk.data)
+		} else {
+			_, _ = b.stdout.Write(chunk.data)
+		}
+	}
+}
+
+func (b *outputBuffer) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.chunks = nil
+	b.size = 0
+	b.dropped = 0
+}
@@ -0,0 +1,73 @@
+package executor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aliancn/logcmd/internal/asciicast"
+	"github.com/aliancn/logcmd/internal/events"
+	"github.com/aliancn/logcmd/internal/logrecord"
+)
+
+// eventRecorder 接收命令发送的事件，按到达时间写入日志并保存到结果中
+type eventRecorder struct {
+	listener  *events.Listener
+	sink      FlushWriter
+	recording *asciicast.Writer
+	sync      func() // 同步输出流中缓存的完整输出
+	breakLine func() // 日志停在一行中间时补上换行，raw 日志模式下为 nil
+
+	mu     sync.Mutex
+	events []events.Event
+}
+
+// listenEvents 创建事件通道，start 为命令开始时间
+func (e *Executor) listenEvents(start time.Time) (*eventRecorder, error) {
+	r := &eventRecorder{sink: e.logSink(logrecord.StreamEvent), recording: e.options.Recording, sync: e.syncOutputs}
+	if e.options.Metadata == nil {
+		r.breakLine = e.breakLogLine
+	}
+	listener, err := events.Listen(start, r.record)
+	if err != nil {
+		return nil, err
+	}
+	r.listener = listener
+	return r, nil
+}
+
+func (r *eventRecorder) path() string {
+	return r.listener.Path()
+}
+
+// record 保存一条事件，并以单独的一行插入日志中当前的位置，录制终端输出时同时写入标记
+func (r *eventRecorder) record(ev events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+	if r.recording != nil {
+		_ = r.recording.Marker(ev.String())
+	}
+	if r.sink == nil {
+		return
+	}
+	// 先写出事件之前收到的完整输出，未结束的行留在过滤器中，待命令输出换行后写在事件之后
+	r.sync()
+	if r.breakLine != nil {
+		r.breakLine()
+	}
+	fmt.Fprintf(r.sink, "[logcmd] +%.3fs %s\n", ev.Offset.Seconds(), ev)
+	_ = r.sink.Flush()
+}
+
+// close 停止接收事件并返回收到的全部事件，r 为 nil 时返回 nil
+func (r *eventRecorder) close() []events.Event {
+	if r == nil {
+		return nil
+	}
+	_ = r.listener.Close()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events
+}
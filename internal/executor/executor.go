@@ -6,31 +6,169 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/aliancn/logcmd/internal/artifact"
+	"github.com/aliancn/logcmd/internal/asciicast"
+	"github.com/aliancn/logcmd/internal/envsnap"
+	"github.com/aliancn/logcmd/internal/events"
+	"github.com/aliancn/logcmd/internal/execctx"
+	"github.com/aliancn/logcmd/internal/filetrack"
+	"github.com/aliancn/logcmd/internal/logrecord"
+	"github.com/aliancn/logcmd/internal/sandbox"
+	"github.com/aliancn/logcmd/internal/shellcmd"
+)
+
+// 命令执行状态
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusTimeout = "timeout"
 )
 
+// 命令被 logcmd 主动终止的原因
+const (
+	ReasonTimeout     = "timeout"      // 超过最长运行时间
+	ReasonIdleTimeout = "idle-timeout" // 超过限定时间没有任何输出
+)
+
+// TimeoutExitCode 命令超时时对外报告的退出码，与 coreutils timeout 保持一致
+const TimeoutExitCode = 124
+
 // Result 记录命令执行结果
 type Result struct {
-	Command   string        // 执行的命令
-	Args      []string      // 命令参数
-	StartTime time.Time     // 开始时间
-	EndTime   time.Time     // 结束时间
-	Duration  time.Duration // 执行时长
-	ExitCode  int           // 退出码
-	Success   bool          // 是否成功
+	Command     string        `json:"command"`               // 执行的命令
+	Args        []string      `json:"args"`                  // 命令参数
+	StartTime   time.Time     `json:"start_time"`            // 开始时间
+	EndTime     time.Time     `json:"end_time"`              // 结束时间
+	Duration    time.Duration `json:"duration_ns"`           // 执行时长
+	ExitCode    int           `json:"exit_code"`             // 退出码
+	Success     bool          `json:"success"`               // 是否成功
+	Status      string        `json:"status"`                // 执行状态：success/failed/timeout
+	PTY         bool          `json:"pty,omitempty"`         // 是否在伪终端中运行
+	Signal      string        `json:"signal,omitempty"`      // 终止命令的信号名称（如 SIGTERM），正常退出时为空
+	Reason      string        `json:"reason,omitempty"`      // logcmd 主动终止命令的原因（timeout/idle-timeout）
+	Interrupted bool          `json:"interrupted,omitempty"` // 执行期间 logcmd 收到并转发了终止信号（如用户按下 Ctrl+C）
+	Usage       *Usage        `json:"usage,omitempty"`       // 命令的资源占用，平台不支持时为 nil
+	Samples     []Sample      `json:"samples,omitempty"`     // 运行期间的资源采样，未启用采样时为空
+
+	Attempt      int    `json:"attempt,omitempty"`       // 第几次尝试（从 1 开始），未启用重试时为 0
+	MaxAttempts  int    `json:"max_attempts,omitempty"`  // 最多尝试次数，未启用重试时为 0
+	AttemptGroup string `json:"attempt_group,omitempty"` // 关联同一命令多次尝试的重试组 ID
+
+	Environment map[string]string `json:"environment,omitempty"` // 运行时的环境变量快照（已过滤和脱敏）
+	Context     *execctx.Info     `json:"context,omitempty"`     // 执行上下文（用户、主机、终端、git 状态）
+	Encoding    string            `json:"encoding,omitempty"`    // 命令输出的原始字符编码，已转换为 UTF-8 写入日志；未转换时为空
+	Script      string            `json:"script,omitempty"`      // shell 模式下执行的完整脚本，此时 Command 为 shell，Args 为 -c 及脚本
+
+	TruncatedBytes int64 `json:"truncated_bytes,omitempty"` // 超过日志大小上限而未写入日志的输出字节数
+
+	StdoutPreview string `json:"stdout_preview,omitempty"` // 标准输出开头和末尾的预览（已经过 LogFilters）
+	StderrPreview string `json:"stderr_preview,omitempty"` // 标准错误开头和末尾的预览
+	ErrorExcerpt  string `json:"error_excerpt,omitempty"`  // 第一条看起来像错误的输出行；命令失败且没有这样的行时为最后一行输出
+
+	Events []events.Event `json:"events,omitempty"` // 命令通过 LOGCMD_EVENTS 发送的事件，按接收顺序排列
+
+	Recording string `json:"recording,omitempty"` // asciicast 录制文件路径，未录制时为空
+
+	TrackFiles  string             `json:"track_files,omitempty"`  // 跟踪文件变化使用的 glob 规则，未跟踪时为空
+	FileChanges []filetrack.Change `json:"file_changes,omitempty"` // 运行前后工作目录中创建、修改和删除的文件
+
+	Artifacts []artifact.Artifact `json:"artifacts,omitempty"` // 运行结束后收集的产物
+
+	StdinRecord string `json:"stdin_record,omitempty"` // 保存的标准输入记录文件路径，未记录时为空
+	StdinBytes  int64  `json:"stdin_bytes,omitempty"`  // 记录的标准输入字节数
+
+	Sandbox            *sandbox.Profile `json:"sandbox,omitempty"`              // 运行命令使用的沙箱配置，未启用沙箱时为 nil
+	SandboxDenials     []string         `json:"sandbox_denials,omitempty"`      // 看起来是被沙箱拒绝的输出行，最多保留前 maxDenials 条
+	SandboxDenialCount int              `json:"sandbox_denial_count,omitempty"` // 看起来是被沙箱拒绝的输出行总数
+}
+
+// ShellExitCode 按照 shell 约定返回退出码：超时为 124，被信号终止为 128+信号值
+func (r *Result) ShellExitCode() int {
+	if r.Status == StatusTimeout {
+		return TimeoutExitCode
+	}
+	if !r.Success && r.Signal != "" {
+		if n := signalNumber(r.Signal); n > 0 {
+			return 128 + n
+		}
+	}
+	return r.ExitCode
+}
+
+// Options 执行器选项
+type Options struct {
+	PTY            bool              // 在伪终端中运行命令（stdout/stderr 合并为终端输出）
+	Records        io.Writer         // 结构化逐行记录（JSON Lines）的写入目标，nil 表示不记录
+	GracePeriod    time.Duration     // 转发终止信号后等待退出的时长，0 表示使用 DefaultGracePeriod
+	Timeout        time.Duration     // 命令最长运行时间，超时后先发送 SIGTERM，宽限期后 SIGKILL；0 表示不限制
+	IdleTimeout    time.Duration     // 两个输出流都没有输出的最长时间，超过后按超时方式终止；0 表示不限制
+	SampleInterval time.Duration     // 运行期间采样进程组 CPU 与内存的间隔，0 表示不采样
+	OnStart        func(pgid int)    // 命令启动后回调，参数为命令所在的进程组 ID
+	LogFilters     []LogFilter       // 按顺序作用于写入日志的输出（如脱敏），终端输出不受影响
+	MaxLogSize     int64             // 写入日志的输出上限（字节），超过后只保留开头和末尾，结构化记录和录制则停止写入；0 表示不限制
+	LogTailSize    int64             // 超过上限时末尾保留的字节数，0 表示上限的一半
+	PreviewLength  int               // 每个输出流预览保留的开头和末尾字节数，0 表示不记录预览
+	Output         string            // 终端输出模式（OutputQuiet 等），为空表示实时回显
+	Events         bool              // 导出 LOGCMD_EVENTS 并接收命令发送的事件，事件按到达时间写入日志
+	Recording      *asciicast.Writer // 录制终端输出的 asciicast 写入器，nil 表示不录制；不受输出模式和 LogFilters 影响
+	Stdin          io.Reader         // 转发给命令的标准输入，nil 表示不提供输入
+	StdinRecord    io.Writer         // 保存转发给命令的标准输入，nil 表示不保存
+	Sandbox        *sandbox.Profile  // 在沙箱中运行命令，nil 表示不限制
+	Metadata       io.Writer         // 尾部元数据、logcmd 提示和事件的写入目标（raw 日志模式，日志只包含未经 LogFilters 的命令输出），nil 表示与输出一起写入日志
+	Environment    *envsnap.Options  // 记录传给命令的环境变量快照，nil 表示不记录
+
+	RecordingFilters []LogFilter // 按顺序作用于录制的输出（如脱敏），不做日志转换以保留终端控制序列
+	RawLogFilters    []LogFilter // raw 日志模式下按顺序作用于日志的输出（如脱敏），不做编码和日志转换
+}
+
+// FlushWriter 可在输出结束时刷新残留数据的写入器
+type FlushWriter interface {
+	io.Writer
+	Flush() error
+}
+
+// Syncer 可以在输出中途写出缓存内容的写入器
+// Sync 只写出已经完整的行（以及重复行计数等待写出的提示），未结束的行、不完整的多字节字符
+// 和跨越多次写入的状态都保留到后续输出；实现时需要在写出后同步下游
+type Syncer interface {
+	Sync() error
 }
 
+// LogFilter 包装写入日志的输出流，每个输出流单独调用一次
+// 返回的写入器在 Flush 时需要写出缓存的内容并刷新下游，可以实现 Syncer 以便在写入事件前同步
+type LogFilter func(dst FlushWriter) FlushWriter
+
 // Executor 命令执行器
 type Executor struct {
 	logFile io.Writer
 	stdout  io.Writer
 	stderr  io.Writer
+	console *console // 按输出模式包装后的终端输出
+	options Options
+	records *logrecord.Writer
+	logCap  *logCap
+	preview *outputPreview
 	logMu   sync.Mutex
+
+	outputsMu sync.Mutex
+	outputs   []*outputSink // 正在写入日志的 stdout/stderr 输出流，写入事件前需要先同步
+	lineOpen  bool          // 日志中最后写入的输出没有以换行结束，由 logMu 保护
+
+	lastOutput atomic.Int64 // 最后一次收到输出的时间（UnixNano）
 }
 
 // New 创建新的执行器
 func New(logFile io.Writer, stdout io.Writer, stderr io.Writer) *Executor {
+	return NewWithOptions(logFile, stdout, stderr, Options{})
+}
+
+// NewWithOptions 使用指定选项创建执行器
+func NewWithOptions(logFile io.Writer, stdout io.Writer, stderr io.Writer, opts Options) *Executor {
 	if stdout == nil {
 		stdout = os.Stdout
 	}
@@ -42,6 +180,8 @@ func New(logFile io.Writer, stdout io.Writer, stderr io.Writer) *Executor {
 		logFile: logFile,
 		stdout:  stdout,
 		stderr:  stderr,
+		console: newConsole(opts.Output, stdout, stderr),
+		options: opts,
 	}
 }
 
@@ -53,9 +193,193 @@ func (e *Executor) Execute(ctx context.Context, command string, args ...string)
 		StartTime: time.Now(),
 	}
 
-	// 创建命令
-	cmd := exec.CommandContext(ctx, command, args...)
+	defer e.begin(result)()
+
+	// 创建命令，取消与信号由 supervisor 以进程组为单位处理
+	cmd := exec.Command(command, args...)
+
+	var recorder *eventRecorder
+	if e.options.Events {
+		if r, err := e.listenEvents(result.StartTime); err != nil {
+			fmt.Fprintf(e.stderr, "创建事件通道失败: %v\n", err)
+		} else {
+			recorder = r
+			cmd.Env = append(os.Environ(), events.EnvVar+"="+r.path())
+		}
+	}
+
+	if e.options.Sandbox != nil {
+		result.Sandbox = e.options.Sandbox
+		if err := sandbox.Wrap(cmd, *e.options.Sandbox); err != nil {
+			recorder.close()
+			return nil, fmt.Errorf("启用沙箱失败: %w", err)
+		}
+	}
+	if e.options.Environment != nil {
+		result.Environment = envsnap.Capture(commandEnviron(cmd), *e.options.Environment)
+	}
+
+	// 启动命令，wait 负责等待输出处理和进程结束
+	var (
+		wait func() error
+		err  error
+		fg   *foreground
+	)
+	if e.options.PTY {
+		// 伪终端模式下命令作为新会话的首进程，自然拥有独立的进程组
+		result.PTY = true
+		wait, err = e.startPTY(cmd)
+	} else {
+		if e.options.Sandbox != nil {
+			setSession(cmd)
+		} else {
+			setProcessGroup(cmd)
+		}
+		fg = e.foreground()
+		fg.attach(cmd)
+		wait, err = e.startPipes(cmd, fg)
+	}
+	if err != nil {
+		recorder.close()
+		return nil, err
+	}
+
+	if e.options.OnStart != nil {
+		e.options.OnStart(cmd.Process.Pid)
+	}
+
+	var samp *sampler
+	if e.options.SampleInterval > 0 {
+		samp = startSampler(cmd.Process.Pid, e.options.SampleInterval, result.StartTime)
+	}
+
+	e.lastOutput.Store(time.Now().UnixNano())
+	sup := e.supervise(ctx, cmd)
+	err = wait()
+	result.Events = recorder.close()
+	forwarded, reason := sup.stop()
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+	if samp != nil {
+		result.Samples = samp.stop()
+	}
+	result.Usage = processUsage(cmd.ProcessState)
+	e.finishLog(result)
+
+	// 优先记录实际导致进程退出的信号，命令自行处理信号后退出时记录转发的信号
+	if sig := exitSignal(cmd.ProcessState); sig != nil {
+		result.Signal = signalName(sig)
+	} else if forwarded != nil {
+		result.Signal = signalName(forwarded)
+	}
+	result.Interrupted = forwarded != nil || fg.interrupted(exitSignal(cmd.ProcessState))
+
+	// 获取退出码
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitErr.ExitCode()
+		} else {
+			result.ExitCode = -1
+		}
+		result.Success = false
+	} else {
+		result.ExitCode = 0
+		result.Success = true
+	}
+
+	switch {
+	case reason != "":
+		result.Success = false
+		result.Status = StatusTimeout
+		result.Reason = reason
+	case result.Success:
+		result.Status = StatusSuccess
+	default:
+		result.Status = StatusFailed
+	}
+
+	e.complete(result)
+	return result, nil
+}
+
+// commandEnviron 返回命令启动时实际使用的环境变量
+// 不包含 logcmd 为本次运行注入的内部变量：沙箱配置在执行目标命令前移除，事件通道路径每次运行都不同
+func commandEnviron(cmd *exec.Cmd) []string {
+	var env []string
+	for _, kv := range cmd.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if name == sandbox.EnvVar || name == events.EnvVar {
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
 
+// begin 为一次运行准备结构化记录、输出预览和日志大小限制，返回的函数在运行结束后刷新结构化记录
+func (e *Executor) begin(result *Result) func() {
+	e.preview = newOutputPreview(e.options.PreviewLength)
+	e.outputs = nil
+	if e.options.Sandbox != nil {
+		e.preview.trackDenials()
+	}
+	if e.options.Recording != nil {
+		e.options.Recording.Limit(e.options.MaxLogSize)
+		if err := e.options.Recording.Start(result.StartTime); err != nil {
+			fmt.Fprintf(e.stderr, "%v\n", err)
+		}
+	}
+	if e.options.MaxLogSize > 0 && e.logFile != nil {
+		e.logCap = newLogCap(e.logFile, e.options.MaxLogSize, e.options.LogTailSize)
+		if e.options.Metadata != nil {
+			e.logCap.notices = e.options.Metadata
+		}
+	}
+
+	if e.options.Records == nil {
+		return func() {}
+	}
+	e.records = logrecord.NewWriter(e.options.Records, result.StartTime)
+	e.records.Limit(e.options.MaxLogSize)
+	return func() {
+		if err := e.records.Flush(); err != nil {
+			fmt.Fprintf(e.stderr, "写入结构化记录失败: %v\n", err)
+		}
+	}
+}
+
+// finishLog 在输出结束后写出超过大小上限时保留的末尾部分
+func (e *Executor) finishLog(result *Result) {
+	if e.logCap == nil {
+		return
+	}
+	e.logMu.Lock()
+	truncated, err := e.logCap.finish()
+	e.logMu.Unlock()
+	if err != nil {
+		fmt.Fprintf(e.stderr, "写入日志失败: %v\n", err)
+	}
+	result.TruncatedBytes = truncated
+}
+
+// complete 在确定运行状态后回显缓存的输出，并填充输出预览和错误摘要
+func (e *Executor) complete(result *Result) {
+	e.console.finish(result.Success)
+
+	result.StdoutPreview = e.preview.preview(logrecord.StreamStdout)
+	result.StderrPreview = e.preview.preview(logrecord.StreamStderr)
+	result.ErrorExcerpt = e.preview.excerpt
+	if result.ErrorExcerpt == "" && !result.Success {
+		result.ErrorExcerpt = e.preview.lastLine(logrecord.StreamStderr)
+		if result.ErrorExcerpt == "" {
+			result.ErrorExcerpt = e.preview.lastLine(logrecord.StreamStdout)
+		}
+	}
+	result.SandboxDenials, result.SandboxDenialCount = e.preview.sandboxDenials()
+}
+
+// startPipes 通过 stdout/stderr 管道启动命令，fg 不为 nil 时命令在终端前台运行
+func (e *Executor) startPipes(cmd *exec.Cmd, fg *foreground) (func() error, error) {
 	// 获取标准输出和错误输出的管道
 	stdoutPipe, err := cmd.StdoutPipe()
 	if err != nil {
@@ -67,10 +391,19 @@ func (e *Executor) Execute(ctx context.Context, command string, args ...string)
 		return nil, fmt.Errorf("获取stderr管道失败: %w", err)
 	}
 
+	stdin, stdinPipe, err := e.wireStdin(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("获取stdin管道失败: %w", err)
+	}
+
 	// 启动命令
 	if err := cmd.Start(); err != nil {
+		fg.stop()
+		stdin.stop()
 		return nil, fmt.Errorf("启动命令失败: %w", err)
 	}
+	fg.watch(cmd.Process.Pid)
+	stdin.start(stdinPipe, func(error) { stdinPipe.Close() })
 
 	// 使用WaitGroup等待所有输出处理完成
 	var wg sync.WaitGroup
@@ -79,51 +412,53 @@ func (e *Executor) Execute(ctx context.Context, command string, args ...string)
 	// 处理标准输出
 	go func() {
 		defer wg.Done()
-		e.streamOutput(stdoutPipe, e.stdout)
+		e.streamOutput(stdoutPipe, e.console.stdout, logrecord.StreamStdout)
 	}()
 
 	// 处理标准错误
 	go func() {
 		defer wg.Done()
-		e.streamOutput(stderrPipe, e.stderr)
+		e.streamOutput(stderrPipe, e.console.stderr, logrecord.StreamStderr)
 	}()
 
-	// 等待输出处理完成
-	wg.Wait()
-
-	// 等待命令完成
-	err = cmd.Wait()
-	result.EndTime = time.Now()
-	result.Duration = result.EndTime.Sub(result.StartTime)
-
-	// 获取退出码
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			result.ExitCode = exitErr.ExitCode()
-		} else {
-			result.ExitCode = -1
-		}
-		result.Success = false
-	} else {
-		result.ExitCode = 0
-		result.Success = true
-	}
-
-	return result, nil
+	return func() error {
+		// 等待输出处理完成后再等待命令结束，Wait 会关闭命令的标准输入管道，转发随之结束
+		wg.Wait()
+		err := cmd.Wait()
+		fg.stop()
+		stdin.stop()
+		return err
+	}, nil
 }
 
 // streamOutput 流式处理输出，同时写入终端和日志文件
-func (e *Executor) streamOutput(reader io.Reader, dest io.Writer) {
-	// 包装 logFile 以支持并发写入
-	var logOutput io.Writer
-	if e.logFile != nil {
-		logOutput = &lockedWriter{w: e.logFile, mu: &e.logMu}
+func (e *Executor) streamOutput(reader io.Reader, dest io.Writer, stream string) {
+	var logOutput FlushWriter
+	if sink := e.logSink(stream); sink != nil {
+		logOutput = e.trackOutput(sink)
+	}
+	if logOutput != nil {
+		defer func() {
+			if err := logOutput.Flush(); err != nil {
+				fmt.Fprintf(e.stderr, "写入日志失败: %v\n", err)
+			}
+		}()
+	}
+
+	var recording FlushWriter
+	if e.options.Recording != nil {
+		recording = e.options.Recording.Stream()
+		for i := len(e.options.RecordingFilters) - 1; i >= 0; i-- {
+			recording = e.options.RecordingFilters[i](recording)
+		}
+		defer recording.Flush()
 	}
 
 	buf := make([]byte, 32*1024)
 	for {
 		n, err := reader.Read(buf)
 		if n > 0 {
+			e.lastOutput.Store(time.Now().UnixNano())
 			chunk := buf[:n]
 
 			if dest != nil {
@@ -134,6 +469,11 @@ func (e *Executor) streamOutput(reader io.Reader, dest io.Writer) {
 				}
 			}
 
+			if recording != nil {
+				// 录制写入失败只影响录制文件，错误在结束时由调用方报告
+				_, _ = recording.Write(chunk)
+			}
+
 			if logOutput != nil {
 				// 写入日志 (Logger 可能会缓冲，但也可能定期刷新)
 				if _, wErr := logOutput.Write(chunk); wErr != nil {
@@ -151,41 +491,304 @@ func (e *Executor) streamOutput(reader io.Reader, dest io.Writer) {
 	}
 }
 
-// WriteMetadata 写入命令元数据到日志
+// WriteMetadata 写入命令元数据到日志，raw 日志模式下写入 Options.Metadata
 func (e *Executor) WriteMetadata(result *Result) {
-	if e.logFile == nil {
+	target := e.options.Metadata
+	if target == nil {
+		target = e.logFile
+	}
+	if target == nil {
 		return
 	}
 
 	metadata := fmt.Sprintf(`
 ================================================================================
-命令: %s %v
+命令: %s
 开始时间: %s
 结束时间: %s
 执行时长: %v
 退出码: %d
 执行状态: %s
-================================================================================
+%s%s================================================================================
 `,
-		result.Command,
-		result.Args,
+		CommandLine(result),
 		result.StartTime.Format("2006-01-02 15:04:05"),
 		result.EndTime.Format("2006-01-02 15:04:05"),
 		result.Duration,
 		result.ExitCode,
-		map[bool]string{true: "成功", false: "失败"}[result.Success],
+		statusLabel(result),
+		footerExtras(result),
+		usageFooter(result),
 	)
 
-	fmt.Fprint(e.logFile, metadata)
+	fmt.Fprint(target, metadata)
+}
+
+// CommandLine 返回写入日志的命令行，shell 模式下为脚本本身
+func CommandLine(result *Result) string {
+	if result.Script != "" {
+		return shellcmd.DisplayLine(result.Script)
+	}
+	return fmt.Sprintf("%s %v", result.Command, result.Args)
+}
+
+// statusLabel 返回写入日志元数据的执行状态
+func statusLabel(result *Result) string {
+	switch {
+	case result.Status == StatusTimeout:
+		return "超时"
+	case result.Success:
+		return "成功"
+	default:
+		return "失败"
+	}
+}
+
+// sinceLastOutput 返回距最后一次输出的时长
+func (e *Executor) sinceLastOutput() time.Duration {
+	return time.Since(time.Unix(0, e.lastOutput.Load()))
+}
+
+// logNotice 在日志中写入一条 logcmd 自身的提示信息，raw 日志模式下写入 Options.Metadata
+func (e *Executor) logNotice(format string, args ...interface{}) {
+	target := e.options.Metadata
+	if target == nil {
+		if e.logFile == nil {
+			return
+		}
+		target = e.outputLog()
+	}
+	e.logMu.Lock()
+	defer e.logMu.Unlock()
+	fmt.Fprintf(target, "\n[logcmd] "+format+"\n", args...)
+}
+
+// footerExtras 返回仅在特定情况下写入元数据的附加行
+func footerExtras(result *Result) string {
+	var extras string
+	if result.Script != "" {
+		extras += fmt.Sprintf("Shell: %s\n", result.Command)
+	}
+	if result.Signal != "" {
+		extras += fmt.Sprintf("终止信号: %s\n", result.Signal)
+	}
+	if result.Reason != "" {
+		extras += fmt.Sprintf("终止原因: %s\n", result.Reason)
+	}
+	if result.Attempt > 0 {
+		extras += fmt.Sprintf("尝试: %d/%d\n", result.Attempt, result.MaxAttempts)
+		extras += fmt.Sprintf("重试组: %s\n", result.AttemptGroup)
+	}
+	if result.TruncatedBytes > 0 {
+		extras += fmt.Sprintf("截断输出: %d 字节\n", result.TruncatedBytes)
+	}
+	if result.Encoding != "" {
+		extras += fmt.Sprintf("原始编码: %s\n", result.Encoding)
+	}
+	if result.Recording != "" {
+		extras += fmt.Sprintf("终端录制: %s\n", result.Recording)
+	}
+	if result.TrackFiles != "" {
+		extras += fmt.Sprintf("文件变更: %s\n", filetrack.Summary(result.FileChanges))
+	}
+	if result.StdinRecord != "" {
+		extras += fmt.Sprintf("标准输入记录: %s (%s)\n", result.StdinRecord, FormatBytes(result.StdinBytes))
+	}
+	if len(result.Artifacts) > 0 {
+		extras += fmt.Sprintf("产物: %d 个 (%s)\n", len(result.Artifacts), FormatBytes(artifact.TotalSize(result.Artifacts)))
+	}
+	if result.Sandbox != nil {
+		extras += fmt.Sprintf("沙箱: %s\n", result.Sandbox)
+		extras += sandboxDenialFooter(result)
+	}
+	return extras
+}
+
+// sandboxDenialFooter 列出看起来被沙箱拒绝的操作，便于在日志末尾一眼看出哪些访问被阻止
+func sandboxDenialFooter(result *Result) string {
+	if result.SandboxDenialCount == 0 {
+		return ""
+	}
+	footer := fmt.Sprintf("沙箱拒绝: %d 处\n", result.SandboxDenialCount)
+	for _, line := range result.SandboxDenials {
+		footer += fmt.Sprintf("  ! %s\n", line)
+	}
+	if more := result.SandboxDenialCount - len(result.SandboxDenials); more > 0 {
+		footer += fmt.Sprintf("  ... 另有 %d 处\n", more)
+	}
+	return footer
+}
+
+// outputLog 返回写入命令输出的目标，设置了大小上限时经过 logCap，元数据始终直接写入 logFile
+func (e *Executor) outputLog() io.Writer {
+	if e.logCap != nil {
+		return e.logCap
+	}
+	return e.logFile
+}
+
+// logSink 构造指定输出流写入日志的目标并依次套上 LogFilters，没有任何目标时返回 nil
+// raw 日志模式下日志只经过 RawLogFilters，LogFilters 只作用于结构化记录和预览
+func (e *Executor) logSink(stream string) FlushWriter {
+	var (
+		writers []io.Writer
+		raw     FlushWriter // raw 日志模式下只经过 RawLogFilters 的日志
+	)
+	if stream == logrecord.StreamEvent && e.options.Metadata != nil {
+		// raw 日志只包含命令输出，事件写入元数据文件
+		writers = append(writers, &lockedWriter{w: e.options.Metadata, mu: &e.logMu})
+	} else if e.logFile != nil {
+		// 包装 logFile 以支持并发写入
+		log := &lockedWriter{w: e.outputLog(), mu: &e.logMu, lineOpen: &e.lineOpen}
+		if e.options.Metadata != nil {
+			raw = &multiSink{writers: []io.Writer{log}}
+			for i := len(e.options.RawLogFilters) - 1; i >= 0; i-- {
+				raw = e.options.RawLogFilters[i](raw)
+			}
+		} else {
+			writers = append(writers, log)
+		}
+	}
+	if e.records != nil {
+		writers = append(writers, e.records.Stream(stream))
+	}
+	// 事件不是命令输出，不参与预览和错误摘要
+	if e.preview != nil && stream != logrecord.StreamEvent {
+		writers = append(writers, e.preview.stream(stream))
+	}
+	if len(writers) == 0 {
+		return raw
+	}
+
+	var sink FlushWriter = &multiSink{writers: writers}
+	for i := len(e.options.LogFilters) - 1; i >= 0; i-- {
+		sink = e.options.LogFilters[i](sink)
+	}
+	if raw != nil {
+		sink = &multiSink{writers: []io.Writer{raw, sink}}
+	}
+	return sink
+}
+
+// outputSink 可被事件接收协程同步的输出流日志写入目标
+type outputSink struct {
+	mu sync.Mutex
+	w  FlushWriter
+}
+
+func (o *outputSink) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.w.Write(p)
+}
+
+func (o *outputSink) Flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.w.Flush()
+}
+
+// Sync 写出过滤器中已经完整的行，输出流结束前不调用 Flush，避免未结束的行被提前写出
+func (o *outputSink) Sync() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if s, ok := o.w.(Syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// trackOutput 登记输出流的日志写入目标，以便写入事件前同步
+func (e *Executor) trackOutput(w FlushWriter) *outputSink {
+	sink := &outputSink{w: w}
+	e.outputsMu.Lock()
+	e.outputs = append(e.outputs, sink)
+	e.outputsMu.Unlock()
+	return sink
+}
+
+// syncOutputs 同步各输出流中缓存的完整输出（如重复行计数），使随后写入的事件位于这些输出之后
+func (e *Executor) syncOutputs() {
+	e.outputsMu.Lock()
+	defer e.outputsMu.Unlock()
+	for _, sink := range e.outputs {
+		if err := sink.Sync(); err != nil {
+			fmt.Fprintf(e.stderr, "写入日志失败: %v\n", err)
+		}
+	}
+}
+
+// breakLogLine 日志中最后写入的输出停在一行的中间时补上换行，使随后写入的事件单独成行
+// 换行只写入日志，不影响结构化记录
+func (e *Executor) breakLogLine() {
+	e.logMu.Lock()
+	defer e.logMu.Unlock()
+	if e.lineOpen {
+		_, _ = io.WriteString(e.outputLog(), "\n")
+		e.lineOpen = false
+	}
+}
+
+// flusher 由需要在输出结束时刷新残留数据的写入器实现
+type flusher interface {
+	Flush() error
+}
+
+// multiSink 将同一份输出写入多个目标
+type multiSink struct {
+	writers []io.Writer
+}
+
+func (m *multiSink) Write(p []byte) (int, error) {
+	var firstErr error
+	for _, w := range m.writers {
+		if _, err := w.Write(p); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return 0, firstErr
+	}
+	return len(p), nil
+}
+
+// Flush 刷新所有支持刷新的目标
+func (m *multiSink) Flush() error {
+	var firstErr error
+	for _, w := range m.writers {
+		if f, ok := w.(flusher); ok {
+			if err := f.Flush(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Sync 同步所有支持中途同步的目标
+func (m *multiSink) Sync() error {
+	var firstErr error
+	for _, w := range m.writers {
+		if s, ok := w.(Syncer); ok {
+			if err := s.Sync(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
 }
 
 type lockedWriter struct {
-	mu *sync.Mutex
-	w  io.Writer
+	mu       *sync.Mutex
+	w        io.Writer
+	lineOpen *bool // 非 nil 时记录最后写入的内容是否停在一行的中间
 }
 
 func (l *lockedWriter) Write(p []byte) (int, error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	if l.lineOpen != nil && len(p) > 0 {
+		*l.lineOpen = p[len(p)-1] != '\n'
+	}
 	return l.w.Write(p)
 }
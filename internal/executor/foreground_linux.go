@@ -0,0 +1,146 @@
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// cldStopped waitid 返回的 si_code，表示子进程被暂停
+const cldStopped = 5
+
+// foreground 让命令的进程组成为终端的前台进程组，命令可以直接读取终端、修改终端属性
+// （如 sudo、ssh 和 git 凭据的密码提示），Ctrl+C、Ctrl+Z 由终端直接发送给命令。
+// 期间 logcmd 位于后台进程组，忽略 SIGTTOU 以便继续输出并在结束后收回终端；
+// 命令被暂停时 logcmd 收回终端并暂停自身，使 shell 能够继续作业控制，恢复后再把终端交还给命令
+type foreground struct {
+	tty       *os.File
+	pgrp      int  // logcmd 所在的进程组
+	resetTTOU bool // 结束后是否恢复 SIGTTOU 的默认处理
+	pid       int
+
+	sigCh    chan os.Signal
+	done     chan struct{}
+	finished chan struct{}
+}
+
+// foreground 在管道模式下需要让命令直接使用终端时返回前台控制器，否则返回 nil
+// 仅当标准输入是 logcmd 所在前台进程组的终端，且不需要记录输入、不在沙箱中运行时启用；
+// logcmd 在后台运行时沿用转发输入的方式，避免抢占 shell 的终端
+func (e *Executor) foreground() *foreground {
+	f, ok := e.options.Stdin.(*os.File)
+	if !ok || e.options.StdinRecord != nil || e.options.Sandbox != nil {
+		return nil
+	}
+	fd := int(f.Fd())
+	if !term.IsTerminal(fd) {
+		return nil
+	}
+	pgrp := syscall.Getpgrp()
+	if owner, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP); err != nil || owner != pgrp {
+		return nil
+	}
+
+	fg := &foreground{
+		tty:      f,
+		pgrp:     pgrp,
+		sigCh:    make(chan os.Signal, 1),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	if !signal.Ignored(syscall.SIGTTOU) {
+		signal.Ignore(syscall.SIGTTOU)
+		fg.resetTTOU = true
+	}
+	return fg
+}
+
+// attach 让 cmd 启动时成为终端的前台进程组，标准输入直接使用终端，f 为 nil 时不做任何事
+func (f *foreground) attach(cmd *exec.Cmd) {
+	if f == nil {
+		return
+	}
+	cmd.Stdin = f.tty
+	cmd.SysProcAttr.Foreground = true
+	cmd.SysProcAttr.Ctty = int(f.tty.Fd())
+}
+
+// watch 在命令启动后处理命令被暂停（Ctrl+Z）的情况
+func (f *foreground) watch(pid int) {
+	if f == nil {
+		return
+	}
+	f.pid = pid
+	signal.Notify(f.sigCh, syscall.SIGCHLD)
+	go func() {
+		defer close(f.finished)
+		for {
+			select {
+			case <-f.done:
+				return
+			case <-f.sigCh:
+				if childStopped(pid) {
+					f.suspend()
+				}
+			}
+		}
+	}()
+}
+
+// suspend 命令被暂停时把终端还给 logcmd 所在的进程组并暂停 logcmd；
+// logcmd 恢复运行后，若位于前台（fg）则把终端交还给命令，再让命令继续运行
+func (f *foreground) suspend() {
+	fd := int(f.tty.Fd())
+	_ = unix.IoctlSetPointerInt(fd, unix.TIOCSPGRP, f.pgrp)
+
+	cont := make(chan os.Signal, 1)
+	signal.Notify(cont, syscall.SIGCONT)
+	defer signal.Stop(cont)
+	_ = syscall.Kill(os.Getpid(), syscall.SIGSTOP)
+	<-cont
+
+	if owner, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP); err == nil && owner == f.pgrp {
+		_ = unix.IoctlSetPointerInt(fd, unix.TIOCSPGRP, f.pid)
+	}
+	_ = syscall.Kill(-f.pid, syscall.SIGCONT)
+}
+
+// stop 在命令结束后收回终端并恢复信号处理，f 为 nil 时不做任何事
+func (f *foreground) stop() {
+	if f == nil {
+		return
+	}
+	if f.pid != 0 {
+		close(f.done)
+		<-f.finished
+		signal.Stop(f.sigCh)
+	}
+
+	// 终端仍属于命令的进程组（或该进程组已不存在）时收回，用户已用 bg 把 logcmd 放到后台时不抢占
+	fd := int(f.tty.Fd())
+	if owner, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP); err == nil && owner != f.pgrp && (owner == f.pid || owner == 0 || f.pid == 0) {
+		_ = unix.IoctlSetPointerInt(fd, unix.TIOCSPGRP, f.pgrp)
+	}
+	if f.resetTTOU {
+		signal.Reset(syscall.SIGTTOU)
+	}
+}
+
+// interrupted 判断命令是否被终端发送的中断信号（Ctrl+C、Ctrl+\）结束
+// 前台模式下这些信号直接发给命令，logcmd 不会收到和转发
+func (f *foreground) interrupted(sig os.Signal) bool {
+	return f != nil && (sig == syscall.SIGINT || sig == syscall.SIGQUIT)
+}
+
+// childStopped 判断命令是否处于暂停状态，只查询暂停事件，不会回收已退出的进程
+func childStopped(pid int) bool {
+	var info unix.Siginfo
+	if err := unix.Waitid(unix.P_PID, pid, &info, unix.WSTOPPED|unix.WNOHANG, nil); err != nil {
+		return false
+	}
+	return info.Signo == int32(syscall.SIGCHLD) && info.Code == cldStopped
+}
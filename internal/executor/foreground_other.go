@@ -0,0 +1,26 @@
+//go:build !linux
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+)
+
+// foreground 当前平台不支持把命令放到终端前台，终端输入经由 logcmd 转发
+type foreground struct{}
+
+// foreground 当前平台总是返回 nil
+func (e *Executor) foreground() *foreground {
+	return nil
+}
+
+func (f *foreground) attach(cmd *exec.Cmd) {}
+
+func (f *foreground) watch(pid int) {}
+
+func (f *foreground) stop() {}
+
+func (f *foreground) interrupted(sig os.Signal) bool {
+	return false
+}
@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// logCap 限制写入日志的命令输出大小
+// 超过上限后保留开头的 head 字节和最近的 tail 字节，中间部分丢弃并以提示行标记
+type logCap struct {
+	w        io.Writer
+	head     int64     // 开头保留的字节数
+	written  int64     // 已写入开头部分的字节数
+	tailSize int       // 末尾保留的字节数
+	tail     []byte    // 超出开头部分的输出，只保留最近的内容
+	overflow int64     // 超出开头部分的总字节数
+	notices  io.Writer // 截断提示的写入目标，nil 表示写入日志
+}
+
+// newLogCap 创建大小限制，tail 为 0 时使用上限的一半，超过上限时按上限处理
+func newLogCap(w io.Writer, max, tail int64) *logCap {
+	if tail <= 0 {
+		tail = max / 2
+	}
+	if tail > max {
+		tail = max
+	}
+	return &logCap{w: w, head: max - tail, tailSize: int(tail)}
+}
+
+func (c *logCap) Write(p []byte) (int, error) {
+	n := len(p)
+	if remaining := c.head - c.written; remaining > 0 {
+		chunk := p
+		if int64(len(chunk)) > remaining {
+			chunk = chunk[:remaining]
+		}
+		if _, err := c.w.Write(chunk); err != nil {
+			return 0, err
+		}
+		c.written += int64(len(chunk))
+		p = p[len(chunk):]
+	}
+	if len(p) == 0 {
+		return n, nil
+	}
+
+	c.overflow += int64(len(p))
+	c.tail = append(c.tail, p...)
+	// 缓存达到两倍 tail 时才整体前移，避免每次写入都复制
+	if excess := len(c.tail) - c.tailSize; excess >= c.tailSize {
+		c.tail = append(c.tail[:0], c.tail[excess:]...)
+	}
+	return n, nil
+}
+
+// finish 写出保留的末尾输出，返回被截断的字节数
+func (c *logCap) finish() (int64, error) {
+	if c.overflow == 0 {
+		return 0, nil
+	}
+
+	keep := c.tail
+	if len(keep) > c.tailSize {
+		keep = keep[len(keep)-c.tailSize:]
+	}
+	truncated := c.overflow - int64(len(keep))
+	if truncated == 0 {
+		_, err := c.w.Write(keep)
+		return 0, err
+	}
+
+	// 末尾部分从完整的一行开始
+	if i := bytes.IndexByte(keep, '\n'); i >= 0 && i < len(keep)-1 {
+		truncated += int64(i + 1)
+		keep = keep[i+1:]
+	}
+	marker := fmt.Sprintf("\n[logcmd] 日志超过大小上限，已截断 %d 字节 (%s)\n", truncated, FormatBytes(truncated))
+	notices := c.notices
+	if notices == nil {
+		notices = c.w
+	}
+	if _, err := io.WriteString(notices, marker); err != nil {
+		return truncated, err
+	}
+	c.tail = nil
+	_, err := c.w.Write(keep)
+	return truncated, err
+}
@@ -0,0 +1,230 @@
+package executor
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/aliancn/logcmd/internal/sandbox"
+)
+
+// errorLineRegex 看起来像错误信息的输出行（如 "error: ..."、"npm ERR!"、"ValueError: ..."、"错误: ..."）
+var errorLineRegex = regexp.MustCompile(`(?i:^\s*\[?(?:error|fatal|panic)\]?[:\s])|(?i:\b(?:error|fatal|panic):)|\bERR!|\b\w*(?:Error|Exception):|^\s*(?:错误|失败|异常)|(?:错误|失败|异常)[:：]`)
+
+// previewANSIRegex 预览和错误摘要中去掉的颜色与光标控制序列
+var previewANSIRegex = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+const (
+	maxExcerptRunes = 200      // 错误摘要最多保留的字符数
+	maxScanLine     = 4 * 1024 // 识别错误行时单行最多缓存的字节数
+	maxDenials      = 20       // 沙箱拒绝最多保留的输出行数
+)
+
+// outputPreview 汇总所有输出流的预览与第一条错误行
+type outputPreview struct {
+	limit   int
+	mu      sync.Mutex
+	excerpt string
+	streams map[string]*streamPreview
+
+	denials     []string // 看起来被沙箱拒绝的输出行，nil 表示未启用识别
+	denialCount int
+}
+
+func newOutputPreview(limit int) *outputPreview {
+	return &outputPreview{limit: limit, streams: make(map[string]*streamPreview)}
+}
+
+// stream 返回指定输出流的预览写入器
+func (o *outputPreview) stream(name string) *streamPreview {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	s := &streamPreview{parent: o, limit: o.limit}
+	o.streams[name] = s
+	return s
+}
+
+// trackDenials 开始识别被沙箱拒绝的操作
+func (o *outputPreview) trackDenials() {
+	o.denials = []string{}
+}
+
+// checkDenial 记录看起来被沙箱拒绝的输出行，未启用识别时不做任何事
+func (o *outputPreview) checkDenial(line []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.denials == nil || !sandbox.IsDenial(line) {
+		return
+	}
+	o.denialCount++
+	if len(o.denials) < maxDenials {
+		o.denials = append(o.denials, excerptLine(line))
+	}
+}
+
+// sandboxDenials 返回记录的拒绝行和总数
+func (o *outputPreview) sandboxDenials() ([]string, int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.denials) == 0 {
+		return nil, o.denialCount
+	}
+	return o.denials, o.denialCount
+}
+
+// setExcerpt 记录第一条错误行，之后的错误行忽略
+func (o *outputPreview) setExcerpt(line []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.excerpt == "" {
+		o.excerpt = excerptLine(line)
+	}
+}
+
+func (o *outputPreview) found() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.excerpt != ""
+}
+
+// preview 返回指定输出流的预览文本
+func (o *outputPreview) preview(name string) string {
+	o.mu.Lock()
+	s := o.streams[name]
+	o.mu.Unlock()
+	if s == nil {
+		return ""
+	}
+	return s.String()
+}
+
+// lastLine 返回指定输出流末尾最后一个非空行
+func (o *outputPreview) lastLine(name string) string {
+	o.mu.Lock()
+	s := o.streams[name]
+	o.mu.Unlock()
+	if s == nil {
+		return ""
+	}
+	return excerptLine([]byte(s.last))
+}
+
+// streamPreview 记录一个输出流开头和末尾各 limit 字节，并逐行识别错误信息
+type streamPreview struct {
+	parent *outputPreview
+	limit  int
+	head   []byte
+	tail   []byte
+	total  int64
+	line   []byte // 尚未结束的行
+	last   string // 最后一个非空行
+	done   bool   // 已找到错误行，不再识别错误
+}
+
+func (s *streamPreview) Write(p []byte) (int, error) {
+	s.record(p)
+	s.scan(p)
+	return len(p), nil
+}
+
+func (s *streamPreview) record(p []byte) {
+	if s.limit <= 0 {
+		return
+	}
+	s.total += int64(len(p))
+	if room := s.limit - len(s.head); room > 0 {
+		n := len(p)
+		if n > room {
+			n = room
+		}
+		s.head = append(s.head, p[:n]...)
+		p = p[n:]
+	}
+	if len(p) == 0 {
+		return
+	}
+	s.tail = append(s.tail, p...)
+	if excess := len(s.tail) - s.limit; excess >= s.limit {
+		s.tail = append(s.tail[:0], s.tail[excess:]...)
+	}
+}
+
+func (s *streamPreview) scan(p []byte) {
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			if room := maxScanLine - len(s.line); room > 0 {
+				if len(p) > room {
+					p = p[:room]
+				}
+				s.line = append(s.line, p...)
+			}
+			return
+		}
+		s.line = append(s.line, p[:i]...)
+		p = p[i+1:]
+		s.checkLine()
+	}
+}
+
+// checkLine 检查已结束的一行，其他输出流已找到错误行时停止识别
+func (s *streamPreview) checkLine() {
+	line := previewANSIRegex.ReplaceAll(s.line, nil)
+	s.line = s.line[:0]
+	if len(bytes.TrimSpace(line)) > 0 {
+		s.last = string(line)
+		s.parent.checkDenial(line)
+	}
+	if s.done {
+		return
+	}
+	if s.parent.found() {
+		s.done = true
+		return
+	}
+	if errorLineRegex.Match(line) {
+		s.parent.setExcerpt(line)
+		s.done = true
+	}
+}
+
+// Flush 检查最后一个没有换行的行
+func (s *streamPreview) Flush() error {
+	if len(s.line) > 0 {
+		s.checkLine()
+	}
+	return nil
+}
+
+// String 返回预览文本，超过 2*limit 时开头与末尾之间以 ... 分隔
+func (s *streamPreview) String() string {
+	tail := s.tail
+	if len(tail) > s.limit {
+		tail = tail[len(tail)-s.limit:]
+	}
+	text := string(s.head)
+	if s.total > int64(len(s.head)+len(tail)) {
+		text += "\n...\n"
+	}
+	text += string(tail)
+	text = previewANSIRegex.ReplaceAllString(text, "")
+	return strings.ToValidUTF8(text, "")
+}
+
+// excerptLine 将一行输出整理为单行摘要
+func excerptLine(line []byte) string {
+	text := strings.ToValidUTF8(string(previewANSIRegex.ReplaceAll(line, nil)), "")
+	// \r 重绘的行只保留最终显示的内容
+	text = strings.TrimRight(text, "\r")
+	if i := strings.LastIndexByte(text, '\r'); i >= 0 {
+		text = text[i+1:]
+	}
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) > maxExcerptRunes {
+		runes := []rune(text)
+		text = string(runes[:maxExcerptRunes]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,119 @@
+//go:build !windows
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// forwardedSignals 执行期间转发给命令进程组的信号
+var forwardedSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// terminateSignal 上下文取消时用于优雅终止命令的信号
+var terminateSignal os.Signal = syscall.SIGTERM
+
+// setProcessGroup 让命令在独立的进程组中运行，便于整体转发信号
+// 标准输入是终端时，foreground 会让该进程组成为终端的前台进程组
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// setSession 让命令在新的会话中运行，会话 id 与进程组 id 都等于命令的 pid，仍可整体转发信号；
+// 新会话没有控制终端，沙箱中的命令无法打开 /dev/tty 或通过 TIOCSTI 向用户终端注入输入
+func setSession(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+}
+
+// signalGroup 向命令所在的进程组发送信号
+func signalGroup(cmd *exec.Cmd, sig os.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return cmd.Process.Signal(sig)
+	}
+	err := syscall.Kill(-cmd.Process.Pid, s)
+	if errors.Is(err, syscall.ESRCH) {
+		return nil
+	}
+	return err
+}
+
+// SignalProcessGroup 向进程组 pgid 中的全部进程发送信号，进程组已不存在时返回 nil
+func SignalProcessGroup(pgid int, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return fmt.Errorf("不支持的信号: %v", sig)
+	}
+	err := syscall.Kill(-pgid, s)
+	if errors.Is(err, syscall.ESRCH) {
+		return nil
+	}
+	return err
+}
+
+// signalName 返回信号的标准名称（如 SIGTERM）
+func signalName(sig os.Signal) string {
+	if s, ok := sig.(syscall.Signal); ok {
+		if name := unix.SignalName(s); name != "" {
+			return name
+		}
+	}
+	return sig.String()
+}
+
+// signalNumber 返回信号名称对应的信号值，未知名称返回 0
+func signalNumber(name string) int {
+	return int(unix.SignalNum(name))
+}
+
+// processUsage 读取已结束进程的 rusage，包含其已回收的子进程
+func processUsage(state *os.ProcessState) *Usage {
+	if state == nil {
+		return nil
+	}
+	ru, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || ru == nil {
+		return nil
+	}
+	// Linux 上 ru_maxrss 的单位是 KiB，macOS 上是字节
+	maxRSS := int64(ru.Maxrss)
+	if runtime.GOOS != "darwin" {
+		maxRSS *= 1024
+	}
+	return &Usage{
+		UserCPU:                time.Duration(ru.Utime.Nano()),
+		SystemCPU:              time.Duration(ru.Stime.Nano()),
+		MaxRSS:                 maxRSS,
+		InBlock:                int64(ru.Inblock),
+		OutBlock:               int64(ru.Oublock),
+		VoluntaryCtxSwitches:   int64(ru.Nvcsw),
+		InvoluntaryCtxSwitches: int64(ru.Nivcsw),
+	}
+}
+
+// exitSignal 返回导致进程退出的信号，正常退出时返回 nil
+func exitSignal(state *os.ProcessState) os.Signal {
+	if state == nil {
+		return nil
+	}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return ws.Signal()
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+//go:build windows
+
+package executor
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// forwardedSignals 执行期间转发给命令的信号
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+// terminateSignal 当前平台无法优雅终止命令，直接结束进程
+var terminateSignal = os.Kill
+
+// setProcessGroup 当前平台不支持进程组
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// setSession 当前平台不支持会话
+func setSession(cmd *exec.Cmd) {}
+
+// signalGroup 当前平台无法向进程发送信号，统一结束命令进程
+func signalGroup(cmd *exec.Cmd, sig os.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+// SignalProcessGroup 当前平台不支持进程组，直接结束 pgid 对应的进程（即进程组的首进程）
+func SignalProcessGroup(pgid int, sig os.Signal) error {
+	process, err := os.FindProcess(pgid)
+	if err != nil {
+		return nil
+	}
+	if err := process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	return nil
+}
+
+// signalName 返回信号名称
+func signalName(sig os.Signal) string {
+	return sig.String()
+}
+
+// signalNumber 当前平台没有信号值
+func signalNumber(name string) int {
+	return 0
+}
+
+// processUsage 当前平台不提供 rusage
+func processUsage(state *os.ProcessState) *Usage {
+	return nil
+}
+
+// exitSignal 当前平台无法获取终止信号
+func exitSignal(state *os.ProcessState) os.Signal {
+	return nil
+}
@@ -0,0 +1,98 @@
+//go:build !windows
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/aliancn/logcmd/internal/logrecord"
+	"github.com/creack/pty"
+	"golang.org/x/term"
+)
+
+// startPTY 在伪终端中启动命令
+// 若当前标准输入是终端，则同步窗口大小；转发的输入来自该终端时以 raw 模式转发键盘输入
+func (e *Executor) startPTY(cmd *exec.Cmd) (func() error, error) {
+	stdinFd := int(os.Stdin.Fd())
+	interactive := term.IsTerminal(stdinFd)
+	keyboard := interactive && e.options.Stdin == os.Stdin
+
+	var size *pty.Winsize
+	if interactive {
+		if ws, err := pty.GetsizeFull(os.Stdin); err == nil {
+			size = ws
+		}
+	}
+
+	ptmx, err := pty.StartWithSize(cmd, size)
+	if err != nil {
+		return nil, fmt.Errorf("启动命令失败: %w", err)
+	}
+
+	winch := make(chan os.Signal, 1)
+	restore := func() {}
+	if interactive {
+		// 窗口大小变化时同步到伪终端
+		signal.Notify(winch, syscall.SIGWINCH)
+		go func() {
+			for range winch {
+				_ = pty.InheritSize(os.Stdin, ptmx)
+			}
+		}()
+	}
+	if keyboard {
+		if state, err := term.MakeRaw(stdinFd); err == nil {
+			restore = func() { _ = term.Restore(stdinFd, state) }
+		}
+	}
+
+	// 转发输入，命令结束并关闭伪终端后停止
+	var stdin *stdinForwarder
+	if e.options.Stdin != nil {
+		stdin = newStdinForwarder(e.options.Stdin, e.options.StdinRecord)
+		stdin.start(ptmx, func(err error) {
+			if err == nil && !keyboard {
+				// 输入来自文件或管道时，读完后发送 EOF 字符，使读取输入的命令能够结束
+				_, _ = ptmx.Write([]byte{4})
+			}
+		})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// 伪终端合并了 stdout 与 stderr，统一按终端输出处理
+		e.streamOutput(ptyReader{f: ptmx}, e.console.stdout, logrecord.StreamStdout)
+	}()
+
+	return func() error {
+		<-done
+		err := cmd.Wait()
+
+		signal.Stop(winch)
+		close(winch)
+		restore()
+		ptmx.Close()
+		stdin.stop()
+		return err
+	}, nil
+}
+
+// ptyReader 将伪终端关闭时返回的 EIO 视为正常结束
+type ptyReader struct {
+	f *os.File
+}
+
+func (r ptyReader) Read(p []byte) (int, error) {
+	n, err := r.f.Read(p)
+	if err != nil && errors.Is(err, syscall.EIO) {
+		err = io.EOF
+	}
+	return n, err
+}
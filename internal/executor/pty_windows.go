@@ -0,0 +1,13 @@
+//go:build windows
+
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// startPTY 当前平台不支持伪终端模式
+func (e *Executor) startPTY(cmd *exec.Cmd) (func() error, error) {
+	return nil, fmt.Errorf("当前平台不支持伪终端模式")
+}
@@ -0,0 +1,94 @@
+//go:build linux
+
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// atClockTick 辅助向量中 USER_HZ 的键（AT_CLKTCK），sysconf(_SC_CLK_TCK) 返回的就是该值
+const atClockTick = 17
+
+// clockTicks /proc/<pid>/stat 中 CPU 时间的单位（USER_HZ），与 sysconf(_SC_CLK_TCK) 一致
+var clockTicks = readClockTicks()
+
+// readClockTicks 从辅助向量读取 USER_HZ，无法读取时使用绝大多数内核的默认值 100
+func readClockTicks() int64 {
+	if auxv, err := unix.Auxv(); err == nil {
+		for _, kv := range auxv {
+			if kv[0] == atClockTick && kv[1] > 0 {
+				return int64(kv[1])
+			}
+		}
+	}
+	return 100
+}
+
+// readGroupSample 从 /proc 汇总进程组内所有进程的 CPU 时间与常驻内存
+func readGroupSample(pgid int) (Sample, error) {
+	var sample Sample
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return sample, fmt.Errorf("读取 /proc 失败: %w", err)
+	}
+
+	pageSize := int64(os.Getpagesize())
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "stat"))
+		if err != nil {
+			// 进程可能已经退出
+			continue
+		}
+		stat, ok := parseProcStat(data)
+		if !ok || stat.pgrp != pgid {
+			continue
+		}
+		sample.Processes++
+		sample.CPU += time.Duration(stat.utime+stat.stime) * time.Second / time.Duration(clockTicks)
+		sample.RSS += stat.rss * pageSize
+	}
+
+	return sample, nil
+}
+
+type procStat struct {
+	pgrp  int
+	utime int64
+	stime int64
+	rss   int64
+}
+
+// parseProcStat 解析 /proc/<pid>/stat，进程名可能包含空格和括号，因此从最后一个 ')' 之后开始解析
+func parseProcStat(data []byte) (procStat, bool) {
+	var stat procStat
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return stat, false
+	}
+	// 字段从第 3 项 state 开始
+	fields := bytes.Fields(data[end+1:])
+	if len(fields) < 22 {
+		return stat, false
+	}
+
+	pgrp, err := strconv.Atoi(string(fields[2]))
+	if err != nil {
+		return stat, false
+	}
+	stat.pgrp = pgrp
+	stat.utime, _ = strconv.ParseInt(string(fields[11]), 10, 64)
+	stat.stime, _ = strconv.ParseInt(string(fields[12]), 10, 64)
+	stat.rss, _ = strconv.ParseInt(string(fields[21]), 10, 64)
+	return stat, true
+}
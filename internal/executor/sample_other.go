@@ -0,0 +1,10 @@
+//go:build !linux
+
+package executor
+
+import "errors"
+
+// readGroupSample 当前平台没有 /proc，无法在运行期间采样
+func readGroupSample(pgid int) (Sample, error) {
+	return Sample{}, errors.New("当前平台不支持资源采样")
+}
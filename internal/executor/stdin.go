@@ -0,0 +1,116 @@
+package executor
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// stdinForwarder 将 Options.Stdin 转发给命令，并按需保存转发的内容
+type stdinForwarder struct {
+	src    io.Reader
+	record io.Writer
+	cancel *cancelReader // 可以中断的输入，平台或输入类型不支持时为 nil
+	done   chan struct{} // 转发 goroutine 结束时关闭，未开始转发时为 nil
+
+	mu      sync.Mutex
+	stopped bool
+}
+
+// newStdinForwarder 创建转发器，输入是文件（终端、管道）时使用可中断的读取，
+// 命令结束后 stop 能够让阻塞在 Read 上的 goroutine 立即退出
+func newStdinForwarder(src io.Reader, record io.Writer) *stdinForwarder {
+	f := &stdinForwarder{src: src, record: record}
+	if file, ok := src.(*os.File); ok {
+		if cr, err := newCancelReader(file); err == nil {
+			f.src = cr
+			f.cancel = cr
+		}
+	}
+	return f
+}
+
+// wireStdin 在命令启动前连接标准输入，返回的转发器在命令启动后调用 start，结束后调用 stop
+// 不需要记录的普通文件或管道直接交给命令；命令在终端前台运行时已直接使用终端（见 foreground）；
+// 其余终端输入经由管道转发，因为命令运行在后台进程组中，直接读取终端会收到 SIGTTIN 而被挂起
+func (e *Executor) wireStdin(cmd *exec.Cmd) (*stdinForwarder, io.WriteCloser, error) {
+	if e.options.Stdin == nil || cmd.Stdin != nil {
+		return nil, nil, nil
+	}
+	if f, ok := e.options.Stdin.(*os.File); ok && e.options.StdinRecord == nil && !term.IsTerminal(int(f.Fd())) {
+		cmd.Stdin = f
+		return nil, nil, nil
+	}
+
+	pipe, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	return newStdinForwarder(e.options.Stdin, e.options.StdinRecord), pipe, nil
+}
+
+// start 开始把输入写入 dst，转发结束后以 copy 的结果调用 finish，f 为 nil 时不做任何事
+func (f *stdinForwarder) start(dst io.Writer, finish func(err error)) {
+	if f == nil {
+		return
+	}
+	f.done = make(chan struct{})
+	go func() {
+		defer close(f.done)
+		finish(f.copy(dst))
+	}()
+}
+
+// copy 将输入写入 dst 直到输入结束、写入失败或转发被停止
+func (f *stdinForwarder) copy(dst io.Writer) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.src.Read(buf)
+		if n > 0 {
+			f.mu.Lock()
+			if f.stopped {
+				// 命令结束后读到的输入（如用户继续在终端中输入）不再转发和记录
+				f.mu.Unlock()
+				return nil
+			}
+			if f.record != nil {
+				_, _ = f.record.Write(buf[:n])
+			}
+			f.mu.Unlock()
+
+			if _, wErr := dst.Write(buf[:n]); wErr != nil {
+				return wErr
+			}
+		}
+		if err != nil {
+			if err == io.EOF || err == errReadCanceled {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// stop 停止转发和记录，并等待转发 goroutine 退出，f 为 nil 时不做任何事
+// 需要在命令的标准输入关闭之后调用，避免 goroutine 阻塞在写入上；
+// 输入不支持中断时（如 Windows 控制台）goroutine 可能仍阻塞在 Read 上，不等待其退出，之后读到的内容会被丢弃
+func (f *stdinForwarder) stop() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	f.stopped = true
+	f.mu.Unlock()
+
+	if f.cancel == nil {
+		return
+	}
+	f.cancel.cancel()
+	if f.done != nil {
+		<-f.done
+	}
+	f.cancel.close()
+}
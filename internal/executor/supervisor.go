@@ -0,0 +1,158 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// DefaultGracePeriod 转发终止信号后等待命令退出的默认时长，超时后发送 SIGKILL
+const DefaultGracePeriod = 10 * time.Second
+
+// supervisor 在命令运行期间转发信号、执行超时与无输出看门狗，并在宽限期后强制结束进程组
+type supervisor struct {
+	exec        *Executor
+	cmd         *exec.Cmd
+	grace       time.Duration
+	timeout     time.Duration
+	idleTimeout time.Duration
+	errOut      io.Writer
+
+	done     chan struct{}
+	finished chan struct{}
+
+	mu       sync.Mutex
+	received os.Signal // 最近一次转发给命令的信号
+	reason   string    // 由 logcmd 主动终止命令的原因
+}
+
+// supervise 启动对已运行命令的监管，调用方需在命令结束后调用 stop
+func (e *Executor) supervise(ctx context.Context, cmd *exec.Cmd) *supervisor {
+	grace := e.options.GracePeriod
+	if grace <= 0 {
+		grace = DefaultGracePeriod
+	}
+
+	s := &supervisor{
+		exec:        e,
+		cmd:         cmd,
+		grace:       grace,
+		timeout:     e.options.Timeout,
+		idleTimeout: e.options.IdleTimeout,
+		errOut:      e.stderr,
+		done:        make(chan struct{}),
+		finished:    make(chan struct{}),
+	}
+
+	sigCh := make(chan os.Signal, len(forwardedSignals))
+	signal.Notify(sigCh, forwardedSignals...)
+
+	go func() {
+		defer close(s.finished)
+		defer signal.Stop(sigCh)
+		s.loop(ctx, sigCh)
+	}()
+
+	return s
+}
+
+func (s *supervisor) loop(ctx context.Context, sigCh <-chan os.Signal) {
+	var killTimer, timeoutTimer, idleTimer <-chan time.Time
+	ctxDone := ctx.Done()
+	if s.timeout > 0 {
+		timer := time.NewTimer(s.timeout)
+		defer timer.Stop()
+		timeoutTimer = timer.C
+	}
+
+	var idle *time.Timer
+	if s.idleTimeout > 0 {
+		idle = time.NewTimer(s.idleTimeout)
+		defer idle.Stop()
+		idleTimer = idle.C
+	}
+
+	// escalate 在首次终止请求时启动宽限期计时
+	escalate := func() {
+		if killTimer == nil {
+			killTimer = time.After(s.grace)
+		}
+	}
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case sig := <-sigCh:
+			s.setReceived(sig)
+			s.send(sig)
+			escalate()
+		case <-ctxDone:
+			ctxDone = nil
+			s.send(terminateSignal)
+			escalate()
+		case <-timeoutTimer:
+			timeoutTimer = nil
+			s.setReason(ReasonTimeout)
+			fmt.Fprintf(s.errOut, "\n命令执行超过 %v，正在终止\n", s.timeout)
+			s.exec.logNotice("命令执行超过 %v，发送 %s 终止命令", s.timeout, signalName(terminateSignal))
+			s.send(terminateSignal)
+			escalate()
+		case <-idleTimer:
+			// 计时期间有新的输出时，按最后一次输出的时间重新计时
+			silent := s.exec.sinceLastOutput()
+			if silent < s.idleTimeout {
+				idle.Reset(s.idleTimeout - silent)
+				continue
+			}
+			idleTimer = nil
+			s.setReason(ReasonIdleTimeout)
+			fmt.Fprintf(s.errOut, "\n命令已 %v 没有输出，看门狗正在终止命令\n", silent.Round(time.Millisecond))
+			s.exec.logNotice("看门狗: 命令已 %v 没有任何输出（限制 %v），发送 %s 终止命令",
+				silent.Round(time.Millisecond), s.idleTimeout, signalName(terminateSignal))
+			s.send(terminateSignal)
+			escalate()
+		case <-killTimer:
+			killTimer = nil
+			fmt.Fprintf(s.errOut, "\n命令在 %v 宽限期内未退出，强制结束进程组\n", s.grace)
+			s.exec.logNotice("命令在 %v 宽限期内未退出，发送 SIGKILL 强制结束进程组", s.grace)
+			s.send(os.Kill)
+		}
+	}
+}
+
+func (s *supervisor) send(sig os.Signal) {
+	if err := signalGroup(s.cmd, sig); err != nil {
+		fmt.Fprintf(s.errOut, "发送信号 %s 失败: %v\n", signalName(sig), err)
+	}
+}
+
+// setReason 记录终止原因，仅保留第一次触发的原因
+func (s *supervisor) setReason(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reason == "" {
+		s.reason = reason
+	}
+}
+
+func (s *supervisor) setReceived(sig os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.received = sig
+}
+
+// stop 停止监管，返回转发过的信号以及 logcmd 主动终止命令的原因
+func (s *supervisor) stop() (os.Signal, string) {
+	close(s.done)
+	<-s.finished
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.received, s.reason
+}
@@ -0,0 +1,132 @@
+package executor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultSampleInterval 后台任务未配置采样间隔时使用的默认值
+const DefaultSampleInterval = 10 * time.Second
+
+// Usage 命令结束后由操作系统统计的资源占用（rusage）
+type Usage struct {
+	UserCPU                time.Duration `json:"user_cpu_ns"`              // 用户态 CPU 时间
+	SystemCPU              time.Duration `json:"system_cpu_ns"`            // 内核态 CPU 时间
+	MaxRSS                 int64         `json:"max_rss_bytes"`            // 最大常驻内存（字节）
+	InBlock                int64         `json:"in_block"`                 // 块设备读操作次数
+	OutBlock               int64         `json:"out_block"`                // 块设备写操作次数
+	VoluntaryCtxSwitches   int64         `json:"voluntary_ctx_switches"`   // 自愿上下文切换次数
+	InvoluntaryCtxSwitches int64         `json:"involuntary_ctx_switches"` // 非自愿上下文切换次数
+}
+
+// CPU 返回用户态与内核态 CPU 时间之和
+func (u *Usage) CPU() time.Duration {
+	return u.UserCPU + u.SystemCPU
+}
+
+// Sample 运行期间对命令进程组的一次资源采样
+type Sample struct {
+	Offset    time.Duration `json:"offset_ns"` // 相对命令开始的时间偏移
+	CPU       time.Duration `json:"cpu_ns"`    // 进程组累计 CPU 时间
+	RSS       int64         `json:"rss_bytes"` // 进程组常驻内存之和（字节）
+	Processes int           `json:"processes"` // 进程组中的进程数
+}
+
+// sampler 按固定间隔采样命令进程组的 CPU 与内存
+type sampler struct {
+	pgid     int
+	interval time.Duration
+	start    time.Time
+
+	done     chan struct{}
+	finished chan struct{}
+	samples  []Sample
+	mu       sync.Mutex
+}
+
+// startSampler 启动采样，调用方需在命令结束后调用 stop
+func startSampler(pgid int, interval time.Duration, start time.Time) *sampler {
+	s := &sampler{
+		pgid:     pgid,
+		interval: interval,
+		start:    start,
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+func (s *sampler) loop() {
+	defer close(s.finished)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			sample, err := readGroupSample(s.pgid)
+			if err != nil || sample.Processes == 0 {
+				continue
+			}
+			sample.Offset = now.Sub(s.start)
+			s.mu.Lock()
+			s.samples = append(s.samples, sample)
+			s.mu.Unlock()
+		}
+	}
+}
+
+// stop 停止采样并返回全部样本
+func (s *sampler) stop() []Sample {
+	close(s.done)
+	<-s.finished
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.samples
+}
+
+// PeakRSS 返回样本中进程组内存占用的峰值
+func PeakRSS(samples []Sample) int64 {
+	var peak int64
+	for _, sample := range samples {
+		if sample.RSS > peak {
+			peak = sample.RSS
+		}
+	}
+	return peak
+}
+
+// FormatBytes 将字节数格式化为易读的形式（如 12.3 MiB）
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit && exp < 3; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGT"[exp])
+}
+
+// usageFooter 返回写入日志元数据的资源占用行
+func usageFooter(result *Result) string {
+	var lines string
+	if u := result.Usage; u != nil {
+		lines += fmt.Sprintf("CPU 时间: 用户 %v, 系统 %v\n", u.UserCPU.Round(time.Millisecond), u.SystemCPU.Round(time.Millisecond))
+		lines += fmt.Sprintf("最大内存: %s\n", FormatBytes(u.MaxRSS))
+		lines += fmt.Sprintf("块 I/O: 读 %d, 写 %d\n", u.InBlock, u.OutBlock)
+		lines += fmt.Sprintf("上下文切换: 自愿 %d, 非自愿 %d\n", u.VoluntaryCtxSwitches, u.InvoluntaryCtxSwitches)
+	}
+	if len(result.Samples) > 0 {
+		lines += fmt.Sprintf("采样峰值内存: %s (%d 次采样)\n", FormatBytes(PeakRSS(result.Samples)), len(result.Samples))
+	}
+	return lines
+}
@@ -0,0 +1,288 @@
+// Package filetrack 在命令运行前后对工作目录做快照，找出命令创建、修改和删除的文件
+package filetrack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 变更类型
+const (
+	ChangeCreated  = "created"
+	ChangeModified = "modified"
+	ChangeDeleted  = "deleted"
+)
+
+// DefaultPattern 未指定 glob 时跟踪工作目录下的全部文件
+const DefaultPattern = "**"
+
+// maxHashSize 超过该大小的文件不计算哈希，只按大小和修改时间判断是否变化
+const maxHashSize = 512 << 20
+
+// skipDirs 快照时跳过的目录
+var skipDirs = map[string]bool{
+	".git":    true,
+	".hg":     true,
+	".svn":    true,
+	".logcmd": true,
+}
+
+// FileState 快照中一个文件的状态
+type FileState struct {
+	Size    int64
+	ModTime time.Time
+	Hash    string // 内容的 SHA-256，文件过大时为空
+}
+
+// Snapshot 工作目录快照，键为相对根目录、以 / 分隔的路径
+type Snapshot map[string]FileState
+
+// Change 一个文件的变化
+type Change struct {
+	Path       string `json:"path"`
+	Type       string `json:"type"` // created、modified 或 deleted
+	SizeBefore int64  `json:"size_before"`
+	SizeAfter  int64  `json:"size_after"`
+	HashBefore string `json:"hash_before,omitempty"`
+	HashAfter  string `json:"hash_after,omitempty"`
+}
+
+// Tracker 按 glob 规则对目录做快照
+type Tracker struct {
+	root     string
+	patterns []string
+	exclude  []string
+}
+
+// ParsePatterns 解析逗号分隔的 glob 列表，为空时返回 DefaultPattern
+func ParsePatterns(value string) []string {
+	var patterns []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			patterns = append(patterns, filepath.ToSlash(item))
+		}
+	}
+	if len(patterns) == 0 {
+		return []string{DefaultPattern}
+	}
+	return patterns
+}
+
+// New 创建跟踪 root 下匹配 patterns 的文件的快照器，exclude 中的路径（如日志目录）不参与快照
+func New(root string, patterns []string, exclude ...string) (*Tracker, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("解析跟踪目录失败: %w", err)
+	}
+	for _, pattern := range patterns {
+		if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+			return nil, fmt.Errorf("无效的文件匹配规则 %q: %w", pattern, err)
+		}
+	}
+
+	t := &Tracker{root: absRoot, patterns: patterns}
+	for _, p := range exclude {
+		if p == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(p); err == nil {
+			t.exclude = append(t.exclude, abs)
+		}
+	}
+	return t, nil
+}
+
+// Snapshot 遍历目录生成快照
+// previous 中大小和修改时间都未变化的文件直接沿用其哈希，避免运行后重复读取未改动的文件
+func (t *Tracker) Snapshot(previous Snapshot) (Snapshot, error) {
+	snapshot := make(Snapshot)
+	err := filepath.WalkDir(t.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 无权限等无法读取的目录直接跳过
+			if d != nil && d.IsDir() && p != t.root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if p != t.root && (skipDirs[d.Name()] || t.excluded(p)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || t.excluded(p) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(t.root, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if !t.Match(rel) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		state := FileState{Size: info.Size(), ModTime: info.ModTime()}
+		if prev, ok := previous[rel]; ok && prev.Size == state.Size && prev.ModTime.Equal(state.ModTime) {
+			state.Hash = prev.Hash
+		} else if state.Size <= maxHashSize {
+			state.Hash = hashFile(p)
+		}
+		snapshot[rel] = state
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("生成文件快照失败: %w", err)
+	}
+	return snapshot, nil
+}
+
+// Match 判断相对路径是否匹配任一规则
+// 不含 / 的规则匹配文件名（如 *.go），含 / 的规则匹配完整路径，** 匹配任意层目录
+func (t *Tracker) Match(rel string) bool {
+	for _, pattern := range t.patterns {
+		if matchPattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tracker) excluded(p string) bool {
+	for _, ex := range t.exclude {
+		if p == ex || strings.HasPrefix(p, ex+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPattern(pattern, rel string) bool {
+	if pattern == DefaultPattern {
+		return true
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(strings.TrimPrefix(pattern, "./"), "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments 逐段匹配路径，** 段匹配零个或多个目录
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+func hashFile(p string) string {
+	f, err := os.Open(p)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Diff 比较两次快照，按路径排序返回变化的文件
+// 内容哈希相同的文件即使修改时间变化也不算修改，没有哈希时按大小和修改时间判断
+func Diff(before, after Snapshot) []Change {
+	var changes []Change
+	for p, now := range after {
+		prev, ok := before[p]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Path: p, Type: ChangeCreated, SizeAfter: now.Size, HashAfter: now.Hash})
+		case modified(prev, now):
+			changes = append(changes, Change{
+				Path:       p,
+				Type:       ChangeModified,
+				SizeBefore: prev.Size,
+				SizeAfter:  now.Size,
+				HashBefore: prev.Hash,
+				HashAfter:  now.Hash,
+			})
+		}
+	}
+	for p, prev := range before {
+		if _, ok := after[p]; !ok {
+			changes = append(changes, Change{Path: p, Type: ChangeDeleted, SizeBefore: prev.Size, HashBefore: prev.Hash})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func modified(prev, now FileState) bool {
+	if prev.Hash != "" && now.Hash != "" {
+		return prev.Hash != now.Hash
+	}
+	return prev.Size != now.Size || !prev.ModTime.Equal(now.ModTime)
+}
+
+// Summary 返回变化的统计，如 "新建 1, 修改 2, 删除 0"
+func Summary(changes []Change) string {
+	var created, modifiedCount, deleted int
+	for _, c := range changes {
+		switch c.Type {
+		case ChangeCreated:
+			created++
+		case ChangeModified:
+			modifiedCount++
+		case ChangeDeleted:
+			deleted++
+		}
+	}
+	return fmt.Sprintf("新建 %d, 修改 %d, 删除 %d", created, modifiedCount, deleted)
+}
+
+// TypeLabel 返回变更类型的中文名称
+func TypeLabel(changeType string) string {
+	switch changeType {
+	case ChangeCreated:
+		return "新建"
+	case ChangeModified:
+		return "修改"
+	case ChangeDeleted:
+		return "删除"
+	default:
+		return changeType
+	}
+}
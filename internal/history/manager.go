@@ -9,6 +9,79 @@ import (
 	"github.com/aliancn/logcmd/internal/model"
 )
 
+// historyColumns 查询命令历史时读取的列，顺序与 scanHistory 一致
+const historyColumns = `id, project_id, command, command_name, command_args, IFNULL(shell, ''),
+	start_time, end_time, duration_ms, exit_code, status, IFNULL(termination_signal, ''), IFNULL(termination_reason, ''),
+	IFNULL(attempt_group, ''), IFNULL(attempt, 0),
+	log_file_path, log_date, IFNULL(recording_path, ''), IFNULL(sandbox_profile, ''), IFNULL(sandbox_denials, 0),
+	IFNULL(cpu_user_ms, 0), IFNULL(cpu_system_ms, 0), IFNULL(max_rss_bytes, 0),
+	IFNULL(block_input, 0), IFNULL(block_output, 0),
+	IFNULL(ctx_switches_voluntary, 0), IFNULL(ctx_switches_involuntary, 0), IFNULL(group_peak_rss_bytes, 0),
+	IFNULL(stdout_preview, ''), IFNULL(stderr_preview, ''), IFNULL(error_excerpt, ''), has_error, IFNULL(output_encoding, ''), IFNULL(truncated_bytes, 0),
+	IFNULL(working_directory, ''), IFNULL(environment_info, ''),
+	IFNULL(os_user, ''), IFNULL(os_uid, ''), IFNULL(hostname, ''), IFNULL(tty, ''), IFNULL(parent_process, ''),
+	IFNULL(git_branch, ''), IFNULL(git_commit, ''), IFNULL(git_dirty, 0),
+	created_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanHistory 按 historyColumns 的顺序读取一行命令历史
+func scanHistory(scanner rowScanner) (*model.CommandHistory, error) {
+	var cmd model.CommandHistory
+	if err := scanner.Scan(
+		&cmd.ID,
+		&cmd.ProjectID,
+		&cmd.Command,
+		&cmd.CommandName,
+		&cmd.ArgsJSON,
+		&cmd.Shell,
+		&cmd.StartTime,
+		&cmd.EndTime,
+		&cmd.DurationMs,
+		&cmd.ExitCode,
+		&cmd.Status,
+		&cmd.Signal,
+		&cmd.Reason,
+		&cmd.AttemptGroup,
+		&cmd.Attempt,
+		&cmd.LogFilePath,
+		&cmd.LogDate,
+		&cmd.Recording,
+		&cmd.SandboxProfile,
+		&cmd.SandboxDenials,
+		&cmd.CPUUserMs,
+		&cmd.CPUSystemMs,
+		&cmd.MaxRSSBytes,
+		&cmd.BlockInput,
+		&cmd.BlockOutput,
+		&cmd.CtxSwitchesVoluntary,
+		&cmd.CtxSwitchesInvoluntary,
+		&cmd.GroupPeakRSSBytes,
+		&cmd.StdoutPreview,
+		&cmd.StderrPreview,
+		&cmd.ErrorExcerpt,
+		&cmd.HasError,
+		&cmd.Encoding,
+		&cmd.TruncatedBytes,
+		&cmd.WorkingDirectory,
+		&cmd.EnvironmentJSON,
+		&cmd.OSUser,
+		&cmd.OSUID,
+		&cmd.Hostname,
+		&cmd.TTY,
+		&cmd.ParentProcess,
+		&cmd.GitBranch,
+		&cmd.GitCommit,
+		&cmd.GitDirty,
+		&cmd.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
+
 // Manager 命令历史管理器
 type Manager struct {
 	db *sql.DB
@@ -27,32 +100,64 @@ func (m *Manager) Record(cmd *model.CommandHistory) error {
 
 	query := `
 		INSERT INTO command_history (
-			project_id, command, command_name, command_args,
-			start_time, end_time, duration_ms, exit_code, status,
-			log_file_path, log_date,
-			stdout_preview, stderr_preview, has_error,
+			project_id, command, command_name, command_args, shell,
+			start_time, end_time, duration_ms, exit_code, status, termination_signal, termination_reason,
+			attempt_group, attempt,
+			log_file_path, log_date, recording_path, sandbox_profile, sandbox_denials,
+			cpu_user_ms, cpu_system_ms, max_rss_bytes, block_input, block_output,
+			ctx_switches_voluntary, ctx_switches_involuntary, group_peak_rss_bytes,
+			stdout_preview, stderr_preview, error_excerpt, has_error, output_encoding, truncated_bytes,
 			working_directory, environment_info,
+			os_user, os_uid, hostname, tty, parent_process,
+			git_branch, git_commit, git_dirty,
 			created_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := m.db.Exec(query,
+	result, err := m.db.Exec(query,
 		cmd.ProjectID,
 		cmd.Command,
 		cmd.CommandName,
 		cmd.ArgsJSON,
+		cmd.Shell,
 		cmd.StartTime,
 		cmd.EndTime,
 		cmd.DurationMs,
 		cmd.ExitCode,
 		cmd.Status,
+		cmd.Signal,
+		cmd.Reason,
+		cmd.AttemptGroup,
+		cmd.Attempt,
 		cmd.LogFilePath,
 		cmd.LogDate,
+		cmd.Recording,
+		cmd.SandboxProfile,
+		cmd.SandboxDenials,
+		cmd.CPUUserMs,
+		cmd.CPUSystemMs,
+		cmd.MaxRSSBytes,
+		cmd.BlockInput,
+		cmd.BlockOutput,
+		cmd.CtxSwitchesVoluntary,
+		cmd.CtxSwitchesInvoluntary,
+		cmd.GroupPeakRSSBytes,
 		cmd.StdoutPreview,
 		cmd.StderrPreview,
+		cmd.ErrorExcerpt,
 		cmd.HasError,
+		cmd.Encoding,
+		cmd.TruncatedBytes,
 		cmd.WorkingDirectory,
 		cmd.EnvironmentJSON,
+		cmd.OSUser,
+		cmd.OSUID,
+		cmd.Hostname,
+		cmd.TTY,
+		cmd.ParentProcess,
+		cmd.GitBranch,
+		cmd.GitCommit,
+		cmd.GitDirty,
 		cmd.CreatedAt,
 	)
 
@@ -60,19 +165,250 @@ func (m *Manager) Record(cmd *model.CommandHistory) error {
 		return fmt.Errorf("记录命令历史失败: %w", err)
 	}
 
+	if id, err := result.LastInsertId(); err == nil {
+		cmd.ID = int(id)
+	}
+
 	return nil
 }
 
+// RecordSamples 保存命令运行期间的资源采样
+func (m *Manager) RecordSamples(historyID int, samples []model.ResourceSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("记录资源采样失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO command_samples (history_id, offset_ms, cpu_ms, rss_bytes, processes)
+		VALUES (?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("记录资源采样失败: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, sample := range samples {
+		if _, err := stmt.Exec(historyID, sample.OffsetMs, sample.CPUMs, sample.RSSBytes, sample.Processes); err != nil {
+			return fmt.Errorf("记录资源采样失败: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetSamples 按时间顺序获取命令的资源采样
+func (m *Manager) GetSamples(historyID int) ([]model.ResourceSample, error) {
+	rows, err := m.db.Query(`
+		SELECT id, history_id, offset_ms, cpu_ms, rss_bytes, processes
+		FROM command_samples
+		WHERE history_id = ?
+		ORDER BY offset_ms ASC
+	`, historyID)
+	if err != nil {
+		return nil, fmt.Errorf("查询资源采样失败: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []model.ResourceSample
+	for rows.Next() {
+		var sample model.ResourceSample
+		if err := rows.Scan(&sample.ID, &sample.HistoryID, &sample.OffsetMs, &sample.CPUMs, &sample.RSSBytes, &sample.Processes); err != nil {
+			return nil, fmt.Errorf("读取资源采样失败: %w", err)
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, rows.Err()
+}
+
+// RecordEvents 保存命令运行期间发送的事件
+func (m *Manager) RecordEvents(historyID int, events []model.CommandEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("记录命令事件失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO command_events (history_id, offset_ms, event_type, text, metric_key, metric_value)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("记录命令事件失败: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, event := range events {
+		if _, err := stmt.Exec(historyID, event.OffsetMs, event.Type, event.Text, event.Key, event.Value); err != nil {
+			return fmt.Errorf("记录命令事件失败: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetEvents 按时间顺序获取命令运行期间发送的事件
+func (m *Manager) GetEvents(historyID int) ([]model.CommandEvent, error) {
+	rows, err := m.db.Query(`
+		SELECT id, history_id, offset_ms, event_type, IFNULL(text, ''), IFNULL(metric_key, ''), IFNULL(metric_value, '')
+		FROM command_events
+		WHERE history_id = ?
+		ORDER BY offset_ms ASC, id ASC
+	`, historyID)
+	if err != nil {
+		return nil, fmt.Errorf("查询命令事件失败: %w", err)
+	}
+	defer rows.Close()
+
+	var events []model.CommandEvent
+	for rows.Next() {
+		var event model.CommandEvent
+		if err := rows.Scan(&event.ID, &event.HistoryID, &event.OffsetMs, &event.Type, &event.Text, &event.Key, &event.Value); err != nil {
+			return nil, fmt.Errorf("读取命令事件失败: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// RecordFileChanges 保存命令运行前后的文件变化
+func (m *Manager) RecordFileChanges(historyID int, changes []model.FileChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("记录文件变化失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO command_file_changes (history_id, path, change_type, size_before, size_after, hash_before, hash_after)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("记录文件变化失败: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, change := range changes {
+		if _, err := stmt.Exec(historyID, change.Path, change.Type, change.SizeBefore, change.SizeAfter, change.HashBefore, change.HashAfter); err != nil {
+			return fmt.Errorf("记录文件变化失败: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetFileChanges 按路径顺序获取命令运行前后的文件变化
+func (m *Manager) GetFileChanges(historyID int) ([]model.FileChange, error) {
+	rows, err := m.db.Query(`
+		SELECT id, history_id, path, change_type, IFNULL(size_before, 0), IFNULL(size_after, 0),
+			IFNULL(hash_before, ''), IFNULL(hash_after, '')
+		FROM command_file_changes
+		WHERE history_id = ?
+		ORDER BY path ASC
+	`, historyID)
+	if err != nil {
+		return nil, fmt.Errorf("查询文件变化失败: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []model.FileChange
+	for rows.Next() {
+		var change model.FileChange
+		if err := rows.Scan(&change.ID, &change.HistoryID, &change.Path, &change.Type,
+			&change.SizeBefore, &change.SizeAfter, &change.HashBefore, &change.HashAfter); err != nil {
+			return nil, fmt.Errorf("读取文件变化失败: %w", err)
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
+
+// RecordArtifacts 保存运行结束后收集的产物
+func (m *Manager) RecordArtifacts(historyID int, artifacts []model.Artifact) error {
+	if len(artifacts) == 0 {
+		return nil
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("记录产物失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO command_artifacts (history_id, name, source_path, stored_path, size_bytes, sha256, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("记录产物失败: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, a := range artifacts {
+		if _, err := stmt.Exec(historyID, a.Name, a.SourcePath, a.StoredPath, a.SizeBytes, a.SHA256, now); err != nil {
+			return fmt.Errorf("记录产物失败: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetArtifacts 按名称顺序获取运行收集的产物
+func (m *Manager) GetArtifacts(historyID int) ([]model.Artifact, error) {
+	rows, err := m.db.Query(`
+		SELECT id, history_id, name, IFNULL(source_path, ''), stored_path, IFNULL(size_bytes, 0), IFNULL(sha256, ''), created_at
+		FROM command_artifacts
+		WHERE history_id = ?
+		ORDER BY name ASC
+	`, historyID)
+	if err != nil {
+		return nil, fmt.Errorf("查询产物失败: %w", err)
+	}
+	defer rows.Close()
+
+	var artifacts []model.Artifact
+	for rows.Next() {
+		var a model.Artifact
+		if err := rows.Scan(&a.ID, &a.HistoryID, &a.Name, &a.SourcePath, &a.StoredPath, &a.SizeBytes, &a.SHA256, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("读取产物失败: %w", err)
+		}
+		artifacts = append(artifacts, a)
+	}
+
+	return artifacts, rows.Err()
+}
+
 // QueryOptions 查询选项
 type QueryOptions struct {
-	ProjectID   int       // 项目ID（0表示所有项目）
-	CommandName string    // 命令名称（空表示所有命令）
-	Status      string    // 状态（success/failed，空表示所有）
-	StartDate   time.Time // 开始日期
-	EndDate     time.Time // 结束日期
-	Limit       int       // 限制返回数量（0表示不限制）
-	Offset      int       // 偏移量
-	OrderBy     string    // 排序字段（默认：start_time DESC）
+	ProjectID    int       // 项目ID（0表示所有项目）
+	CommandName  string    // 命令名称（空表示所有命令）
+	Status       string    // 状态（success/failed，空表示所有）
+	AttemptGroup string    // 重试组 ID（空表示不限制）
+	OSUser       string    // 执行用户（空表示不限制）
+	Hostname     string    // 主机名（空表示不限制）
+	GitBranch    string    // git 分支（空表示不限制）
+	StartDate    time.Time // 开始日期
+	EndDate      time.Time // 结束日期
+	Limit        int       // 限制返回数量（0表示不限制）
+	Offset       int       // 偏移量
+	OrderBy      string    // 排序字段（默认：start_time DESC）
 }
 
 // Query 查询命令历史
@@ -96,6 +432,26 @@ func (m *Manager) Query(opts QueryOptions) ([]*model.CommandHistory, error) {
 		args = append(args, opts.Status)
 	}
 
+	if opts.AttemptGroup != "" {
+		conditions = append(conditions, "attempt_group = ?")
+		args = append(args, opts.AttemptGroup)
+	}
+
+	if opts.OSUser != "" {
+		conditions = append(conditions, "os_user = ?")
+		args = append(args, opts.OSUser)
+	}
+
+	if opts.Hostname != "" {
+		conditions = append(conditions, "hostname = ?")
+		args = append(args, opts.Hostname)
+	}
+
+	if opts.GitBranch != "" {
+		conditions = append(conditions, "git_branch = ?")
+		args = append(args, opts.GitBranch)
+	}
+
 	if !opts.StartDate.IsZero() {
 		conditions = append(conditions, "start_time >= ?")
 		args = append(args, opts.StartDate)
@@ -107,15 +463,7 @@ func (m *Manager) Query(opts QueryOptions) ([]*model.CommandHistory, error) {
 	}
 
 	// 构建SQL查询
-	query := `
-		SELECT id, project_id, command, command_name, command_args,
-			   start_time, end_time, duration_ms, exit_code, status,
-			   log_file_path, log_date,
-			   stdout_preview, stderr_preview, has_error,
-			   working_directory, environment_info,
-			   created_at
-		FROM command_history
-	`
+	query := "SELECT " + historyColumns + " FROM command_history"
 
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
@@ -144,27 +492,7 @@ func (m *Manager) Query(opts QueryOptions) ([]*model.CommandHistory, error) {
 
 	var results []*model.CommandHistory
 	for rows.Next() {
-		var cmd model.CommandHistory
-		err := rows.Scan(
-			&cmd.ID,
-			&cmd.ProjectID,
-			&cmd.Command,
-			&cmd.CommandName,
-			&cmd.ArgsJSON,
-			&cmd.StartTime,
-			&cmd.EndTime,
-			&cmd.DurationMs,
-			&cmd.ExitCode,
-			&cmd.Status,
-			&cmd.LogFilePath,
-			&cmd.LogDate,
-			&cmd.StdoutPreview,
-			&cmd.StderrPreview,
-			&cmd.HasError,
-			&cmd.WorkingDirectory,
-			&cmd.EnvironmentJSON,
-			&cmd.CreatedAt,
-		)
+		cmd, err := scanHistory(rows)
 		if err != nil {
 			return nil, fmt.Errorf("读取数据失败: %w", err)
 		}
@@ -173,7 +501,7 @@ func (m *Manager) Query(opts QueryOptions) ([]*model.CommandHistory, error) {
 			return nil, fmt.Errorf("加载数据失败: %w", err)
 		}
 
-		results = append(results, &cmd)
+		results = append(results, cmd)
 	}
 
 	return results, nil
@@ -181,38 +509,9 @@ func (m *Manager) Query(opts QueryOptions) ([]*model.CommandHistory, error) {
 
 // GetByID 根据ID获取命令历史
 func (m *Manager) GetByID(id int) (*model.CommandHistory, error) {
-	query := `
-		SELECT id, project_id, command, command_name, command_args,
-			   start_time, end_time, duration_ms, exit_code, status,
-			   log_file_path, log_date,
-			   stdout_preview, stderr_preview, has_error,
-			   working_directory, environment_info,
-			   created_at
-		FROM command_history
-		WHERE id = ?
-	`
+	query := "SELECT " + historyColumns + " FROM command_history WHERE id = ?"
 
-	var cmd model.CommandHistory
-	err := m.db.QueryRow(query, id).Scan(
-		&cmd.ID,
-		&cmd.ProjectID,
-		&cmd.Command,
-		&cmd.CommandName,
-		&cmd.ArgsJSON,
-		&cmd.StartTime,
-		&cmd.EndTime,
-		&cmd.DurationMs,
-		&cmd.ExitCode,
-		&cmd.Status,
-		&cmd.LogFilePath,
-		&cmd.LogDate,
-		&cmd.StdoutPreview,
-		&cmd.StderrPreview,
-		&cmd.HasError,
-		&cmd.WorkingDirectory,
-		&cmd.EnvironmentJSON,
-		&cmd.CreatedAt,
-	)
+	cmd, err := scanHistory(m.db.QueryRow(query, id))
 
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("未找到命令历史: %d", id)
@@ -225,7 +524,27 @@ func (m *Manager) GetByID(id int) (*model.CommandHistory, error) {
 		return nil, fmt.Errorf("加载数据失败: %w", err)
 	}
 
-	return &cmd, nil
+	return cmd, nil
+}
+
+// GetByLogPath 根据日志文件路径获取命令历史，同一路径有多条记录时返回最新的一条
+func (m *Manager) GetByLogPath(logPath string) (*model.CommandHistory, error) {
+	query := "SELECT " + historyColumns + " FROM command_history WHERE log_file_path = ? ORDER BY id DESC LIMIT 1"
+
+	cmd, err := scanHistory(m.db.QueryRow(query, logPath))
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("未找到日志对应的命令历史: %s", logPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询失败: %w", err)
+	}
+
+	if err := cmd.AfterLoad(); err != nil {
+		return nil, fmt.Errorf("加载数据失败: %w", err)
+	}
+
+	return cmd, nil
 }
 
 // GetRecent 获取最近的命令历史
@@ -247,6 +566,17 @@ func (m *Manager) GetFailed(projectID int, limit int) ([]*model.CommandHistory,
 	})
 }
 
+// GetAttempts 按尝试顺序获取同一重试组的全部命令历史
+func (m *Manager) GetAttempts(group string) ([]*model.CommandHistory, error) {
+	if group == "" {
+		return nil, fmt.Errorf("重试组 ID 不能为空")
+	}
+	return m.Query(QueryOptions{
+		AttemptGroup: group,
+		OrderBy:      "attempt ASC",
+	})
+}
+
 // GetByDate 获取指定日期的命令历史
 func (m *Manager) GetByDate(projectID int, date string) ([]*model.CommandHistory, error) {
 	return m.Query(QueryOptions{
@@ -313,7 +643,7 @@ func (m *Manager) Delete(id int) error {
 		return fmt.Errorf("未找到命令历史: %d", id)
 	}
 
-	return nil
+	return m.pruneOrphans()
 }
 
 // DeleteByProject 删除项目的所有命令历史
@@ -322,7 +652,7 @@ func (m *Manager) DeleteByProject(projectID int) error {
 	if err != nil {
 		return fmt.Errorf("删除项目命令历史失败: %w", err)
 	}
-	return nil
+	return m.pruneOrphans()
 }
 
 // DeleteOldRecords 删除指定天数之前的记录
@@ -339,6 +669,29 @@ func (m *Manager) DeleteOldRecords(days int) error {
 	}
 
 	fmt.Printf("已删除 %d 条旧记录（%d天前）\n", rowsAffected, days)
+	return m.pruneOrphans()
+}
+
+// pruneOrphans 删除已不存在的命令历史对应的资源采样、事件、文件变化和产物记录
+// 产物文件本身保存在日志目录中，随日志一起清理
+// SQLite 默认不启用外键约束，因此需要在删除历史后手动清理
+func (m *Manager) pruneOrphans() error {
+	_, err := m.db.Exec("DELETE FROM command_samples WHERE history_id NOT IN (SELECT id FROM command_history)")
+	if err != nil {
+		return fmt.Errorf("清理资源采样失败: %w", err)
+	}
+	_, err = m.db.Exec("DELETE FROM command_events WHERE history_id NOT IN (SELECT id FROM command_history)")
+	if err != nil {
+		return fmt.Errorf("清理命令事件失败: %w", err)
+	}
+	_, err = m.db.Exec("DELETE FROM command_file_changes WHERE history_id NOT IN (SELECT id FROM command_history)")
+	if err != nil {
+		return fmt.Errorf("清理文件变化失败: %w", err)
+	}
+	_, err = m.db.Exec("DELETE FROM command_artifacts WHERE history_id NOT IN (SELECT id FROM command_history)")
+	if err != nil {
+		return fmt.Errorf("清理产物记录失败: %w", err)
+	}
 	return nil
 }
 
@@ -4,13 +4,31 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/aliancn/logcmd/internal/artifact"
+	"github.com/aliancn/logcmd/internal/asciicast"
+	"github.com/aliancn/logcmd/internal/charset"
 	"github.com/aliancn/logcmd/internal/config"
+	"github.com/aliancn/logcmd/internal/envsnap"
+	"github.com/aliancn/logcmd/internal/execctx"
 	"github.com/aliancn/logcmd/internal/executor"
+	"github.com/aliancn/logcmd/internal/filetrack"
+	"github.com/aliancn/logcmd/internal/logmeta"
+	"github.com/aliancn/logcmd/internal/logrecord"
 	"github.com/aliancn/logcmd/internal/model"
+	"github.com/aliancn/logcmd/internal/redact"
+	"github.com/aliancn/logcmd/internal/retry"
+	"github.com/aliancn/logcmd/internal/shellcmd"
+	"github.com/aliancn/logcmd/internal/transform"
 )
 
 // Logger 日志记录器
@@ -21,6 +39,17 @@ type Logger struct {
 	file         *os.File
 	writer       *bufio.Writer
 	logPath      string // 预设的日志路径
+	onStart      func(pgid int)
+	onAttempt    func(attempt int, logPath string)
+	execContext  *execctx.Info // 执行上下文，未设置时在运行前采集
+	script       string        // shell 模式下执行的脚本
+	source       string        // 捕获模式下输出的来源，写入日志头部
+	stdinReplay  string        // 作为命令标准输入重放的文件，为空时转发当前的标准输入
+	stdinSpool   string        // 启用重试时缓存的管道输入，每次尝试都从头重放
+	meta         *os.File      // raw 日志模式下写入头部和尾部元数据的文件，其余模式为 nil
+	filters      []executor.LogFilter
+	recFilters   []executor.LogFilter
+	project      *model.Project // 运行所属的项目，注册失败时为 nil
 	mu           sync.Mutex
 	lastFlush    time.Time
 }
@@ -29,6 +58,7 @@ type Logger struct {
 type RunRepository interface {
 	RegisterProject(path string) (*model.Project, error)
 	RecordRun(project *model.Project, result *executor.Result, logFilePath string) error
+	PreviewLength() int
 }
 
 // ProjectStatsUpdater 负责项目级别的统计更新
@@ -54,24 +84,285 @@ func (l *Logger) SetLogPath(path string) {
 	l.logPath = path
 }
 
+// SetOnStart 设置命令启动后的回调，参数为命令所在的进程组 ID
+func (l *Logger) SetOnStart(fn func(pgid int)) {
+	l.onStart = fn
+}
+
+// SetOnAttempt 设置每次尝试开始前的回调，参数为尝试序号（从 1 开始）和该次尝试的日志路径
+func (l *Logger) SetOnAttempt(fn func(attempt int, logPath string)) {
+	l.onAttempt = fn
+}
+
+// SetStdinReplay 设置重放给命令的标准输入文件（通常是 --record-stdin 保存的记录），每次尝试都从头读取
+func (l *Logger) SetStdinReplay(path string) {
+	l.stdinReplay = path
+}
+
+// StdinPath 返回日志文件对应的标准输入记录路径
+func StdinPath(logPath string) string {
+	return logPath + ".stdin"
+}
+
+// SetContext 设置执行上下文，后台任务使用提交任务时采集的上下文
+func (l *Logger) SetContext(info *execctx.Info) {
+	l.execContext = info
+}
+
+// attemptInfo 描述启用重试时的单次尝试
+type attemptInfo struct {
+	number int
+	max    int
+	group  string
+}
+
 // Run 执行命令并记录日志
+// 配置了重试时按策略重复执行，每次尝试单独写入日志，返回最后一次尝试的结果
 func (l *Logger) Run(ctx context.Context, command string, args ...string) (*executor.Result, string, error) {
 	// 设置命令信息（用于生成日志文件名）
 	l.config.Command = command
 	l.config.CommandArgs = args
 
-	var logPath string
-	var err error
+	// shell 模式下整条命令作为脚本交给 shell 执行，日志按脚本中第一个执行的程序命名
+	l.script = ""
+	l.source = ""
+	if shell := l.config.Run.Shell; shell != "" {
+		l.script = shellcmd.Script(command, args)
+		command, args = shell, shellcmd.Args(l.script)
+		l.config.Command = shellcmd.ProgramName(l.script)
+		l.config.CommandArgs = nil
+	}
+
+	logPath, err := l.prepare()
+	if err != nil {
+		return nil, "", err
+	}
+
+	policy, err := l.retryPolicy()
+	if err != nil {
+		return nil, "", err
+	}
+
+	execute := func(ctx context.Context, exec *executor.Executor) (*executor.Result, error) {
+		return exec.Execute(ctx, command, args...)
+	}
+	if policy.Retries == 0 {
+		result, path, err := l.runAttempt(ctx, command, args, logPath, nil, execute)
+		l.updateProjectStats(result)
+		return result, path, err
+	}
 
-	if l.logPath != "" {
-		logPath = l.logPath
-	} else {
+	rewind, cleanup, err := l.replayableStdin()
+	if err != nil {
+		return nil, "", err
+	}
+	defer cleanup()
+
+	attempt := &attemptInfo{max: policy.Attempts(), group: retry.NewGroupID()}
+	for {
+		attempt.number++
+		path := logPath
+		if attempt.number > 1 {
+			path = attemptLogPath(logPath, attempt.number)
+			rewind()
+		}
+		if l.onAttempt != nil {
+			l.onAttempt(attempt.number, path)
+		}
+
+		result, path, err := l.runAttempt(ctx, command, args, path, attempt, execute)
+		// 命令无法启动、已成功、被用户中断或不满足重试条件时结束
+		if result == nil || result.Success || result.Interrupted || ctx.Err() != nil ||
+			!policy.ShouldRetry(attempt.number, result.ShellExitCode()) {
+			l.updateProjectStats(result)
+			return result, path, err
+		}
+
+		delay := policy.Backoff.Delay(attempt.number)
+		// on-failure 模式下失败尝试的输出已回显，提示重试以便区分多次输出
+		if mode := l.config.Run.Output; mode == executor.OutputNormal || mode == executor.OutputOnFailure {
+			fmt.Fprintf(os.Stderr, "\n第 %d/%d 次尝试失败 (退出码 %d)，%v 后重试\n",
+				attempt.number, attempt.max, result.ShellExitCode(), delay)
+		}
+		if waitErr := waitBackoff(ctx, delay); waitErr != nil {
+			l.updateProjectStats(result)
+			return result, path, fmt.Errorf("停止重试: %w", waitErr)
+		}
+	}
+}
+
+// Capture 将通过 input 传入的输出（如管道传入的标准输入）以 name 为命令名记录日志
+// 与 Run 一样写入日志头尾、命令历史和项目统计，exitCode 为上游命令的退出码
+func (l *Logger) Capture(ctx context.Context, name string, input io.Reader, exitCode int) (*executor.Result, string, error) {
+	l.config.Command = name
+	l.config.CommandArgs = nil
+	l.script = ""
+	l.source = "标准输入"
+
+	logPath, err := l.prepare()
+	if err != nil {
+		return nil, "", err
+	}
+
+	result, path, err := l.runAttempt(ctx, name, nil, logPath, nil, func(ctx context.Context, exec *executor.Executor) (*executor.Result, error) {
+		return exec.Capture(ctx, name, input, exitCode)
+	})
+	l.updateProjectStats(result)
+	return result, path, err
+}
+
+// updateProjectStats 按一次运行更新项目统计，启用重试时只按最后一次尝试计入，每次尝试仍单独记录历史
+func (l *Logger) updateProjectStats(result *executor.Result) {
+	if result == nil || l.project == nil || l.statsUpdater == nil {
+		return
+	}
+	lastCommand := result.Command
+	if result.Script != "" {
+		lastCommand = result.Script
+	}
+	if err := l.statsUpdater.UpdateProjectStats(l.project.ID, lastCommand, result.Success, result.Duration); err != nil {
+		fmt.Fprintf(os.Stderr, "更新项目统计失败: %v\n", err)
+	}
+}
+
+// prepare 确定日志路径并加载日志过滤器和执行上下文
+func (l *Logger) prepare() (string, error) {
+	logPath := l.logPath
+	if logPath == "" {
 		// 生成日志文件路径
+		var err error
 		logPath, err = l.config.GetLogFilePath()
 		if err != nil {
-			return nil, "", fmt.Errorf("生成日志路径失败: %w", err)
+			return "", fmt.Errorf("生成日志路径失败: %w", err)
+		}
+	}
+
+	filters, recFilters, err := l.logFilters()
+	if err != nil {
+		return "", err
+	}
+	l.filters = filters
+	l.recFilters = recFilters
+
+	if l.execContext == nil {
+		l.execContext = execctx.Capture("")
+	}
+	return logPath, nil
+}
+
+// retryPolicy 根据运行选项构造重试策略
+func (l *Logger) retryPolicy() (retry.Policy, error) {
+	opts := l.config.Run
+	policy := retry.Policy{Retries: opts.Retries, RetryOn: opts.RetryOn}
+	if opts.Retries <= 0 {
+		policy.Retries = 0
+		return policy, nil
+	}
+	backoff, err := retry.ParseBackoff(opts.Backoff)
+	if err != nil {
+		return policy, fmt.Errorf("解析退避策略失败: %w", err)
+	}
+	policy.Backoff = backoff
+	return policy, nil
+}
+
+// logFilters 根据配置构造作用于日志输出和终端录制的过滤器
+// 转换在脱敏之前应用，去掉颜色序列和重绘后脱敏规则才能匹配完整的文本；
+// 录制需要保留原始的终端控制序列，只做脱敏
+func (l *Logger) logFilters() ([]executor.LogFilter, []executor.LogFilter, error) {
+	filters, err := transform.Chain(l.config.LogTransforms, transform.Options{DropPatterns: l.config.DropPatterns})
+	if err != nil {
+		return nil, nil, fmt.Errorf("加载日志转换失败: %w", err)
+	}
+	var recFilters []executor.LogFilter
+	if l.config.Redact {
+		redactor, err := redact.New(l.config.RedactPatterns)
+		if err != nil {
+			return nil, nil, fmt.Errorf("加载脱敏规则失败: %w", err)
+		}
+		redactFilter := func(dst executor.FlushWriter) executor.FlushWriter {
+			return redactor.NewWriter(dst)
+		}
+		filters = append(filters, redactFilter)
+		recFilters = append(recFilters, redactFilter)
+	}
+	return filters, recFilters, nil
+}
+
+// replayableStdin 让每次尝试都能读到完整的标准输入
+// 普通文件在每次重试前回到开始运行时的位置；管道和 socket 无法回退，先读完并缓存到临时文件，
+// 每次尝试从头重放。返回的 rewind 在重试前调用，cleanup 在全部尝试结束后调用
+func (l *Logger) replayableStdin() (rewind func(), cleanup func(), err error) {
+	noop := func() {}
+	if l.stdinReplay != "" {
+		return noop, noop, nil
+	}
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return noop, noop, nil
+	}
+
+	switch mode := info.Mode(); {
+	case mode.IsRegular():
+		offset, err := os.Stdin.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return noop, noop, nil
+		}
+		return func() { _, _ = os.Stdin.Seek(offset, io.SeekStart) }, noop, nil
+	case mode&(os.ModeNamedPipe|os.ModeSocket) != 0:
+		spool, err := os.CreateTemp("", "logcmd-stdin-*")
+		if err != nil {
+			return nil, nil, fmt.Errorf("创建标准输入缓存失败: %w", err)
+		}
+		_, err = io.Copy(spool, os.Stdin)
+		if closeErr := spool.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(spool.Name())
+			return nil, nil, fmt.Errorf("缓存标准输入失败: %w", err)
 		}
+		l.stdinSpool = spool.Name()
+		return noop, func() {
+			os.Remove(l.stdinSpool)
+			l.stdinSpool = ""
+		}, nil
+	default:
+		// 终端每次尝试都由用户重新输入
+		return noop, noop, nil
 	}
+}
+
+// attemptLogPath 返回第 n 次尝试的日志路径，如 build.log -> build.attempt2.log
+func attemptLogPath(logPath string, n int) string {
+	return fmt.Sprintf("%s.attempt%d.log", strings.TrimSuffix(logPath, ".log"), n)
+}
+
+// waitBackoff 等待重试间隔，期间收到中断信号或上下文取消时返回错误
+func waitBackoff(ctx context.Context, d time.Duration) error {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case sig := <-sigCh:
+		return fmt.Errorf("等待重试时收到信号 %v", sig)
+	}
+}
+
+// runFunc 使用准备好的执行器完成一次运行（执行命令或捕获输入）
+type runFunc func(ctx context.Context, exec *executor.Executor) (*executor.Result, error)
+
+// runAttempt 执行一次命令，写入日志并记录执行结果
+func (l *Logger) runAttempt(ctx context.Context, command string, args []string, logPath string, attempt *attemptInfo, run runFunc) (*executor.Result, string, error) {
+	var err error
 
 	// 自动注册项目（如果尚未注册）
 	var project *model.Project
@@ -81,6 +372,7 @@ func (l *Logger) Run(ctx context.Context, command string, args ...string) (*exec
 			fmt.Fprintf(os.Stderr, "注册项目失败: %v\n", err)
 		}
 	}
+	l.project = project
 
 	// 打开日志文件
 	file, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
@@ -91,6 +383,17 @@ func (l *Logger) Run(ctx context.Context, command string, args ...string) (*exec
 
 	l.file = file
 	l.writer = bufio.NewWriterSize(file, l.config.BufferSize)
+
+	// raw 日志模式下日志只包含命令输出，头部、尾部元数据和提示写入日志旁的 .meta 文件
+	l.meta = nil
+	if l.config.Run.RawLog {
+		meta, err := os.OpenFile(logmeta.PathFor(logPath), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, "", fmt.Errorf("打开元数据文件失败: %w", err)
+		}
+		defer meta.Close()
+		l.meta = meta
+	}
 	l.lastFlush = time.Now()
 
 	// 确保最后刷新
@@ -102,28 +405,162 @@ func (l *Logger) Run(ctx context.Context, command string, args ...string) (*exec
 		}
 	}()
 
-	// 显示日志文件路径
-	fmt.Printf("正在记录日志到: %s\n", logPath)
+	// 显示日志文件路径，quiet 等模式下只在结束时由调用方决定是否输出
+	if l.config.Run.Output == executor.OutputNormal {
+		fmt.Printf("正在记录日志到: %s\n", logPath)
+	}
 
 	// 写入日志头部
-	l.writeHeader(command, args)
+	l.writeHeader(command, args, attempt)
 
 	// 创建带锁的 writer
 	sw := &syncedWriter{l: l}
 
+	opts := executor.Options{
+		PTY:            l.config.Run.PTY,
+		GracePeriod:    l.config.Run.GracePeriod,
+		Timeout:        l.config.Run.Timeout,
+		IdleTimeout:    l.config.Run.IdleTimeout,
+		SampleInterval: l.config.Run.SampleInterval,
+		OnStart:        l.onStart,
+		LogFilters:     l.filters,
+		MaxLogSize:     l.config.Run.MaxLogSize,
+		LogTailSize:    l.config.Run.LogTailSize,
+		Output:         l.config.Run.Output,
+		Events:         l.config.Run.Events,
+		Sandbox:        l.config.Run.Sandbox,
+		Environment: &envsnap.Options{
+			Allow: l.config.EnvAllow,
+			Deny:  l.config.EnvDeny,
+		},
+	}
+	if l.meta != nil {
+		// raw 日志不做编码和日志转换，但仍然脱敏
+		opts.Metadata = l.meta
+		opts.RawLogFilters = l.recFilters
+	}
+	if l.repo != nil {
+		opts.PreviewLength = l.repo.PreviewLength()
+	}
+
+	// 非 UTF-8 输出先转换编码，后续的转换和脱敏规则才能匹配
+	var decoder *charset.Decoder
+	if l.config.Run.InputEncoding != "" {
+		decoder, err = charset.NewDecoder(l.config.Run.InputEncoding)
+		if err != nil {
+			return nil, "", fmt.Errorf("加载输入编码失败: %w", err)
+		}
+		opts.LogFilters = append([]executor.LogFilter{func(dst executor.FlushWriter) executor.FlushWriter {
+			return decoder.NewWriter(dst)
+		}}, l.filters...)
+	}
+
+	// 结构化逐行记录写入日志旁的 .jsonl 文件
+	if l.config.Run.StructuredLog {
+		recordFile, err := os.OpenFile(logrecord.PathFor(logPath), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "创建结构化记录文件失败: %v\n", err)
+		} else {
+			defer recordFile.Close()
+			opts.Records = recordFile
+		}
+	}
+
+	// 终端输出录制写入日志旁的 .cast 文件
+	var recording *recordingFile
+	if l.config.Run.Record {
+		recording, err = l.openRecording(logPath, command, args)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "创建录制文件失败: %v\n", err)
+		} else {
+			opts.Recording = recording.writer
+			opts.RecordingFilters = l.recFilters
+		}
+	}
+
+	// 转发标准输入，--record-stdin 时同时保存到日志旁的 .stdin 文件
+	// capture 的标准输入就是要记录的输出，不再转发给命令
+	var stdinRecord *countingWriter
+	if l.source == "" {
+		opts.Stdin = os.Stdin
+		replayPath := l.stdinReplay
+		if replayPath == "" {
+			replayPath = l.stdinSpool
+		}
+		if replayPath != "" {
+			replay, err := os.Open(replayPath)
+			if err != nil {
+				return nil, "", fmt.Errorf("打开标准输入记录失败: %w", err)
+			}
+			defer replay.Close()
+			opts.Stdin = replay
+		}
+		if l.config.Run.RecordStdin {
+			// 输入中可能包含密码等敏感内容，只允许当前用户读取
+			recordFile, err := os.OpenFile(StdinPath(logPath), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "创建标准输入记录失败: %v\n", err)
+			} else {
+				defer recordFile.Close()
+				stdinRecord = &countingWriter{w: recordFile}
+				opts.StdinRecord = stdinRecord
+			}
+		}
+	}
+
+	// 运行前对工作目录做快照，运行后比较得出命令创建、修改和删除的文件
+	var tracker *filetrack.Tracker
+	var before filetrack.Snapshot
+	if l.config.Run.TrackFiles != "" {
+		tracker, before, err = l.snapshotFiles()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "跟踪文件变化失败: %v\n", err)
+		}
+	}
+
 	// 创建执行器并执行命令
-	exec := executor.New(sw, os.Stdout, os.Stderr)
-	result, err := exec.Execute(ctx, command, args...)
+	exec := executor.NewWithOptions(sw, os.Stdout, os.Stderr, opts)
+	result, err := run(ctx, exec)
+	if tracker != nil && result != nil {
+		if after, snapErr := tracker.Snapshot(before); snapErr != nil {
+			fmt.Fprintf(os.Stderr, "跟踪文件变化失败: %v\n", snapErr)
+		} else {
+			result.TrackFiles = l.config.Run.TrackFiles
+			result.FileChanges = filetrack.Diff(before, after)
+		}
+	}
+	if stdinRecord != nil && result != nil {
+		result.StdinRecord = StdinPath(logPath)
+		result.StdinBytes = stdinRecord.bytes()
+	}
+	if len(l.config.Run.Artifacts) > 0 && result != nil {
+		result.Artifacts = l.collectArtifacts(logPath)
+	}
+	if recording != nil {
+		if closeErr := recording.close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "写入录制文件失败: %v\n", closeErr)
+		} else if result != nil {
+			result.Recording = recording.path
+		}
+	}
 
 	// 写入元数据
 	if result != nil {
+		if attempt != nil {
+			result.Attempt = attempt.number
+			result.MaxAttempts = attempt.max
+			result.AttemptGroup = attempt.group
+		}
+		result.Context = l.execContext
+		result.Script = l.script
+		if decoder != nil {
+			result.Encoding = decoder.Encoding()
+		}
 		exec.WriteMetadata(result)
-		
-		if project != nil && l.statsUpdater != nil {
-			if err := l.statsUpdater.UpdateProjectStats(project.ID, result.Command, result.Success, result.Duration); err != nil {
-				fmt.Fprintf(os.Stderr, "更新项目统计失败: %v\n", err)
-			}
+		if err := l.writeSidecar(logPath, result); err != nil {
+			fmt.Fprintf(os.Stderr, "写入元数据文件失败: %v\n", err)
 		}
+
 		if project != nil && l.repo != nil {
 			if err := l.repo.RecordRun(project, result, logPath); err != nil {
 				fmt.Fprintf(os.Stderr, "记录命令历史失败: %v\n", err)
@@ -143,23 +580,48 @@ func (l *Logger) Run(ctx context.Context, command string, args ...string) (*exec
 }
 
 // writeHeader 写入日志头部信息
-func (l *Logger) writeHeader(command string, args []string) {
+func (l *Logger) writeHeader(command string, args []string, attempt *attemptInfo) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	
+
+	commandLine := fmt.Sprintf("%s %v", command, args)
+	var extras string
+	if l.script != "" {
+		commandLine = shellcmd.DisplayLine(l.script)
+		extras = fmt.Sprintf("# Shell: %s\n", command)
+	}
+	if l.source != "" {
+		extras += fmt.Sprintf("# 来源: %s\n", l.source)
+	} else if l.stdinReplay != "" {
+		extras += fmt.Sprintf("# 标准输入: %s\n", l.stdinReplay)
+	}
+	if p := l.config.Run.Sandbox; p != nil && l.source == "" {
+		extras += fmt.Sprintf("# 沙箱: %s\n", p)
+	}
+	if attempt != nil {
+		extras += fmt.Sprintf("# 尝试: %d/%d (重试组 %s)\n", attempt.number, attempt.max, attempt.group)
+	}
+	for _, line := range l.execContext.HeaderLines() {
+		extras += line + "\n"
+	}
+
 	header := fmt.Sprintf(`
 ################################################################################
 # LogCmd - 命令执行日志
 # 时间: %s
-# 命令: %s %v
-################################################################################
+# 命令: %s
+%s################################################################################
 
 `,
 		time.Now().In(l.config.TimeZone).Format("2006-01-02 15:04:05"),
-		command,
-		args,
+		commandLine,
+		extras,
 	)
 
+	if l.meta != nil {
+		l.meta.WriteString(header)
+		return
+	}
 	l.writer.WriteString(header)
 	l.writer.Flush()
 	l.lastFlush = time.Now()
@@ -198,3 +660,108 @@ func (s *syncedWriter) Write(p []byte) (int, error) {
 	}
 	return n, err
 }
+
+// snapshotFiles 对当前工作目录中匹配 TrackFiles 的文件做快照，日志目录不参与跟踪
+func (l *Logger) snapshotFiles() (*filetrack.Tracker, filetrack.Snapshot, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取工作目录失败: %w", err)
+	}
+	tracker, err := filetrack.New(wd, filetrack.ParsePatterns(l.config.Run.TrackFiles), l.config.LogDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshot, err := tracker.Snapshot(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tracker, snapshot, nil
+}
+
+// writeSidecar 写入日志对应的 JSON 元数据，供统计和搜索直接读取，不再依赖解析日志尾部文本
+func (l *Logger) writeSidecar(logPath string, result *executor.Result) error {
+	wd, _ := os.Getwd()
+	return logmeta.Write(logPath, &logmeta.Metadata{
+		LogFile:          logPath,
+		RawLog:           l.config.Run.RawLog,
+		WorkingDirectory: wd,
+		CommandLine:      executor.CommandLine(result),
+		Result:           result,
+	})
+}
+
+// collectArtifacts 将 --artifact 匹配的文件保存到日志旁的产物目录，失败时只输出警告
+// 命令失败时同样收集，便于保留失败现场的报告
+func (l *Logger) collectArtifacts(logPath string) []artifact.Artifact {
+	wd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "收集产物失败: %v\n", err)
+		return nil
+	}
+	collected, unmatched, err := artifact.Collect(wd, l.config.Run.Artifacts, artifact.DirFor(logPath), l.config.LogDir)
+	for _, spec := range unmatched {
+		fmt.Fprintf(os.Stderr, "警告: --artifact %s 没有匹配的文件\n", spec)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "收集产物失败: %v\n", err)
+	}
+	return collected
+}
+
+// recordingFile 一次运行的终端输出录制
+type recordingFile struct {
+	path   string
+	file   *os.File
+	writer *asciicast.Writer
+}
+
+// openRecording 在日志旁创建 asciicast 录制文件，终端大小取自当前终端
+func (l *Logger) openRecording(logPath, command string, args []string) (*recordingFile, error) {
+	path := asciicast.PathFor(logPath)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	commandLine := l.script
+	if commandLine == "" {
+		commandLine = strings.Join(append([]string{command}, args...), " ")
+	}
+	width, height := asciicast.TerminalSize()
+	return &recordingFile{
+		path: path,
+		file: file,
+		writer: asciicast.NewWriter(file, asciicast.Header{
+			Width:   width,
+			Height:  height,
+			Command: commandLine,
+			Title:   filepath.Base(logPath),
+			Env:     map[string]string{"SHELL": os.Getenv("SHELL"), "TERM": os.Getenv("TERM")},
+		}),
+	}, nil
+}
+
+// close 刷新并关闭录制文件
+func (r *recordingFile) close() error {
+	err := r.writer.Flush()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// countingWriter 统计写入字节数，写入与读取可能发生在不同的 goroutine
+type countingWriter struct {
+	w io.Writer
+	n atomic.Int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+func (c *countingWriter) bytes() int64 {
+	return c.n.Load()
+}
@@ -0,0 +1,27 @@
+// Package logmeta 管理日志旁的元数据文件
+// raw 日志模式下日志只包含命令的原始输出，logcmd 的头部、尾部元数据以及运行期间的提示和事件
+// 以与普通日志相同的格式写入 <日志>.meta，读取元数据时优先使用该文件。
+// 此外每次运行都会写入 <日志>.meta.json，以带版本号的 JSON 保存完整的执行结果，
+// 统计和搜索优先读取该文件，旧日志没有时再解析头部与尾部文本
+package logmeta
+
+import "os"
+
+// PathFor 返回日志文件对应的元数据文件路径
+func PathFor(logPath string) string {
+	return logPath + ".meta"
+}
+
+// Exists 判断日志文件是否有对应的元数据文件（raw 日志模式）
+func Exists(logPath string) bool {
+	_, err := os.Stat(PathFor(logPath))
+	return err == nil
+}
+
+// Source 返回包含日志头部与尾部元数据的文件：raw 日志为元数据文件，其余为日志本身
+func Source(logPath string) string {
+	if Exists(logPath) {
+		return PathFor(logPath)
+	}
+	return logPath
+}
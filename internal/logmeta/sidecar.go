@@ -0,0 +1,81 @@
+package logmeta
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aliancn/logcmd/internal/executor"
+)
+
+// Version 当前写入的 JSON 元数据格式版本，字段含义发生不兼容变化时递增
+const Version = 1
+
+// Metadata 每次运行写入 <日志>.meta.json 的机器可读元数据
+type Metadata struct {
+	Version          int              `json:"version"`
+	LogFile          string           `json:"log_file"`                    // 对应的日志文件路径
+	RawLog           bool             `json:"raw_log,omitempty"`           // 日志是否为 raw 模式（头部与尾部位于 .meta）
+	WorkingDirectory string           `json:"working_directory,omitempty"` // 命令执行时的工作目录
+	CommandLine      string           `json:"command_line"`                // 写入日志的命令行，shell 模式下为脚本本身
+	Result           *executor.Result `json:"result"`                      // 完整的执行结果
+}
+
+// JSONPathFor 返回日志文件对应的 JSON 元数据文件路径
+func JSONPathFor(logPath string) string {
+	return logPath + ".meta.json"
+}
+
+// Write 写入日志对应的 JSON 元数据，先写临时文件再重命名，读取方不会看到写了一半的文件
+func Write(logPath string, m *Metadata) error {
+	m.Version = Version
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化元数据失败: %w", err)
+	}
+
+	path := JSONPathFor(logPath)
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建元数据文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入元数据文件失败: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("设置元数据文件权限失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入元数据文件失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("保存元数据文件失败: %w", err)
+	}
+	return nil
+}
+
+// Read 读取日志对应的 JSON 元数据；文件不存在时返回的错误满足 os.IsNotExist
+// 旧版本 logcmd 写入的日志没有该文件，调用方应回退到解析日志头部与尾部
+func Read(logPath string) (*Metadata, error) {
+	data, err := os.ReadFile(JSONPathFor(logPath))
+	if err != nil {
+		return nil, err
+	}
+
+	var m Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("解析元数据文件失败: %w", err)
+	}
+	if m.Version < 1 || m.Version > Version {
+		return nil, fmt.Errorf("不支持的元数据版本 %d（当前支持 %d）", m.Version, Version)
+	}
+	if m.Result == nil {
+		return nil, fmt.Errorf("元数据文件缺少执行结果")
+	}
+	return &m, nil
+}
@@ -0,0 +1,229 @@
+package logrecord
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+	StreamEvent  = "event"  // 命令通过 LOGCMD_EVENTS 发送的事件
+	StreamNotice = "logcmd" // logcmd 写入的提示（如超过大小上限）
+
+	// maxPendingLine 单行缓冲上限，超过后即使没有换行也会输出一条记录
+	maxPendingLine = 64 * 1024
+	// flushInterval 记录文件的刷新间隔，便于 tail 实时查看
+	flushInterval = 200 * time.Millisecond
+)
+
+// Record 结构化日志中的一行输出
+type Record struct {
+	Seq      int64   `json:"seq"`       // 全局序号（跨输出流递增）
+	Stream   string  `json:"stream"`    // 输出流：stdout/stderr
+	OffsetMs float64 `json:"offset_ms"` // 相对命令开始时间的偏移（毫秒，单调时钟）
+	Line     string  `json:"line"`      // 行内容（不含换行符）
+}
+
+// Offset 返回记录相对命令开始时间的偏移
+func (r *Record) Offset() time.Duration {
+	return time.Duration(r.OffsetMs * float64(time.Millisecond))
+}
+
+// PathFor 返回日志文件对应的结构化记录文件路径
+func PathFor(logPath string) string {
+	return logPath + ".jsonl"
+}
+
+// Writer 以 JSON Lines 格式写入结构化记录，可被多个输出流并发使用
+type Writer struct {
+	mu        sync.Mutex
+	buf       *bufio.Writer
+	start     time.Time
+	seq       int64
+	lastFlush time.Time
+	limit     int64 // 记录的输出字节数上限，0 表示不限制
+	written   int64 // 已记录的输出字节数
+	truncated bool  // 已超过上限，之后的输出不再记录
+}
+
+// NewWriter 创建记录写入器，start 为命令开始时间
+func NewWriter(w io.Writer, start time.Time) *Writer {
+	return &Writer{
+		buf:       bufio.NewWriter(w),
+		start:     start,
+		lastFlush: time.Now(),
+	}
+}
+
+// Stream 返回指定输出流的写入器，按换行切分后写入记录
+func (w *Writer) Stream(name string) *StreamWriter {
+	return &StreamWriter{parent: w, stream: name}
+}
+
+// Limit 限制记录的输出字节数，超过后写入一条提示并丢弃之后的输出，事件不受限制
+func (w *Writer) Limit(max int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.limit = max
+}
+
+// Flush 将缓冲的记录写入底层文件
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastFlush = time.Now()
+	return w.buf.Flush()
+}
+
+func (w *Writer) writeLine(stream string, line []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.limit > 0 && stream != StreamEvent {
+		if w.truncated {
+			return nil
+		}
+		if w.written+int64(len(line)) > w.limit {
+			w.truncated = true
+			stream = StreamNotice
+			line = []byte("[logcmd] 输出超过大小上限，之后的输出不再记录")
+		} else {
+			w.written += int64(len(line))
+		}
+	}
+
+	w.seq++
+	record := Record{
+		Seq:      w.seq,
+		Stream:   stream,
+		OffsetMs: float64(time.Since(w.start).Microseconds()) / 1000,
+		Line:     string(line),
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := w.buf.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	if time.Since(w.lastFlush) > flushInterval {
+		w.lastFlush = time.Now()
+		return w.buf.Flush()
+	}
+	return nil
+}
+
+// StreamWriter 单个输出流的行切分写入器
+type StreamWriter struct {
+	parent  *Writer
+	stream  string
+	pending []byte
+}
+
+// Write 缓冲不完整的行，遇到换行时写入记录
+func (s *StreamWriter) Write(p []byte) (int, error) {
+	data := p
+	for len(data) > 0 {
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			s.pending = append(s.pending, data...)
+			if len(s.pending) >= maxPendingLine {
+				if err := s.emit(); err != nil {
+					return 0, err
+				}
+			}
+			break
+		}
+
+		s.pending = append(s.pending, data[:idx]...)
+		if err := s.emit(); err != nil {
+			return 0, err
+		}
+		data = data[idx+1:]
+	}
+	return len(p), nil
+}
+
+// Flush 输出残留的不完整行
+func (s *StreamWriter) Flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	return s.emit()
+}
+
+func (s *StreamWriter) emit() error {
+	line := bytes.TrimSuffix(s.pending, []byte{'\r'})
+	err := s.parent.writeLine(s.stream, line)
+	s.pending = s.pending[:0]
+	return err
+}
+
+// Decode 解析单行 JSON 记录
+func Decode(line []byte) (*Record, error) {
+	var record Record
+	if err := json.Unmarshal(line, &record); err != nil {
+		return nil, fmt.Errorf("解析结构化记录失败: %w", err)
+	}
+	return &record, nil
+}
+
+// Exists 判断日志文件是否有对应的结构化记录
+func Exists(logPath string) bool {
+	info, err := os.Stat(PathFor(logPath))
+	return err == nil && !info.IsDir()
+}
+
+// Scanner 逐条读取结构化记录
+type Scanner struct {
+	scanner *bufio.Scanner
+	record  *Record
+	err     error
+}
+
+// NewScanner 创建记录读取器
+func NewScanner(r io.Reader) *Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 256*1024), 1024*1024)
+	return &Scanner{scanner: scanner}
+}
+
+// Scan 读取下一条记录，无更多记录或出错时返回 false
+func (s *Scanner) Scan() bool {
+	for s.scanner.Scan() {
+		line := bytes.TrimSpace(s.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		record, err := Decode(line)
+		if err != nil {
+			s.err = err
+			return false
+		}
+		s.record = record
+		return true
+	}
+	return false
+}
+
+// Record 返回当前记录
+func (s *Scanner) Record() *Record {
+	return s.record
+}
+
+// Err 返回读取过程中的错误
+func (s *Scanner) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.scanner.Err()
+}
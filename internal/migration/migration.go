@@ -15,9 +15,117 @@ func NewMigration(db *sql.DB) *Migration {
 	return &Migration{db: db}
 }
 
+// columnMigration 描述在初始表结构之后新增的列
+type columnMigration struct {
+	table      string
+	column     string
+	definition string
+}
+
+// columnMigrations 按顺序记录新增列，启动时为旧数据库补齐
+var columnMigrations = []columnMigration{
+	{table: "tasks", column: "run_options", definition: "TEXT DEFAULT ''"},
+	{table: "tasks", column: "process_group", definition: "INTEGER"},
+	{table: "command_history", column: "termination_signal", definition: "TEXT DEFAULT ''"},
+	{table: "project_stats_cache", column: "timeout_commands", definition: "INTEGER DEFAULT 0"},
+	{table: "command_history", column: "termination_reason", definition: "TEXT DEFAULT ''"},
+	{table: "tasks", column: "termination_reason", definition: "TEXT DEFAULT ''"},
+	{table: "command_history", column: "attempt_group", definition: "TEXT DEFAULT ''"},
+	{table: "command_history", column: "attempt", definition: "INTEGER DEFAULT 0"},
+	{table: "project_stats_cache", column: "retried_success_commands", definition: "INTEGER DEFAULT 0"},
+	{table: "command_history", column: "cpu_user_ms", definition: "INTEGER DEFAULT 0"},
+	{table: "command_history", column: "cpu_system_ms", definition: "INTEGER DEFAULT 0"},
+	{table: "command_history", column: "max_rss_bytes", definition: "INTEGER DEFAULT 0"},
+	{table: "command_history", column: "block_input", definition: "INTEGER DEFAULT 0"},
+	{table: "command_history", column: "block_output", definition: "INTEGER DEFAULT 0"},
+	{table: "command_history", column: "ctx_switches_voluntary", definition: "INTEGER DEFAULT 0"},
+	{table: "command_history", column: "ctx_switches_involuntary", definition: "INTEGER DEFAULT 0"},
+	{table: "project_stats_cache", column: "resource_distribution", definition: "TEXT DEFAULT ''"},
+	{table: "command_history", column: "os_user", definition: "TEXT DEFAULT ''"},
+	{table: "command_history", column: "os_uid", definition: "TEXT DEFAULT ''"},
+	{table: "command_history", column: "hostname", definition: "TEXT DEFAULT ''"},
+	{table: "command_history", column: "tty", definition: "TEXT DEFAULT ''"},
+	{table: "command_history", column: "parent_process", definition: "TEXT DEFAULT ''"},
+	{table: "command_history", column: "git_branch", definition: "TEXT DEFAULT ''"},
+	{table: "command_history", column: "git_commit", definition: "TEXT DEFAULT ''"},
+	{table: "command_history", column: "git_dirty", definition: "BOOLEAN DEFAULT 0"},
+	{table: "tasks", column: "execution_context", definition: "TEXT DEFAULT ''"},
+	{table: "command_history", column: "output_encoding", definition: "TEXT DEFAULT ''"},
+	{table: "command_history", column: "truncated_bytes", definition: "INTEGER DEFAULT 0"},
+	{table: "command_history", column: "shell", definition: "TEXT DEFAULT ''"},
+	{table: "command_history", column: "error_excerpt", definition: "TEXT DEFAULT ''"},
+	{table: "command_history", column: "recording_path", definition: "TEXT DEFAULT ''"},
+	{table: "command_history", column: "sandbox_profile", definition: "TEXT DEFAULT ''"},
+	{table: "command_history", column: "sandbox_denials", definition: "INTEGER DEFAULT 0"},
+	{table: "command_history", column: "group_peak_rss_bytes", definition: "INTEGER DEFAULT 0"},
+	{table: "project_stats_cache", column: "retried_attempts", definition: "INTEGER DEFAULT 0"},
+}
+
 // Migrate 执行数据库迁移
 func (m *Migration) Migrate() error {
-	return m.createNewTables()
+	if err := m.createNewTables(); err != nil {
+		return err
+	}
+	return m.addColumns()
+}
+
+// addColumns 为已存在的表补齐新增列
+func (m *Migration) addColumns() error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	existing := make(map[string]map[string]bool)
+	for _, col := range columnMigrations {
+		columns, ok := existing[col.table]
+		if !ok {
+			columns, err = tableColumns(tx, col.table)
+			if err != nil {
+				return fmt.Errorf("读取 %s 表结构失败: %w", col.table, err)
+			}
+			existing[col.table] = columns
+		}
+		if columns[col.column] {
+			continue
+		}
+
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", col.table, col.column, col.definition)
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("为 %s 表添加列 %s 失败: %w", col.table, col.column, err)
+		}
+		columns[col.column] = true
+	}
+
+	return tx.Commit()
+}
+
+// tableColumns 返回表中已有的列名集合
+func tableColumns(tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+
+	return columns, rows.Err()
 }
 
 // createNewTables 创建新版本的所有表
@@ -140,6 +248,95 @@ func (m *Migration) createNewTables() error {
 		}
 	}
 
+	// 创建资源采样表
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS command_samples (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			history_id INTEGER NOT NULL,
+			offset_ms INTEGER NOT NULL,
+			cpu_ms INTEGER DEFAULT 0,
+			rss_bytes INTEGER DEFAULT 0,
+			processes INTEGER DEFAULT 0,
+
+			FOREIGN KEY (history_id) REFERENCES command_history(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("创建 command_samples 表失败: %w", err)
+	}
+
+	if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_command_samples_history_id ON command_samples(history_id)"); err != nil {
+		return fmt.Errorf("创建资源采样索引失败: %w", err)
+	}
+
+	// 创建命令事件表
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS command_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			history_id INTEGER NOT NULL,
+			offset_ms INTEGER NOT NULL,
+			event_type TEXT NOT NULL,
+			text TEXT DEFAULT '',
+			metric_key TEXT DEFAULT '',
+			metric_value TEXT DEFAULT '',
+
+			FOREIGN KEY (history_id) REFERENCES command_history(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("创建 command_events 表失败: %w", err)
+	}
+
+	if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_command_events_history_id ON command_events(history_id)"); err != nil {
+		return fmt.Errorf("创建命令事件索引失败: %w", err)
+	}
+
+	// 创建 command_file_changes 表（run --track-files 记录的文件变化）
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS command_file_changes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			history_id INTEGER NOT NULL,
+			path TEXT NOT NULL,
+			change_type TEXT NOT NULL,
+			size_before INTEGER DEFAULT 0,
+			size_after INTEGER DEFAULT 0,
+			hash_before TEXT DEFAULT '',
+			hash_after TEXT DEFAULT '',
+
+			FOREIGN KEY (history_id) REFERENCES command_history(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("创建 command_file_changes 表失败: %w", err)
+	}
+
+	if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_command_file_changes_history_id ON command_file_changes(history_id)"); err != nil {
+		return fmt.Errorf("创建文件变化索引失败: %w", err)
+	}
+
+	// 创建 command_artifacts 表（run --artifact 收集的产物）
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS command_artifacts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			history_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			source_path TEXT DEFAULT '',
+			stored_path TEXT NOT NULL,
+			size_bytes INTEGER DEFAULT 0,
+			sha256 TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+			FOREIGN KEY (history_id) REFERENCES command_history(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("创建 command_artifacts 表失败: %w", err)
+	}
+
+	if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_command_artifacts_history_id ON command_artifacts(history_id)"); err != nil {
+		return fmt.Errorf("创建产物索引失败: %w", err)
+	}
+
 	// 创建 project_stats_cache 表
 	_, err = tx.Exec(`
 		CREATE TABLE IF NOT EXISTS project_stats_cache (
@@ -16,27 +16,64 @@ type CommandHistory struct {
 	CommandName string   `db:"command_name"`
 	CommandArgs []string `db:"-"`
 	ArgsJSON    string   `db:"command_args"`
+	Shell       string   `db:"shell"` // shell 模式下执行脚本的 shell，此时 Command 为完整脚本
 
 	// 执行信息
 	StartTime  time.Time `db:"start_time"`
 	EndTime    time.Time `db:"end_time"`
 	DurationMs int64     `db:"duration_ms"`
 	ExitCode   int       `db:"exit_code"`
-	Status     string    `db:"status"` // "success" or "failed"
+	Status     string    `db:"status"`             // "success", "failed" or "timeout"
+	Signal     string    `db:"termination_signal"` // 终止命令的信号名称，正常退出时为空
+	Reason     string    `db:"termination_reason"` // logcmd 主动终止命令的原因（timeout/idle-timeout）
+
+	// 重试信息
+	AttemptGroup string `db:"attempt_group"` // 关联同一命令多次尝试的重试组 ID，未启用重试时为空
+	Attempt      int    `db:"attempt"`       // 第几次尝试（从 1 开始），未启用重试时为 0
 
 	// 日志文件关联
 	LogFilePath string `db:"log_file_path"`
 	LogDate     string `db:"log_date"` // YYYY-MM-DD
+	Recording   string `db:"recording_path"` // asciicast 终端录制文件路径，未录制时为空
+
+	// 沙箱（run --sandbox）
+	SandboxProfile string `db:"sandbox_profile"` // JSON 编码的沙箱配置，未启用沙箱时为空
+	SandboxDenials int    `db:"sandbox_denials"` // 看起来被沙箱拒绝的输出行数
+
+	// 资源占用（rusage），平台不支持时为 0
+	CPUUserMs              int64 `db:"cpu_user_ms"`
+	CPUSystemMs            int64 `db:"cpu_system_ms"`
+	MaxRSSBytes            int64 `db:"max_rss_bytes"` // rusage 记录的单个进程最大常驻内存
+	BlockInput             int64 `db:"block_input"`
+	BlockOutput            int64 `db:"block_output"`
+	CtxSwitchesVoluntary   int64 `db:"ctx_switches_voluntary"`
+	CtxSwitchesInvoluntary int64 `db:"ctx_switches_involuntary"`
+	GroupPeakRSSBytes      int64 `db:"group_peak_rss_bytes"` // 运行期间采样到的进程组常驻内存之和的峰值，未采样时为 0
 
 	// 输出预览
 	StdoutPreview string `db:"stdout_preview"`
 	StderrPreview string `db:"stderr_preview"`
+	ErrorExcerpt  string `db:"error_excerpt"` // 第一条错误输出行，用于一眼看出失败原因
 	HasError      bool   `db:"has_error"`
 
+	// 日志写入
+	Encoding       string `db:"output_encoding"` // 输出的原始字符编码，未转换时为空
+	TruncatedBytes int64  `db:"truncated_bytes"` // 超过日志大小上限被丢弃的输出字节数
+
 	// 元数据
 	WorkingDirectory string `db:"working_directory"`
 	EnvironmentJSON  string `db:"environment_info"`
 
+	// 执行上下文
+	OSUser        string `db:"os_user"`
+	OSUID         string `db:"os_uid"`
+	Hostname      string `db:"hostname"`
+	TTY           string `db:"tty"`            // 非交互执行时为空
+	ParentProcess string `db:"parent_process"` // 启动 logcmd 的父进程名称
+	GitBranch     string `db:"git_branch"`
+	GitCommit     string `db:"git_commit"`
+	GitDirty      bool   `db:"git_dirty"`
+
 	// 时间戳
 	CreatedAt time.Time `db:"created_at"`
 }
@@ -98,3 +135,48 @@ func TruncateOutput(output string, maxLen int) string {
 	}
 	return output[:maxLen] + "..."
 }
+
+// ResourceSample 运行期间对命令进程组的一次资源采样
+type ResourceSample struct {
+	ID        int   `db:"id"`
+	HistoryID int   `db:"history_id"`
+	OffsetMs  int64 `db:"offset_ms"` // 相对命令开始的时间偏移
+	CPUMs     int64 `db:"cpu_ms"`    // 进程组累计 CPU 时间
+	RSSBytes  int64 `db:"rss_bytes"` // 进程组常驻内存之和
+	Processes int   `db:"processes"` // 进程组中的进程数
+}
+
+// CommandEvent 命令运行期间通过 LOGCMD_EVENTS 发送的一条事件
+type CommandEvent struct {
+	ID        int    `db:"id"`
+	HistoryID int    `db:"history_id"`
+	OffsetMs  int64  `db:"offset_ms"`  // 相对命令开始的时间偏移
+	Type      string `db:"event_type"` // mark、metric 或 warning
+	Text      string `db:"text"`
+	Key       string `db:"metric_key"` // metric 事件的指标名
+	Value     string `db:"metric_value"`
+}
+
+// FileChange 命令运行前后工作目录中一个文件的变化（run --track-files）
+type FileChange struct {
+	ID         int    `db:"id"`
+	HistoryID  int    `db:"history_id"`
+	Path       string `db:"path"`        // 相对工作目录的路径
+	Type       string `db:"change_type"` // created、modified 或 deleted
+	SizeBefore int64  `db:"size_before"`
+	SizeAfter  int64  `db:"size_after"`
+	HashBefore string `db:"hash_before"` // 内容的 SHA-256，文件过大或不存在时为空
+	HashAfter  string `db:"hash_after"`
+}
+
+// Artifact 运行结束后收集的产物文件（run --artifact）
+type Artifact struct {
+	ID         int       `db:"id"`
+	HistoryID  int       `db:"history_id"`
+	Name       string    `db:"name"`        // 产物目录中的相对路径
+	SourcePath string    `db:"source_path"` // 收集时的源文件路径
+	StoredPath string    `db:"stored_path"` // 保存后的文件路径
+	SizeBytes  int64     `db:"size_bytes"`
+	SHA256     string    `db:"sha256"`
+	CreatedAt  time.Time `db:"created_at"`
+}
@@ -15,22 +15,48 @@ type ProjectStatsCache struct {
 	TotalCommands   int   `db:"total_commands"`
 	SuccessCommands int   `db:"success_commands"`
 	FailedCommands  int   `db:"failed_commands"`
+	TimeoutCommands int   `db:"timeout_commands"`
+	RetriedSuccess  int   `db:"retried_success_commands"` // 重试后才成功的命令数
+	RetriedAttempts int   `db:"retried_attempts"`         // 之后又被重试的尝试次数，不计入命令数
 	TotalDurationMs int64 `db:"total_duration_ms"`
 	AvgDurationMs   int64 `db:"avg_duration_ms"`
 	MaxDurationMs   int64 `db:"max_duration_ms"`
 	MinDurationMs   int64 `db:"min_duration_ms"`
 
 	// 分布统计（JSON 存储）
-	CommandDistribution  map[string]int `db:"-"`
-	CommandDistJSON      string         `db:"command_distribution"`
-	ExitCodeDistribution map[int]int    `db:"-"`
-	ExitCodeDistJSON     string         `db:"exit_code_distribution"`
+	CommandDistribution  map[string]int              `db:"-"`
+	CommandDistJSON      string                      `db:"command_distribution"`
+	ExitCodeDistribution map[int]int                 `db:"-"`
+	ExitCodeDistJSON     string                      `db:"exit_code_distribution"`
+	ResourceDistribution map[string]*CommandResource `db:"-"`
+	ResourceDistJSON     string                      `db:"resource_distribution"`
 
 	// 时间戳
 	CreatedAt time.Time `db:"created_at"`
 	UpdatedAt time.Time `db:"updated_at"`
 }
 
+// CommandResource 单个命令的资源占用汇总
+type CommandResource struct {
+	CPUMs             int64 `json:"cpu_ms"`                         // 累计 CPU 时间（用户态 + 内核态）
+	PeakRSSBytes      int64 `json:"peak_rss_bytes"`                 // rusage 记录的单个进程最大内存
+	GroupPeakRSSBytes int64 `json:"group_peak_rss_bytes,omitempty"` // 运行期间采样到的进程组内存峰值
+}
+
+// Merge 合并另一份资源占用：CPU 时间累加，峰值内存取最大值
+func (r *CommandResource) Merge(other *CommandResource) {
+	if other == nil {
+		return
+	}
+	r.CPUMs += other.CPUMs
+	if other.PeakRSSBytes > r.PeakRSSBytes {
+		r.PeakRSSBytes = other.PeakRSSBytes
+	}
+	if other.GroupPeakRSSBytes > r.GroupPeakRSSBytes {
+		r.GroupPeakRSSBytes = other.GroupPeakRSSBytes
+	}
+}
+
 // BeforeSave 在保存前序列化 JSON 字段
 func (s *ProjectStatsCache) BeforeSave() error {
 	if s.CommandDistribution != nil {
@@ -49,6 +75,14 @@ func (s *ProjectStatsCache) BeforeSave() error {
 		s.ExitCodeDistJSON = string(exitJSON)
 	}
 
+	if s.ResourceDistribution != nil {
+		resourceJSON, err := json.Marshal(s.ResourceDistribution)
+		if err != nil {
+			return err
+		}
+		s.ResourceDistJSON = string(resourceJSON)
+	}
+
 	return nil
 }
 
@@ -66,6 +100,12 @@ func (s *ProjectStatsCache) AfterLoad() error {
 		}
 	}
 
+	if s.ResourceDistJSON != "" {
+		if err := json.Unmarshal([]byte(s.ResourceDistJSON), &s.ResourceDistribution); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -12,6 +12,7 @@ const (
 	TaskStatusSuccess = "success"
 	TaskStatusFailed  = "failed"
 	TaskStatusStopped = "stopped"
+	TaskStatusTimeout = "timeout"
 )
 
 // Task 描述一个后台运行的命令
@@ -22,11 +23,15 @@ type Task struct {
 	ArgsJSON     string
 	WorkingDir   string
 	LogDir       string
+	OptionsJSON  string // 运行选项（JSON）
+	ContextJSON  string // 提交任务时的执行上下文（JSON），包括用户、终端和 git 状态
 	Status       string
 	PID          *int64
+	ProcessGroup *int64 // 命令所在的进程组 ID，用于整体转发信号
 	LogFilePath  string
 	ExitCode     *int64
 	ErrorMessage string
+	Reason       string // logcmd 主动终止命令的原因（timeout/idle-timeout）
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	StartedAt    *time.Time
@@ -3,16 +3,25 @@ package persistence
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/aliancn/logcmd/internal/artifact"
+	"github.com/aliancn/logcmd/internal/envsnap"
+	"github.com/aliancn/logcmd/internal/events"
 	"github.com/aliancn/logcmd/internal/executor"
+	"github.com/aliancn/logcmd/internal/filetrack"
 	"github.com/aliancn/logcmd/internal/history"
 	"github.com/aliancn/logcmd/internal/model"
 	"github.com/aliancn/logcmd/internal/registry"
+	"github.com/aliancn/logcmd/internal/shellcmd"
 	"github.com/aliancn/logcmd/internal/stats"
 )
 
+// defaultPreviewLength 未配置 max_preview_length 时输出预览保留的字节数
+const defaultPreviewLength = 500
+
 // RunRepository 提供命令执行结果的持久化实现，依赖共享的 Registry/DB。
 type RunRepository struct {
 	registry *registry.Registry
@@ -62,18 +71,90 @@ func (r *RunRepository) RecordRun(project *model.Project, result *executor.Resul
 		EndTime:          result.EndTime,
 		DurationMs:       result.Duration.Milliseconds(),
 		ExitCode:         result.ExitCode,
-		Status:           map[bool]string{true: "success", false: "failed"}[result.Success],
+		Status:           runStatus(result),
+		Signal:           result.Signal,
+		Reason:           result.Reason,
+		AttemptGroup:     result.AttemptGroup,
+		Attempt:          result.Attempt,
 		LogFilePath:      logFilePath,
 		LogDate:          logDate,
+		Recording:        result.Recording,
 		HasError:         !result.Success,
+		StdoutPreview:    result.StdoutPreview,
+		StderrPreview:    result.StderrPreview,
+		ErrorExcerpt:     result.ErrorExcerpt,
+		Encoding:         result.Encoding,
+		TruncatedBytes:   result.TruncatedBytes,
 		WorkingDirectory: getWorkingDirectory(),
 		CreatedAt:        time.Now(),
 	}
 
+	if result.Script != "" {
+		// shell 模式记录完整脚本，按脚本中第一个执行的程序统计
+		record.Command = result.Script
+		record.CommandName = shellcmd.ProgramName(result.Script)
+		record.Shell = result.Command
+	}
+	if result.Sandbox != nil {
+		record.SandboxProfile = result.Sandbox.Encode()
+		record.SandboxDenials = result.SandboxDenialCount
+	}
+	if usage := result.Usage; usage != nil {
+		record.CPUUserMs = usage.UserCPU.Milliseconds()
+		record.CPUSystemMs = usage.SystemCPU.Milliseconds()
+		record.MaxRSSBytes = usage.MaxRSS
+		record.BlockInput = usage.InBlock
+		record.BlockOutput = usage.OutBlock
+		record.CtxSwitchesVoluntary = usage.VoluntaryCtxSwitches
+		record.CtxSwitchesInvoluntary = usage.InvoluntaryCtxSwitches
+	}
+	if info := result.Context; info != nil {
+		record.OSUser = info.User
+		record.OSUID = info.UID
+		record.Hostname = info.Hostname
+		record.TTY = info.TTY
+		record.ParentProcess = info.ParentProcess
+		record.GitBranch = info.GitBranch
+		record.GitCommit = info.GitCommit
+		record.GitDirty = info.GitDirty
+	}
+	// 采样到的进程组峰值内存包含所有子进程，与 rusage 的单进程峰值含义不同，分开保存
+	record.GroupPeakRSSBytes = executor.PeakRSS(result.Samples)
+
+	environment, err := envsnap.Encode(result.Environment)
+	if err != nil {
+		return err
+	}
+	record.EnvironmentJSON = environment
+
 	if err := r.history.Record(record); err != nil {
 		return err
 	}
 
+	if len(result.Samples) > 0 {
+		if err := r.history.RecordSamples(record.ID, toResourceSamples(result.Samples)); err != nil {
+			return err
+		}
+	}
+
+	if len(result.Events) > 0 {
+		if err := r.history.RecordEvents(record.ID, toCommandEvents(result.Events)); err != nil {
+			return err
+		}
+	}
+
+	if len(result.FileChanges) > 0 {
+		if err := r.history.RecordFileChanges(record.ID, toFileChanges(result.FileChanges)); err != nil {
+			return err
+		}
+	}
+
+	if len(result.Artifacts) > 0 {
+		if err := r.history.RecordArtifacts(record.ID, toArtifacts(result.Artifacts)); err != nil {
+			return err
+		}
+	}
+
 	if err := r.cache.GenerateForDate(project.ID, logDate); err != nil {
 		return err
 	}
@@ -81,6 +162,97 @@ func (r *RunRepository) RecordRun(project *model.Project, result *executor.Resul
 	return nil
 }
 
+// PreviewLength 返回命令历史中输出预览保留的开头和末尾字节数，
+// 由 system_config 的 enable_stdout_preview 与 max_preview_length 控制。
+func (r *RunRepository) PreviewLength() int {
+	if r == nil || r.registry == nil {
+		return 0
+	}
+	if enabled, err := r.registry.GetSystemConfig("enable_stdout_preview"); err == nil && enabled == "false" {
+		return 0
+	}
+	value, err := r.registry.GetSystemConfig("max_preview_length")
+	if err != nil || value == "" {
+		return defaultPreviewLength
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return defaultPreviewLength
+	}
+	return n
+}
+
+// runStatus 返回写入命令历史的状态，兼容未设置 Status 的结果
+func runStatus(result *executor.Result) string {
+	if result.Status != "" {
+		return result.Status
+	}
+	if result.Success {
+		return executor.StatusSuccess
+	}
+	return executor.StatusFailed
+}
+
+// toResourceSamples 将执行器的采样结果转换为持久化模型
+func toResourceSamples(samples []executor.Sample) []model.ResourceSample {
+	out := make([]model.ResourceSample, 0, len(samples))
+	for _, sample := range samples {
+		out = append(out, model.ResourceSample{
+			OffsetMs:  sample.Offset.Milliseconds(),
+			CPUMs:     sample.CPU.Milliseconds(),
+			RSSBytes:  sample.RSS,
+			Processes: sample.Processes,
+		})
+	}
+	return out
+}
+
+// toCommandEvents 将执行器收到的事件转换为持久化模型
+func toCommandEvents(list []events.Event) []model.CommandEvent {
+	out := make([]model.CommandEvent, 0, len(list))
+	for _, ev := range list {
+		out = append(out, model.CommandEvent{
+			OffsetMs: ev.Offset.Milliseconds(),
+			Type:     ev.Type,
+			Text:     ev.Text,
+			Key:      ev.Key,
+			Value:    ev.Value,
+		})
+	}
+	return out
+}
+
+// toFileChanges 将运行前后的文件变化转换为持久化模型
+func toFileChanges(list []filetrack.Change) []model.FileChange {
+	out := make([]model.FileChange, 0, len(list))
+	for _, c := range list {
+		out = append(out, model.FileChange{
+			Path:       c.Path,
+			Type:       c.Type,
+			SizeBefore: c.SizeBefore,
+			SizeAfter:  c.SizeAfter,
+			HashBefore: c.HashBefore,
+			HashAfter:  c.HashAfter,
+		})
+	}
+	return out
+}
+
+// toArtifacts 将收集到的产物转换为持久化模型
+func toArtifacts(list []artifact.Artifact) []model.Artifact {
+	out := make([]model.Artifact, 0, len(list))
+	for _, a := range list {
+		out = append(out, model.Artifact{
+			Name:       a.Name,
+			SourcePath: a.Source,
+			StoredPath: a.Path,
+			SizeBytes:  a.Size,
+			SHA256:     a.SHA256,
+		})
+	}
+	return out
+}
+
 func buildCommandString(command string, args []string) string {
 	parts := []string{}
 	if command != "" {
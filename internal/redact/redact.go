@@ -0,0 +1,193 @@
+package redact
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// Placeholder 替换敏感信息的占位符
+const Placeholder = "[REDACTED]"
+
+// PrivateKeyPlaceholder 替换整个私钥块的占位符
+const PrivateKeyPlaceholder = "[REDACTED PRIVATE KEY]"
+
+// maxPending 未遇到换行时最多缓存的字节数，超过后按规则安全切分写出
+const maxPending = 64 * 1024
+
+// maxKeyBlock 私钥块最多跳过的字节数，超过后认为私钥块没有结束，恢复写出后续输出
+// 真实私钥远小于该上限，避免 BEGIN 行之后的全部输出都被丢弃
+const maxKeyBlock = 64 * 1024
+
+// unterminatedKeyMarker 私钥块超过上限仍未结束时写入的提示
+const unterminatedKeyMarker = "\n[logcmd] 私钥块未结束\n"
+
+// rule 一条脱敏规则，replacement 中可以用 ${1} 等保留匹配的前缀
+type rule struct {
+	name        string
+	re          *regexp.Regexp
+	replacement string
+}
+
+// builtinRules 内置检测规则
+// 匹配长度都有上限，这样长时间没有换行的输出总能找到不截断匹配的切分位置
+var builtinRules = []rule{
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), Placeholder},
+	{"aws-secret-key", regexp.MustCompile(`(?i)(aws_secret_access_key["']?\s*[=:]\s*["']?)[A-Za-z0-9/+=]{40}`), "${1}" + Placeholder},
+	{"bearer-token", regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9\-._~+/]{1,1000}=*`), "${1}" + Placeholder},
+	{"password-pair", regexp.MustCompile(`(?i)(\b(?:password|passwd|pwd)["']?\s*[=:]\s*)("[^"]{0,256}"|'[^']{0,256}'|[^\s"'&;,]{1,256})`), "${1}" + Placeholder},
+	{"url-credentials", regexp.MustCompile(`(://[^/\s:@]{1,256}:)[^@\s/]{1,256}(@)`), "${1}" + Placeholder + "${2}"},
+	{"private-key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`), PrivateKeyPlaceholder},
+}
+
+var (
+	privateKeyBegin = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)
+	privateKeyEnd   = regexp.MustCompile(`-----END [A-Z ]*PRIVATE KEY-----`)
+)
+
+// Redactor 根据内置规则和用户规则替换敏感信息
+type Redactor struct {
+	rules []rule
+}
+
+// New 创建脱敏器，patterns 为用户自定义的正则表达式，匹配内容整体替换
+func New(patterns []string) (*Redactor, error) {
+	r := &Redactor{rules: append([]rule(nil), builtinRules...)}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("脱敏规则 %q 无效: %w", pattern, err)
+		}
+		r.rules = append(r.rules, rule{name: "custom", re: re, replacement: Placeholder})
+	}
+	return r, nil
+}
+
+// Redact 替换文本中的敏感信息
+func (r *Redactor) Redact(p []byte) []byte {
+	for _, rl := range r.rules {
+		p = rl.re.ReplaceAll(p, []byte(rl.replacement))
+	}
+	return p
+}
+
+// Writer 对写入的输出脱敏后转发给下游
+// 输出按行处理，跨越多次 Write 的敏感信息（包括多行私钥块）也能被识别；
+// \r 同样视为行结束，进度条等原地刷新的输出可以及时写出
+type Writer struct {
+	r       *Redactor
+	w       io.Writer
+	buf     []byte
+	inKey   bool // 正在跳过私钥块
+	skipped int  // 当前私钥块已跳过的字节数
+}
+
+// NewWriter 创建脱敏写入器，输出结束后需调用 Flush 写出残留内容
+func (r *Redactor) NewWriter(w io.Writer) *Writer {
+	return &Writer{r: r, w: w}
+}
+
+// Write 缓存不完整的行，只写出完整的行
+func (w *Writer) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	end := bytes.LastIndexAny(w.buf, "\r\n") + 1
+	if end == 0 && len(w.buf) > maxPending {
+		// 长时间没有换行（如进度条），在不截断任何匹配的位置切分
+		end = w.safeCut()
+	}
+	if end == 0 {
+		return len(p), nil
+	}
+
+	if err := w.emit(w.buf[:end]); err != nil {
+		return 0, err
+	}
+	w.buf = append(w.buf[:0], w.buf[end:]...)
+	return len(p), nil
+}
+
+// Flush 写出残留内容并刷新下游
+func (w *Writer) Flush() error {
+	if len(w.buf) > 0 {
+		if err := w.emit(w.buf); err != nil {
+			return err
+		}
+		w.buf = w.buf[:0]
+	}
+	if f, ok := w.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Sync 输出中途同步下游；Write 已经写出全部完整的行，未结束的行继续缓存以便完整匹配
+func (w *Writer) Sync() error {
+	if s, ok := w.w.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// safeCut 返回缓存中可以安全写出的长度，保留末尾一段以便与后续输出一起匹配
+func (w *Writer) safeCut() int {
+	cut := len(w.buf) - maxPending/2
+	for _, rl := range w.r.rules {
+		for _, loc := range rl.re.FindAllIndex(w.buf, -1) {
+			if loc[0] < cut && loc[1] >= cut {
+				cut = loc[0]
+			}
+		}
+	}
+	if loc := privateKeyBegin.FindIndex(w.buf); loc != nil && loc[0] < cut {
+		cut = loc[0]
+	}
+	if cut <= 0 && len(w.buf) > 4*maxPending {
+		// 自定义规则的匹配可能没有上限，避免无限缓存
+		cut = len(w.buf) - maxPending/2
+	}
+	if cut < 0 {
+		return 0
+	}
+	return cut
+}
+
+// emit 脱敏并写出一段内容，处理跨越多段输出的私钥块
+func (w *Writer) emit(p []byte) error {
+	var marker []byte
+	if w.inKey {
+		if loc := privateKeyEnd.FindIndex(p); loc != nil {
+			w.inKey = false
+			p = p[loc[1]:]
+		} else if w.skipped+len(p) <= maxKeyBlock {
+			// 整段都在私钥块中
+			w.skipped += len(p)
+			return nil
+		} else {
+			// 超过上限仍没有 END 行，从上限之后的下一行恢复写出
+			w.inKey = false
+			resume := max(maxKeyBlock-w.skipped, 0)
+			if i := bytes.IndexByte(p[resume:], '\n'); i >= 0 {
+				resume += i + 1
+			}
+			p = p[resume:]
+			marker = []byte(unterminatedKeyMarker)
+		}
+	}
+
+	out := w.r.Redact(p)
+	// 完整的私钥块已被替换，剩余的 BEGIN 说明私钥块延续到后续输出
+	if loc := privateKeyBegin.FindIndex(out); loc != nil {
+		w.skipped = len(out) - loc[1]
+		out = append(out[:loc[0]:loc[0]], PrivateKeyPlaceholder...)
+		w.inKey = true
+	}
+	out = append(marker, out...)
+
+	if len(out) == 0 {
+		return nil
+	}
+	_, err := w.w.Write(out)
+	return err
+}
@@ -435,6 +435,19 @@ func (r *Registry) UpdateLastChecked(idOrPath string) error {
 	return nil
 }
 
+// GetSystemConfig 读取 system_config 中的配置项，不存在时返回空字符串
+func (r *Registry) GetSystemConfig(key string) (string, error) {
+	var value string
+	err := r.db.QueryRow(`SELECT value FROM system_config WHERE key = ?`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("读取系统配置失败: %w", err)
+	}
+	return value, nil
+}
+
 // extractProjectName 从路径中提取项目名称
 func extractProjectName(path string) string {
 	sep := string(os.PathSeparator)
@@ -0,0 +1,140 @@
+package retry
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBackoff 未指定退避策略时使用的默认值
+const DefaultBackoff = "exp:1s..1m"
+
+// Backoff 描述两次尝试之间的等待时长
+type Backoff struct {
+	Exponential bool          // 是否按指数增长
+	Min         time.Duration // 第一次重试前的等待时长
+	Max         time.Duration // 等待时长上限，0 表示不限制
+}
+
+// ParseBackoff 解析退避策略，支持以下格式：
+//
+//	5s            固定等待 5s
+//	fixed:5s      固定等待 5s
+//	exp:2s..1m    从 2s 开始每次翻倍，最多等待 1m
+func ParseBackoff(spec string) (Backoff, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		spec = DefaultBackoff
+	}
+
+	kind, value, found := strings.Cut(spec, ":")
+	if !found {
+		kind, value = "fixed", spec
+	}
+
+	switch kind {
+	case "fixed":
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return Backoff{}, fmt.Errorf("无效的退避时长: %s", value)
+		}
+		return Backoff{Min: d, Max: d}, nil
+	case "exp":
+		minPart, maxPart, hasMax := strings.Cut(value, "..")
+		min, err := time.ParseDuration(minPart)
+		if err != nil || min <= 0 {
+			return Backoff{}, fmt.Errorf("无效的退避起始时长: %s", minPart)
+		}
+		b := Backoff{Exponential: true, Min: min}
+		if hasMax {
+			max, err := time.ParseDuration(maxPart)
+			if err != nil || max < min {
+				return Backoff{}, fmt.Errorf("无效的退避上限: %s", maxPart)
+			}
+			b.Max = max
+		}
+		return b, nil
+	default:
+		return Backoff{}, fmt.Errorf("未知的退避策略: %s (支持 fixed:5s 或 exp:2s..1m)", kind)
+	}
+}
+
+// Delay 返回第 n 次重试（从 1 开始）前的等待时长
+func (b Backoff) Delay(n int) time.Duration {
+	if n < 1 {
+		n = 1
+	}
+	d := b.Min
+	if b.Exponential {
+		for i := 1; i < n; i++ {
+			d *= 2
+			if b.Max > 0 && d >= b.Max {
+				return b.Max
+			}
+		}
+	}
+	if b.Max > 0 && d > b.Max {
+		return b.Max
+	}
+	return d
+}
+
+// ParseExitCodes 解析逗号分隔的退出码列表，如 "1,137"
+func ParseExitCodes(spec string) ([]int, error) {
+	var codes []int
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		code, err := strconv.Atoi(part)
+		if err != nil || code < 0 || code > 255 {
+			return nil, fmt.Errorf("无效的退出码: %s", part)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// Policy 描述失败后的重试策略
+type Policy struct {
+	Retries int     // 失败后最多重试的次数
+	RetryOn []int   // 仅在这些退出码时重试，为空表示任何失败都重试
+	Backoff Backoff // 两次尝试之间的等待时长
+}
+
+// Attempts 返回最多执行的次数（含第一次）
+func (p Policy) Attempts() int {
+	if p.Retries < 0 {
+		return 1
+	}
+	return p.Retries + 1
+}
+
+// ShouldRetry 判断第 attempt 次尝试以 exitCode 失败后是否继续重试
+func (p Policy) ShouldRetry(attempt int, exitCode int) bool {
+	if attempt >= p.Attempts() {
+		return false
+	}
+	if len(p.RetryOn) == 0 {
+		return true
+	}
+	for _, code := range p.RetryOn {
+		if code == exitCode {
+			return true
+		}
+	}
+	return false
+}
+
+// NewGroupID 生成关联同一命令多次尝试的重试组 ID
+func NewGroupID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf)
+}
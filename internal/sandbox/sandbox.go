@@ -0,0 +1,123 @@
+// Package sandbox 在受限环境中运行不受信任的命令（如第三方安装脚本）：
+// 主机文件系统只读，只有工作目录和配置允许的路径可写，可选禁止网络访问
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile run --sandbox 未指定配置名称时使用的配置
+const DefaultProfile = "default"
+
+// EnvVar 传递给沙箱辅助进程的环境变量，值为 JSON 编码的 Profile，执行目标命令前移除
+const EnvVar = "LOGCMD_SANDBOX"
+
+// Profile 沙箱配置，可在 config.json 的 sandbox_profiles 中按名称定义
+type Profile struct {
+	Name      string   `json:"name,omitempty"`
+	Writable  []string `json:"writable,omitempty"`   // 工作目录之外允许写入的路径，支持 ~ 和环境变量
+	NoNetwork bool     `json:"no_network,omitempty"` // 在独立的网络命名空间中运行，只有未启用的回环接口
+}
+
+// builtinProfiles 内置配置，config.json 中的同名配置会覆盖
+var builtinProfiles = map[string]Profile{
+	DefaultProfile: {},
+	"offline":      {NoNetwork: true},
+}
+
+// denialRegex 沙箱拒绝操作时命令常见的报错（英文与中文 locale）
+var denialRegex = regexp.MustCompile(`(?i:permission denied|read-only file system|operation not permitted|network is unreachable|could not resolve host|temporary failure in name resolution|\b(?:EACCES|EROFS|EPERM|ENETUNREACH)\b)|权限不够|只读文件系统|不允许的操作|网络不可达`)
+
+// Resolve 按名称查找配置，custom 为配置文件中定义的配置
+// 返回的配置中可写路径已展开为绝对路径（相对路径相对于当前目录）
+func Resolve(name string, custom map[string]Profile) (Profile, error) {
+	if name == "" {
+		name = DefaultProfile
+	}
+	p, ok := custom[name]
+	if !ok {
+		if p, ok = builtinProfiles[name]; !ok {
+			return Profile{}, fmt.Errorf("未知的沙箱配置 %q，可用: %s", name, strings.Join(Names(custom), ", "))
+		}
+	}
+	p.Name = name
+
+	writable := make([]string, 0, len(p.Writable))
+	for _, path := range p.Writable {
+		abs, err := expandPath(path)
+		if err != nil {
+			return Profile{}, fmt.Errorf("沙箱配置 %s 的路径 %q 无效: %w", name, path, err)
+		}
+		writable = append(writable, abs)
+	}
+	p.Writable = writable
+	return p, nil
+}
+
+// Names 返回内置配置与自定义配置的名称，按字母排序
+func Names(custom map[string]Profile) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for name := range builtinProfiles {
+		seen[name] = true
+		names = append(names, name)
+	}
+	for name := range custom {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func expandPath(path string) (string, error) {
+	path = os.ExpandEnv(path)
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	if path == "" {
+		return "", fmt.Errorf("路径为空")
+	}
+	return filepath.Abs(path)
+}
+
+// String 返回写入日志头部和尾部的单行描述，如 "offline (可写: 工作目录, /tmp; 网络: 禁止)"
+func (p Profile) String() string {
+	writable := append([]string{"工作目录"}, p.Writable...)
+	network := "允许"
+	if p.NoNetwork {
+		network = "禁止"
+	}
+	return fmt.Sprintf("%s (可写: %s; 网络: %s)", p.Name, strings.Join(writable, ", "), network)
+}
+
+// Encode 将配置序列化为 JSON，用于传递给辅助进程和写入运行记录
+func (p Profile) Encode() string {
+	data, _ := json.Marshal(p)
+	return string(data)
+}
+
+// Decode 从 JSON 恢复配置
+func Decode(data string) (Profile, error) {
+	var p Profile
+	if err := json.Unmarshal([]byte(data), &p); err != nil {
+		return p, fmt.Errorf("解析沙箱配置失败: %w", err)
+	}
+	return p, nil
+}
+
+// IsDenial 判断一行输出是否像是被沙箱拒绝的操作
+func IsDenial(line []byte) bool {
+	return denialRegex.Match(line)
+}
@@ -0,0 +1,205 @@
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// helperArg 标识沙箱辅助进程的参数，辅助进程由 logcmd 自身重新执行而来，
+// 在应用 Landlock 限制后 exec 目标命令，因此命令的 pid、进程组和 rusage 与直接执行时一致
+const helperArg = "__sandbox-exec"
+
+const (
+	// accessRead 主机文件系统上允许的访问
+	accessRead = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR
+	// accessFile 可以授予普通文件（而非目录）的访问
+	accessFile = accessRead&^unix.LANDLOCK_ACCESS_FS_READ_DIR | unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	// accessDevice /dev 下允许的访问：可以读写 /dev/null 等已有设备，不能创建文件，也不能对打开的设备执行 ioctl
+	accessDevice = accessRead | unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_TRUNCATE
+)
+
+// Supported 检查当前内核是否支持沙箱（Landlock）
+func Supported() error {
+	if _, err := landlockABI(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Wrap 改写 cmd，使其经由沙箱辅助进程在 p 的限制下执行
+// 禁止网络时命令在新的用户与网络命名空间中运行，用户映射保持不变
+func Wrap(cmd *exec.Cmd, p Profile) error {
+	if cmd.Err != nil {
+		// 找不到命令等错误留给 Start 报告
+		return nil
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("获取 logcmd 路径失败: %w", err)
+	}
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(env, EnvVar+"="+p.Encode())
+	cmd.Args = append([]string{self, helperArg, cmd.Path}, cmd.Args...)
+	cmd.Path = self
+
+	if p.NoNetwork {
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		attr := cmd.SysProcAttr
+		attr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+		attr.GidMappingsEnableSetgroups = false
+	}
+	return nil
+}
+
+// Init 在沙箱辅助进程中应用限制并执行目标命令，不会返回；其他情况下立即返回
+// 需要在 main 的最开始调用
+func Init() {
+	if len(os.Args) < 4 || os.Args[1] != helperArg {
+		return
+	}
+	// Landlock 与 no_new_privs 只作用于调用线程，限制和 exec 必须在同一线程上完成
+	runtime.LockOSThread()
+
+	path, argv := os.Args[2], os.Args[3:]
+	var (
+		encoded string
+		env     []string
+	)
+	for _, kv := range os.Environ() {
+		if v, ok := strings.CutPrefix(kv, EnvVar+"="); ok {
+			encoded = v
+			continue
+		}
+		env = append(env, kv)
+	}
+
+	p, err := Decode(encoded)
+	if err == nil {
+		err = restrict(p)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[logcmd] 沙箱初始化失败: %v\n", err)
+		os.Exit(126)
+	}
+	err = syscall.Exec(path, argv, env)
+	fmt.Fprintf(os.Stderr, "[logcmd] 执行 %s 失败: %v\n", path, err)
+	os.Exit(127)
+}
+
+// restrict 对当前线程应用 Landlock 规则：整个文件系统只读，工作目录和 p.Writable 可写
+func restrict(p Profile) error {
+	abi, err := landlockABI()
+	if err != nil {
+		return err
+	}
+	handled := handledAccess(abi)
+
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("创建 Landlock 规则集失败: %w", errno)
+	}
+	ruleset := int(fd)
+	defer unix.Close(ruleset)
+
+	if err := allow(ruleset, "/", accessRead); err != nil {
+		return err
+	}
+	if err := allow(ruleset, "/dev", accessDevice&handled); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("获取工作目录失败: %w", err)
+	}
+	// 可写路径下的设备同样不授予 ioctl
+	writable := handled &^ unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	if err := allow(ruleset, cwd, writable); err != nil {
+		return err
+	}
+	for _, path := range p.Writable {
+		if err := allow(ruleset, path, writable); err != nil {
+			if os.IsNotExist(err) {
+				fmt.Fprintf(os.Stderr, "[logcmd] 沙箱可写路径不存在，已忽略: %s\n", path)
+				continue
+			}
+			return err
+		}
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("设置 no_new_privs 失败: %w", err)
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, uintptr(ruleset), 0, 0); errno != 0 {
+		return fmt.Errorf("应用 Landlock 规则失败: %w", errno)
+	}
+	return nil
+}
+
+// allow 为 path 及其下的全部文件添加访问规则，path 为文件时只授予文件可用的访问
+func allow(ruleset int, path string, access uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	defer unix.Close(fd)
+
+	var st unix.Stat_t
+	if err := unix.Fstat(fd, &st); err != nil {
+		return &os.PathError{Op: "stat", Path: path, Err: err}
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= accessFile
+	}
+
+	rule := unix.LandlockPathBeneathAttr{Allowed_access: access, Parent_fd: int32(fd)}
+	if _, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(ruleset), unix.LANDLOCK_RULE_PATH_BENEATH,
+		uintptr(unsafe.Pointer(&rule)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("添加沙箱规则 %s 失败: %w", path, errno)
+	}
+	return nil
+}
+
+// landlockABI 返回内核支持的 Landlock ABI 版本
+func landlockABI() (int, error) {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return 0, fmt.Errorf("当前内核不支持 Landlock（需要 Linux 5.13+ 并启用 Landlock LSM）: %w", errno)
+	}
+	return int(abi), nil
+}
+
+// handledAccess 返回 ABI 版本支持的全部文件系统访问类型，未在规则中授予的访问都会被拒绝
+// ABI 5 起处理 IOCTL_DEV：命令不能对沙箱内打开的设备执行 ioctl，继承的标准输入输出不受影响
+func handledAccess(abi int) uint64 {
+	access := uint64(unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_DIR | unix.LANDLOCK_ACCESS_FS_REMOVE_DIR | unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR | unix.LANDLOCK_ACCESS_FS_MAKE_DIR | unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK | unix.LANDLOCK_ACCESS_FS_MAKE_FIFO | unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM)
+	if abi >= 2 {
+		access |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		access |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	if abi >= 5 {
+		access |= unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	}
+	return access
+}
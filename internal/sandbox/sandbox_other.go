@@ -0,0 +1,23 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"errors"
+	"os/exec"
+)
+
+var errUnsupported = errors.New("沙箱模式仅支持 Linux")
+
+// Supported 检查当前平台是否支持沙箱
+func Supported() error {
+	return errUnsupported
+}
+
+// Wrap 当前平台不支持沙箱
+func Wrap(cmd *exec.Cmd, p Profile) error {
+	return errUnsupported
+}
+
+// Init 当前平台没有沙箱辅助进程
+func Init() {}
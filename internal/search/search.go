@@ -10,27 +10,36 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aliancn/logcmd/internal/artifact"
+	"github.com/aliancn/logcmd/internal/execctx"
+	"github.com/aliancn/logcmd/internal/logmeta"
+	"github.com/aliancn/logcmd/internal/logrecord"
 	"github.com/aliancn/logcmd/internal/walker"
 )
 
 // SearchOptions 搜索选项
 type SearchOptions struct {
-	LogDir        string    // 日志目录
-	Keyword       string    // 搜索关键词
-	UseRegex      bool      // 使用正则表达式
-	StartDate     time.Time // 开始日期
-	EndDate       time.Time // 结束日期
-	CaseSensitive bool      // 区分大小写
-	ShowContext   int       // 显示上下文行数
+	LogDir        string         // 日志目录
+	Keyword       string         // 搜索关键词
+	UseRegex      bool           // 使用正则表达式
+	StartDate     time.Time      // 开始日期
+	EndDate       time.Time      // 结束日期
+	CaseSensitive bool           // 区分大小写
+	ShowContext   int            // 显示上下文行数
+	Stream        string         // 仅搜索指定输出流（stdout/stderr），需要结构化记录
+	Timestamps    bool           // 优先使用结构化记录，结果附带输出流与时间偏移
+	Context       execctx.Filter // 只搜索执行上下文满足条件的日志（用户、主机、分支）
 	CompiledRegex *regexp.Regexp
 }
 
 // SearchResult 搜索结果
 type SearchResult struct {
-	FilePath string   // 文件路径
-	LineNum  int      // 行号
-	Line     string   // 匹配的行
-	Context  []string // 上下文行
+	FilePath string        // 文件路径
+	LineNum  int           // 行号（来自结构化记录时为记录序号）
+	Line     string        // 匹配的行
+	Context  []string      // 上下文行
+	Stream   string        // 输出流（仅结构化记录）
+	Offset   time.Duration // 相对命令开始的时间偏移（仅结构化记录）
 }
 
 // Searcher 日志搜索器
@@ -50,6 +59,14 @@ type pendingContext struct {
 	remaining int
 }
 
+// lineEntry 待匹配的一行内容
+type lineEntry struct {
+	num    int
+	text   string
+	stream string
+	offset time.Duration
+}
+
 // New 创建搜索器
 func New(options *SearchOptions) (*Searcher, error) {
 	s := &Searcher{
@@ -90,7 +107,8 @@ func (s *Searcher) Search(ctx context.Context, handler ResultHandler) error {
 	}
 
 	fileWalker, err := walker.New(walker.Options{
-		Root: s.options.LogDir,
+		Root:     s.options.LogDir,
+		SkipDirs: []string{artifact.DirName},
 		FileFilter: func(path string, info os.FileInfo) bool {
 			if !strings.HasSuffix(path, ".log") {
 				return false
@@ -122,27 +140,119 @@ func (s *Searcher) Search(ctx context.Context, handler ResultHandler) error {
 
 // searchFile 在单个文件中搜索
 func (s *Searcher) searchFile(ctx context.Context, filePath string, handler ResultHandler) error {
+	if !s.options.Context.IsZero() {
+		matched, err := s.matchContext(filePath)
+		if err != nil || !matched {
+			return err
+		}
+	}
+
+	if s.options.Stream != "" || s.options.Timestamps {
+		if logrecord.Exists(filePath) {
+			return s.searchRecords(ctx, filePath, handler)
+		}
+		// 没有结构化记录的日志无法区分输出流
+		if s.options.Stream != "" {
+			return nil
+		}
+	}
+
 	file, err := os.Open(filePath)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	prevLines := make([]string, 0, s.options.ShowContext)
-	var pendings []*pendingContext
-
 	scanner := bufio.NewScanner(file)
 	buf := make([]byte, 0, 256*1024)
 	scanner.Buffer(buf, 1024*1024)
 
 	lineNum := 0
-	for scanner.Scan() {
+	next := func() (lineEntry, bool) {
+		if !scanner.Scan() {
+			return lineEntry{}, false
+		}
+		lineNum++
+		return lineEntry{num: lineNum, text: scanner.Text()}, true
+	}
+
+	if err := s.matchLines(ctx, filePath, next, handler); err != nil {
+		return err
+	}
+	return scanner.Err()
+}
+
+// matchContext 判断日志的执行上下文是否满足筛选条件
+// 优先读取 JSON 元数据文件，旧日志回退到解析日志头部（raw 日志为 .meta 文件）
+func (s *Searcher) matchContext(filePath string) (bool, error) {
+	sidecar, err := logmeta.Read(filePath)
+	if err == nil {
+		return s.options.Context.Match(sidecar.Result.Context), nil
+	}
+	if !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "警告: %s 的元数据文件无法使用，改为解析日志: %v\n", filePath, err)
+	}
+
+	file, err := os.Open(logmeta.Source(filePath))
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	info, err := execctx.ReadHeader(file)
+	if err != nil {
+		return false, err
+	}
+	return s.options.Context.Match(info), nil
+}
+
+// searchRecords 在日志对应的结构化记录中搜索
+func (s *Searcher) searchRecords(ctx context.Context, filePath string, handler ResultHandler) error {
+	file, err := os.Open(logrecord.PathFor(filePath))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := logrecord.NewScanner(file)
+	next := func() (lineEntry, bool) {
+		for scanner.Scan() {
+			record := scanner.Record()
+			if s.options.Stream != "" && record.Stream != s.options.Stream {
+				continue
+			}
+			return lineEntry{
+				num:    int(record.Seq),
+				text:   record.Line,
+				stream: record.Stream,
+				offset: record.Offset(),
+			}, true
+		}
+		return lineEntry{}, false
+	}
+
+	if err := s.matchLines(ctx, filePath, next, handler); err != nil {
+		return err
+	}
+	return scanner.Err()
+}
+
+// matchLines 逐行匹配并维护上下文
+func (s *Searcher) matchLines(ctx context.Context, filePath string, next func() (lineEntry, bool), handler ResultHandler) error {
+	prevLines := make([]string, 0, s.options.ShowContext)
+	var pendings []*pendingContext
+	var err error
+
+	for {
+		entry, ok := next()
+		if !ok {
+			break
+		}
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
 
-		lineNum++
-		line := scanner.Text()
+		line := entry.text
 
 		pendings, err = s.feedPendingContexts(pendings, line, handler)
 		if err != nil {
@@ -152,8 +262,10 @@ func (s *Searcher) searchFile(ctx context.Context, filePath string, handler Resu
 		if s.matches(line) {
 			result := &SearchResult{
 				FilePath: filePath,
-				LineNum:  lineNum,
+				LineNum:  entry.num,
 				Line:     line,
+				Stream:   entry.stream,
+				Offset:   entry.offset,
 			}
 
 			if s.options.ShowContext > 0 {
@@ -162,12 +274,10 @@ func (s *Searcher) searchFile(ctx context.Context, filePath string, handler Resu
 				contextLines = append(contextLines, line)
 				result.Context = contextLines
 
-				if s.options.ShowContext > 0 {
-					pendings = append(pendings, &pendingContext{
-						result:    result,
-						remaining: s.options.ShowContext,
-					})
-				}
+				pendings = append(pendings, &pendingContext{
+					result:    result,
+					remaining: s.options.ShowContext,
+				})
 			} else {
 				if err := handler(result); err != nil {
 					return err
@@ -183,15 +293,7 @@ func (s *Searcher) searchFile(ctx context.Context, filePath string, handler Resu
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return err
-	}
-
-	if err := flushPendingContexts(pendings, handler); err != nil {
-		return err
-	}
-
-	return nil
+	return flushPendingContexts(pendings, handler)
 }
 
 func (s *Searcher) feedPendingContexts(pendings []*pendingContext, line string, handler ResultHandler) ([]*pendingContext, error) {
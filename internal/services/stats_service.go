@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/aliancn/logcmd/internal/execctx"
+	"github.com/aliancn/logcmd/internal/history"
 	"github.com/aliancn/logcmd/internal/model"
 	"github.com/aliancn/logcmd/internal/registry"
 	"github.com/aliancn/logcmd/internal/stats"
@@ -16,20 +19,31 @@ import (
 type StatsService struct {
 	registry *registry.Registry
 	cache    *stats.CacheManager
+	history  *history.Manager
+	filter   execctx.Filter
 }
 
 // NewStatsService 创建统计服务。
 func NewStatsService(reg *registry.Registry) *StatsService {
 	var cache *stats.CacheManager
+	var historyManager *history.Manager
 	if reg != nil {
 		cache = stats.NewCacheManager(reg.GetDB())
+		historyManager = history.NewManager(reg.GetDB())
 	}
 	return &StatsService{
 		registry: reg,
 		cache:    cache,
+		history:  historyManager,
 	}
 }
 
+// SetFilter 只统计执行上下文满足条件的命令
+// 统计缓存不区分执行上下文，设置筛选条件后直接查询命令历史
+func (s *StatsService) SetFilter(filter execctx.Filter) {
+	s.filter = filter
+}
+
 // StatsForProject 返回单个项目的统计数据。
 func (s *StatsService) StatsForProject(ctx context.Context, project *model.Project) (*stats.Stats, error) {
 	if err := ctx.Err(); err != nil {
@@ -39,7 +53,13 @@ func (s *StatsService) StatsForProject(ctx context.Context, project *model.Proje
 		return nil, fmt.Errorf("project 不能为空")
 	}
 
-	report, err := s.statsFromCache(ctx, project)
+	var report *stats.Stats
+	var err error
+	if s.filter.IsZero() {
+		report, err = s.statsFromCache(ctx, project)
+	} else {
+		report, err = s.statsFromHistory(ctx, project)
+	}
 	if err == nil && report != nil {
 		return report, nil
 	}
@@ -147,8 +167,34 @@ func (s *StatsService) statsFromCache(ctx context.Context, project *model.Projec
 	return report, nil
 }
 
+func (s *StatsService) statsFromHistory(ctx context.Context, project *model.Project) (*stats.Stats, error) {
+	if s.history == nil {
+		return nil, fmt.Errorf("命令历史不可用")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	records, err := s.history.Query(history.QueryOptions{
+		ProjectID: project.ID,
+		OSUser:    s.filter.User,
+		Hostname:  s.filter.Host,
+		GitBranch: s.filter.Branch,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询命令历史失败: %w", err)
+	}
+
+	report := stats.FromHistory(records, s.displayName(project))
+	report.RangeLabel = s.filter.String()
+	return report, nil
+}
+
 func (s *StatsService) statsFromLogs(ctx context.Context, path, displayName string) (*stats.Stats, error) {
 	analyzer := stats.New(path)
+	analyzer.SetFilter(s.filter)
+	analyzer.SetHistory(s.HistoryLookup())
 	report, err := analyzer.Analyze(ctx)
 	if err != nil {
 		return nil, err
@@ -156,9 +202,27 @@ func (s *StatsService) statsFromLogs(ctx context.Context, path, displayName stri
 	if displayName != "" {
 		report.ProjectName = displayName
 	}
+	report.RangeLabel = s.filter.String()
 	return report, nil
 }
 
+// HistoryLookup 返回按日志路径查询命令历史的函数，供扫描日志时读取准确的资源占用；未连接数据库时返回 nil
+func (s *StatsService) HistoryLookup() stats.HistoryLookup {
+	if s.history == nil {
+		return nil
+	}
+	return func(logPath string) *model.CommandHistory {
+		if abs, err := filepath.Abs(logPath); err == nil {
+			logPath = abs
+		}
+		record, err := s.history.GetByLogPath(logPath)
+		if err != nil {
+			return nil
+		}
+		return record
+	}
+}
+
 func (s *StatsService) displayName(project *model.Project) string {
 	if project == nil {
 		return ""
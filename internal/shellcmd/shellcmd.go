@@ -0,0 +1,199 @@
+package shellcmd
+
+import (
+	"os"
+	"strings"
+)
+
+// DefaultShell 未配置 shell 且没有 $SHELL 时使用的 shell
+const DefaultShell = "/bin/sh"
+
+// Resolve 返回 shell 模式使用的 shell：配置优先，其次是 $SHELL，最后是 /bin/sh
+func Resolve(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	return DefaultShell
+}
+
+// Script 将命令行参数拼接为交给 shell 执行的脚本，与 sh -c "$*" 一致
+func Script(command string, args []string) string {
+	return strings.Join(append([]string{command}, args...), " ")
+}
+
+// DisplayLine 返回写入日志头部和尾部的单行脚本，换行显示为 \n
+func DisplayLine(script string) string {
+	return strings.ReplaceAll(script, "\n", `\n`)
+}
+
+// Args 返回以 shell 执行脚本的参数
+func Args(script string) []string {
+	return []string{"-c", script}
+}
+
+// wrappers 包装其他命令执行的前缀，命令名称取其后的程序
+var wrappers = map[string]bool{
+	"exec": true, "time": true, "command": true, "builtin": true, "nohup": true,
+	"sudo": true, "env": true, "nice": true, "stdbuf": true, "timeout": true,
+}
+
+// setupCommands 只改变 shell 状态的内置命令，统计时跳过以便取到真正执行的程序
+var setupCommands = map[string]bool{
+	"cd": true, "pushd": true, "popd": true, "export": true, "set": true, "unset": true,
+	"source": true, ".": true, "umask": true, "ulimit": true, "alias": true, "shopt": true,
+	"true": true, ":": true,
+}
+
+// keywords 出现在命令位置的 shell 关键字
+var keywords = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "fi": true, "while": true, "until": true,
+	"do": true, "done": true, "for": true, "case": true, "esac": true, "!": true, "{": true, "}": true,
+}
+
+// ProgramName 返回脚本中第一个实际执行的程序名称，用于命令统计
+// 跳过环境变量赋值、重定向、shell 关键字、cd/export 等内置命令以及 sudo/env/time 等包装命令
+// 无法识别时返回第一个单词
+func ProgramName(script string) string {
+	var first string
+commands:
+	for _, words := range simpleCommands(script) {
+		for i := 0; i < len(words); i++ {
+			word := words[i]
+			if first == "" {
+				first = word
+			}
+			switch {
+			case keywords[word] || isAssignment(word):
+			case isRedirect(word):
+				// 重定向目标与操作符分开书写（如 > out.txt）时一并跳过
+				if strings.TrimLeft(word, "0123456789&<>|") == "" {
+					i++
+				}
+			case wrappers[word]:
+				// 跳过包装命令的选项（如 sudo -u root、timeout 10s、nice -n 5）
+				for i+1 < len(words) && (strings.HasPrefix(words[i+1], "-") || isAssignment(words[i+1]) || isDuration(words[i+1])) {
+					i++
+				}
+			case setupCommands[word]:
+				continue commands
+			default:
+				return word
+			}
+		}
+	}
+	return first
+}
+
+// simpleCommands 按 ;、&&、||、|、& 和换行拆分脚本，返回每条简单命令的单词
+// 引号内的内容不拆分，$(...) 和反引号中的命令不单独识别
+func simpleCommands(script string) [][]string {
+	var (
+		commands [][]string
+		words    []string
+		word     strings.Builder
+		inWord   bool
+		quote    rune
+	)
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, words)
+			words = nil
+		}
+	}
+
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if quote != 0 {
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+			continue
+		}
+		switch r {
+		case '\'', '"':
+			quote = r
+			inWord = true
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				word.WriteRune(runes[i])
+				inWord = true
+			}
+		case ' ', '\t':
+			endWord()
+		case '\n', ';', '|', '&', '(', ')':
+			// 重定向中的 & 属于同一个单词（如 2>&1、&>file）
+			if r == '&' && inWord && strings.HasSuffix(word.String(), ">") {
+				word.WriteRune(r)
+				continue
+			}
+			if r == '&' && i+1 < len(runes) && runes[i+1] == '>' {
+				endWord()
+				word.WriteRune(r)
+				inWord = true
+				continue
+			}
+			endCommand()
+		case '#':
+			if !inWord {
+				// 注释到行尾
+				for i+1 < len(runes) && runes[i+1] != '\n' {
+					i++
+				}
+				continue
+			}
+			word.WriteRune(r)
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	endCommand()
+	return commands
+}
+
+func isAssignment(word string) bool {
+	name, _, ok := strings.Cut(word, "=")
+	if !ok || name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func isRedirect(word string) bool {
+	trimmed := strings.TrimLeft(word, "0123456789&")
+	return strings.HasPrefix(trimmed, ">") || strings.HasPrefix(trimmed, "<")
+}
+
+// isDuration 识别 timeout 等包装命令的时长参数（如 10、10s、1.5m）
+func isDuration(word string) bool {
+	word = strings.TrimRight(word, "smhd")
+	if word == "" {
+		return false
+	}
+	for _, r := range word {
+		if (r < '0' || r > '9') && r != '.' {
+			return false
+		}
+	}
+	return true
+}
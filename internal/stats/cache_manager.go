@@ -19,6 +19,54 @@ func NewCacheManager(db *sql.DB) *CacheManager {
 	return &CacheManager{db: db}
 }
 
+// cacheColumns 查询统计缓存时读取的列，顺序与 scanCache 一致
+const cacheColumns = `id, project_id, stat_date,
+	total_commands, success_commands, failed_commands, IFNULL(timeout_commands, 0),
+	IFNULL(retried_success_commands, 0), IFNULL(retried_attempts, 0),
+	total_duration_ms, avg_duration_ms, max_duration_ms, min_duration_ms,
+	command_distribution, exit_code_distribution, IFNULL(resource_distribution, ''),
+	created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanCache 按 cacheColumns 的顺序读取一行统计缓存
+func scanCache(scanner rowScanner) (*model.ProjectStatsCache, error) {
+	var cache model.ProjectStatsCache
+	if err := scanner.Scan(
+		&cache.ID,
+		&cache.ProjectID,
+		&cache.StatDate,
+		&cache.TotalCommands,
+		&cache.SuccessCommands,
+		&cache.FailedCommands,
+		&cache.TimeoutCommands,
+		&cache.RetriedSuccess,
+		&cache.RetriedAttempts,
+		&cache.TotalDurationMs,
+		&cache.AvgDurationMs,
+		&cache.MaxDurationMs,
+		&cache.MinDurationMs,
+		&cache.CommandDistJSON,
+		&cache.ExitCodeDistJSON,
+		&cache.ResourceDistJSON,
+		&cache.CreatedAt,
+		&cache.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+// supersededAttempt 判断命令历史是否为之后又被重试的尝试（同一重试组中存在更晚的尝试）
+// 命令数、时长、命令与退出码分布只统计每个重试组的最后一次尝试，资源占用包含全部尝试
+const supersededAttempt = `(IFNULL(attempt_group, '') != '' AND EXISTS (
+	SELECT 1 FROM command_history later
+	WHERE later.project_id = command_history.project_id
+		AND later.attempt_group = command_history.attempt_group
+		AND later.attempt > command_history.attempt))`
+
 // GenerateForDate 为指定日期生成统计缓存
 func (m *CacheManager) GenerateForDate(projectID int, date string) error {
 	// 从命令历史中统计数据
@@ -27,28 +75,34 @@ func (m *CacheManager) GenerateForDate(projectID int, date string) error {
 			COUNT(*) as total,
 			SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END) as success,
 			SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END) as failed,
+			SUM(CASE WHEN status = 'timeout' THEN 1 ELSE 0 END) as timeout,
+			SUM(CASE WHEN status = 'success' AND attempt > 1 THEN 1 ELSE 0 END) as retried_success,
 			SUM(duration_ms) as total_duration,
 			AVG(duration_ms) as avg_duration,
 			MAX(duration_ms) as max_duration,
 			MIN(duration_ms) as min_duration
 		FROM command_history
-		WHERE project_id = ? AND log_date = ?
-	`
+		WHERE project_id = ? AND log_date = ? AND NOT ` + supersededAttempt
 
-	var total, success, failed int
+	var total, success, failed, timeout, retriedSuccess, retriedAttempts int
 	var totalDuration, maxDuration, minDuration sql.NullInt64
 	var avgDuration sql.NullFloat64
 
 	err := m.db.QueryRow(query, projectID, date).Scan(
-		&total, &success, &failed,
+		&total, &success, &failed, &timeout, &retriedSuccess,
 		&totalDuration, &avgDuration, &maxDuration, &minDuration,
 	)
 	if err != nil {
 		return fmt.Errorf("查询统计数据失败: %w", err)
 	}
 
+	retriedQuery := "SELECT COUNT(*) FROM command_history WHERE project_id = ? AND log_date = ? AND " + supersededAttempt
+	if err := m.db.QueryRow(retriedQuery, projectID, date).Scan(&retriedAttempts); err != nil {
+		return fmt.Errorf("查询重试次数失败: %w", err)
+	}
+
 	// 如果没有数据，不生成缓存
-	if total == 0 {
+	if total == 0 && retriedAttempts == 0 {
 		return nil
 	}
 
@@ -64,6 +118,12 @@ func (m *CacheManager) GenerateForDate(projectID int, date string) error {
 		return fmt.Errorf("获取退出码分布失败: %w", err)
 	}
 
+	// 获取各命令的资源占用
+	resourceDist, err := m.getResourceDistribution(projectID, date)
+	if err != nil {
+		return fmt.Errorf("获取资源占用分布失败: %w", err)
+	}
+
 	// 创建统计缓存对象
 	cache := &model.ProjectStatsCache{
 		ProjectID:            projectID,
@@ -71,12 +131,16 @@ func (m *CacheManager) GenerateForDate(projectID int, date string) error {
 		TotalCommands:        total,
 		SuccessCommands:      success,
 		FailedCommands:       failed,
+		TimeoutCommands:      timeout,
+		RetriedSuccess:       retriedSuccess,
+		RetriedAttempts:      retriedAttempts,
 		TotalDurationMs:      totalDuration.Int64,
 		AvgDurationMs:        int64(avgDuration.Float64),
 		MaxDurationMs:        maxDuration.Int64,
 		MinDurationMs:        minDuration.Int64,
 		CommandDistribution:  cmdDist,
 		ExitCodeDistribution: exitDist,
+		ResourceDistribution: resourceDist,
 		CreatedAt:            time.Now(),
 		UpdatedAt:            time.Now(),
 	}
@@ -90,7 +154,7 @@ func (m *CacheManager) getCommandDistribution(projectID int, date string) (map[s
 	query := `
 		SELECT command_name, COUNT(*) as count
 		FROM command_history
-		WHERE project_id = ? AND log_date = ?
+		WHERE project_id = ? AND log_date = ? AND NOT ` + supersededAttempt + `
 		GROUP BY command_name
 	`
 
@@ -118,7 +182,7 @@ func (m *CacheManager) getExitCodeDistribution(projectID int, date string) (map[
 	query := `
 		SELECT exit_code, COUNT(*) as count
 		FROM command_history
-		WHERE project_id = ? AND log_date = ?
+		WHERE project_id = ? AND log_date = ? AND NOT ` + supersededAttempt + `
 		GROUP BY exit_code
 	`
 
@@ -141,6 +205,39 @@ func (m *CacheManager) getExitCodeDistribution(projectID int, date string) (map[
 	return dist, nil
 }
 
+// getResourceDistribution 获取各命令的 CPU 时间与峰值内存，没有资源数据的记录不计入
+func (m *CacheManager) getResourceDistribution(projectID int, date string) (map[string]*model.CommandResource, error) {
+	query := `
+		SELECT command_name,
+			SUM(IFNULL(cpu_user_ms, 0) + IFNULL(cpu_system_ms, 0)),
+			MAX(IFNULL(max_rss_bytes, 0)),
+			MAX(IFNULL(group_peak_rss_bytes, 0))
+		FROM command_history
+		WHERE project_id = ? AND log_date = ?
+			AND (IFNULL(cpu_user_ms, 0) + IFNULL(cpu_system_ms, 0) > 0 OR IFNULL(max_rss_bytes, 0) > 0
+				OR IFNULL(group_peak_rss_bytes, 0) > 0)
+		GROUP BY command_name
+	`
+
+	rows, err := m.db.Query(query, projectID, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dist := make(map[string]*model.CommandResource)
+	for rows.Next() {
+		var cmdName string
+		resource := &model.CommandResource{}
+		if err := rows.Scan(&cmdName, &resource.CPUMs, &resource.PeakRSSBytes, &resource.GroupPeakRSSBytes); err != nil {
+			return nil, err
+		}
+		dist[cmdName] = resource
+	}
+
+	return dist, rows.Err()
+}
+
 // Save 保存统计缓存
 func (m *CacheManager) Save(cache *model.ProjectStatsCache) error {
 	if err := cache.BeforeSave(); err != nil {
@@ -150,21 +247,25 @@ func (m *CacheManager) Save(cache *model.ProjectStatsCache) error {
 	query := `
 		INSERT INTO project_stats_cache (
 			project_id, stat_date,
-			total_commands, success_commands, failed_commands,
+			total_commands, success_commands, failed_commands, timeout_commands, retried_success_commands, retried_attempts,
 			total_duration_ms, avg_duration_ms, max_duration_ms, min_duration_ms,
-			command_distribution, exit_code_distribution,
+			command_distribution, exit_code_distribution, resource_distribution,
 			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(project_id, stat_date) DO UPDATE SET
 			total_commands = excluded.total_commands,
 			success_commands = excluded.success_commands,
 			failed_commands = excluded.failed_commands,
+			timeout_commands = excluded.timeout_commands,
+			retried_success_commands = excluded.retried_success_commands,
+			retried_attempts = excluded.retried_attempts,
 			total_duration_ms = excluded.total_duration_ms,
 			avg_duration_ms = excluded.avg_duration_ms,
 			max_duration_ms = excluded.max_duration_ms,
 			min_duration_ms = excluded.min_duration_ms,
 			command_distribution = excluded.command_distribution,
 			exit_code_distribution = excluded.exit_code_distribution,
+			resource_distribution = excluded.resource_distribution,
 			updated_at = excluded.updated_at
 	`
 
@@ -174,12 +275,16 @@ func (m *CacheManager) Save(cache *model.ProjectStatsCache) error {
 		cache.TotalCommands,
 		cache.SuccessCommands,
 		cache.FailedCommands,
+		cache.TimeoutCommands,
+		cache.RetriedSuccess,
+		cache.RetriedAttempts,
 		cache.TotalDurationMs,
 		cache.AvgDurationMs,
 		cache.MaxDurationMs,
 		cache.MinDurationMs,
 		cache.CommandDistJSON,
 		cache.ExitCodeDistJSON,
+		cache.ResourceDistJSON,
 		cache.CreatedAt,
 		cache.UpdatedAt,
 	)
@@ -193,34 +298,9 @@ func (m *CacheManager) Save(cache *model.ProjectStatsCache) error {
 
 // Get 获取指定日期的统计缓存
 func (m *CacheManager) Get(projectID int, date string) (*model.ProjectStatsCache, error) {
-	query := `
-		SELECT id, project_id, stat_date,
-			   total_commands, success_commands, failed_commands,
-			   total_duration_ms, avg_duration_ms, max_duration_ms, min_duration_ms,
-			   command_distribution, exit_code_distribution,
-			   created_at, updated_at
-		FROM project_stats_cache
-		WHERE project_id = ? AND stat_date = ?
-	`
-
-	var cache model.ProjectStatsCache
-	err := m.db.QueryRow(query, projectID, date).Scan(
-		&cache.ID,
-		&cache.ProjectID,
-		&cache.StatDate,
-		&cache.TotalCommands,
-		&cache.SuccessCommands,
-		&cache.FailedCommands,
-		&cache.TotalDurationMs,
-		&cache.AvgDurationMs,
-		&cache.MaxDurationMs,
-		&cache.MinDurationMs,
-		&cache.CommandDistJSON,
-		&cache.ExitCodeDistJSON,
-		&cache.CreatedAt,
-		&cache.UpdatedAt,
-	)
+	query := "SELECT " + cacheColumns + " FROM project_stats_cache WHERE project_id = ? AND stat_date = ?"
 
+	cache, err := scanCache(m.db.QueryRow(query, projectID, date))
 	if err == sql.ErrNoRows {
 		return nil, nil // 没有缓存，返回 nil 而不是错误
 	}
@@ -232,21 +312,14 @@ func (m *CacheManager) Get(projectID int, date string) (*model.ProjectStatsCache
 		return nil, fmt.Errorf("加载缓存数据失败: %w", err)
 	}
 
-	return &cache, nil
+	return cache, nil
 }
 
 // GetRange 获取日期范围内的统计缓存
 func (m *CacheManager) GetRange(projectID int, startDate, endDate string) ([]*model.ProjectStatsCache, error) {
-	query := `
-		SELECT id, project_id, stat_date,
-			   total_commands, success_commands, failed_commands,
-			   total_duration_ms, avg_duration_ms, max_duration_ms, min_duration_ms,
-			   command_distribution, exit_code_distribution,
-			   created_at, updated_at
-		FROM project_stats_cache
+	query := "SELECT " + cacheColumns + ` FROM project_stats_cache
 		WHERE project_id = ? AND stat_date BETWEEN ? AND ?
-		ORDER BY stat_date ASC
-	`
+		ORDER BY stat_date ASC`
 
 	rows, err := m.db.Query(query, projectID, startDate, endDate)
 	if err != nil {
@@ -256,23 +329,7 @@ func (m *CacheManager) GetRange(projectID int, startDate, endDate string) ([]*mo
 
 	var caches []*model.ProjectStatsCache
 	for rows.Next() {
-		var cache model.ProjectStatsCache
-		err := rows.Scan(
-			&cache.ID,
-			&cache.ProjectID,
-			&cache.StatDate,
-			&cache.TotalCommands,
-			&cache.SuccessCommands,
-			&cache.FailedCommands,
-			&cache.TotalDurationMs,
-			&cache.AvgDurationMs,
-			&cache.MaxDurationMs,
-			&cache.MinDurationMs,
-			&cache.CommandDistJSON,
-			&cache.ExitCodeDistJSON,
-			&cache.CreatedAt,
-			&cache.UpdatedAt,
-		)
+		cache, err := scanCache(rows)
 		if err != nil {
 			return nil, fmt.Errorf("读取缓存数据失败: %w", err)
 		}
@@ -281,7 +338,7 @@ func (m *CacheManager) GetRange(projectID int, startDate, endDate string) ([]*mo
 			return nil, fmt.Errorf("加载缓存数据失败: %w", err)
 		}
 
-		caches = append(caches, &cache)
+		caches = append(caches, cache)
 	}
 
 	return caches, nil
@@ -427,6 +484,7 @@ func (m *CacheManager) GetSummary(projectID int, startDate, endDate string) (*mo
 		StatDate:             fmt.Sprintf("%s to %s", startDate, endDate),
 		CommandDistribution:  make(map[string]int),
 		ExitCodeDistribution: make(map[int]int),
+		ResourceDistribution: make(map[string]*model.CommandResource),
 	}
 
 	var maxDur, minDur int64 = 0, 999999999
@@ -435,6 +493,9 @@ func (m *CacheManager) GetSummary(projectID int, startDate, endDate string) (*mo
 		summary.TotalCommands += cache.TotalCommands
 		summary.SuccessCommands += cache.SuccessCommands
 		summary.FailedCommands += cache.FailedCommands
+		summary.TimeoutCommands += cache.TimeoutCommands
+		summary.RetriedSuccess += cache.RetriedSuccess
+		summary.RetriedAttempts += cache.RetriedAttempts
 		summary.TotalDurationMs += cache.TotalDurationMs
 
 		if cache.MaxDurationMs > maxDur {
@@ -453,6 +514,16 @@ func (m *CacheManager) GetSummary(projectID int, startDate, endDate string) (*mo
 		for code, count := range cache.ExitCodeDistribution {
 			summary.ExitCodeDistribution[code] += count
 		}
+
+		// 合并资源占用
+		for cmd, resource := range cache.ResourceDistribution {
+			merged, ok := summary.ResourceDistribution[cmd]
+			if !ok {
+				merged = &model.CommandResource{}
+				summary.ResourceDistribution[cmd] = merged
+			}
+			merged.Merge(resource)
+		}
 	}
 
 	summary.MaxDurationMs = maxDur
@@ -3,6 +3,7 @@ package stats
 import (
 	"time"
 
+	"github.com/aliancn/logcmd/internal/executor"
 	"github.com/aliancn/logcmd/internal/model"
 )
 
@@ -22,6 +23,11 @@ func FromCache(cache *model.ProjectStatsCache, projectName string) *Stats {
 		exitDist = make(map[int]int)
 	}
 
+	resourceDist := cache.ResourceDistribution
+	if resourceDist == nil {
+		resourceDist = make(map[string]*model.CommandResource)
+	}
+
 	report := &Stats{
 		ProjectName:     projectName,
 		RangeLabel:      cache.StatDate,
@@ -29,6 +35,9 @@ func FromCache(cache *model.ProjectStatsCache, projectName string) *Stats {
 		TotalCommands:   cache.TotalCommands,
 		SuccessCommands: cache.SuccessCommands,
 		FailedCommands:  cache.FailedCommands,
+		TimeoutCommands: cache.TimeoutCommands,
+		RetriedSuccess:  cache.RetriedSuccess,
+		RetriedAttempts: cache.RetriedAttempts,
 		TotalDuration:   time.Duration(cache.TotalDurationMs) * time.Millisecond,
 		AvgDuration:     time.Duration(cache.AvgDurationMs) * time.Millisecond,
 		MaxDuration:     time.Duration(cache.MaxDurationMs) * time.Millisecond,
@@ -36,6 +45,8 @@ func FromCache(cache *model.ProjectStatsCache, projectName string) *Stats {
 		CommandCounts:   commandDist,
 		ExitCodes:       exitDist,
 		DailyStats:      make(map[string]*DayStats),
+
+		CommandResources: resourceDist,
 	}
 
 	if report.AvgDuration == 0 && report.TotalCommands > 0 {
@@ -44,3 +55,30 @@ func FromCache(cache *model.ProjectStatsCache, projectName string) *Stats {
 
 	return report
 }
+
+// FromHistory 直接从命令历史构造统计报告，用于统计缓存无法满足的筛选条件（如按分支统计）
+func FromHistory(records []*model.CommandHistory, projectName string) *Stats {
+	analyzer := New("")
+	analyzer.stats.ProjectName = projectName
+	analyzer.stats.Source = SourceDatabase
+
+	for _, record := range records {
+		analyzer.updateStats(&LogMetadata{
+			Command:  record.CommandName,
+			ExitCode: record.ExitCode,
+			Success:  record.IsSuccess(),
+			TimedOut: record.Status == executor.StatusTimeout,
+			Attempt:  record.Attempt,
+			CPU:      time.Duration(record.CPUUserMs+record.CPUSystemMs) * time.Millisecond,
+			PeakRSS:  record.MaxRSSBytes,
+			Duration: record.GetDuration(),
+			Date:     record.LogDate,
+
+			AttemptGroup: record.AttemptGroup,
+			GroupPeakRSS: record.GroupPeakRSSBytes,
+		})
+	}
+
+	analyzer.finish()
+	return analyzer.stats
+}
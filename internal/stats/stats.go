@@ -9,10 +9,18 @@ import (
 	"os"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/aliancn/logcmd/internal/artifact"
+	"github.com/aliancn/logcmd/internal/execctx"
+	"github.com/aliancn/logcmd/internal/executor"
+	"github.com/aliancn/logcmd/internal/logmeta"
+	"github.com/aliancn/logcmd/internal/logrecord"
+	"github.com/aliancn/logcmd/internal/model"
+	"github.com/aliancn/logcmd/internal/shellcmd"
 	"github.com/aliancn/logcmd/internal/walker"
 )
 
@@ -27,6 +35,12 @@ var (
 	statusRegex   = regexp.MustCompile(`^执行状态:\s*(\S+)$`)
 	durationRegex = regexp.MustCompile(`^执行时长:\s*(.+)$`)
 	dateRegex     = regexp.MustCompile(`^# 时间:\s*(.+)$`)
+	attemptRegex  = regexp.MustCompile(`^尝试:\s*(\d+)/(\d+)$`)
+	retryRegex    = regexp.MustCompile(`^重试组:\s*(\S+)$`)
+	cpuRegex      = regexp.MustCompile(`^CPU 时间:\s*用户 (\S+), 系统 (\S+)$`)
+	shellRegex    = regexp.MustCompile(`^Shell:\s*(.+)$`)
+	maxRSSRegex   = regexp.MustCompile(`^最大内存:\s*([\d.]+) ([KMGT]?i?B)`)
+	groupRSSRegex = regexp.MustCompile(`^采样峰值内存:\s*([\d.]+) ([KMGT]?i?B)`)
 )
 
 // SourceType 标识统计数据来源
@@ -45,6 +59,9 @@ type Stats struct {
 	TotalCommands   int                  // 总命令数
 	SuccessCommands int                  // 成功命令数
 	FailedCommands  int                  // 失败命令数
+	TimeoutCommands int                  // 超时命令数（不计入失败）
+	RetriedSuccess  int                  // 重试后才成功的命令数
+	RetriedAttempts int                  // 之后又被重试的尝试次数，不计入命令数
 	TotalDuration   time.Duration        // 总执行时长
 	AvgDuration     time.Duration        // 平均执行时长
 	MaxDuration     time.Duration        // 最长执行时长
@@ -52,6 +69,9 @@ type Stats struct {
 	CommandCounts   map[string]int       // 各命令执行次数
 	ExitCodes       map[int]int          // 退出码分布
 	DailyStats      map[string]*DayStats // 每日统计
+	StreamLines     map[string]int       // 各输出流行数（仅统计带结构化记录的日志）
+
+	CommandResources map[string]*model.CommandResource // 各命令的 CPU 时间与峰值内存
 }
 
 // DayStats 单日统计
@@ -60,23 +80,39 @@ type DayStats struct {
 	Commands int
 	Success  int
 	Failed   int
+	Timeout  int
 	Duration time.Duration
 }
 
 // LogMetadata 从日志中解析的元数据
 type LogMetadata struct {
-	Command  string
-	ExitCode int
-	Success  bool
-	Duration time.Duration
-	Date     string
+	Command      string
+	ExitCode     int
+	Success      bool
+	TimedOut     bool
+	Attempt      int    // 第几次尝试，未启用重试时为 0
+	AttemptGroup string // 重试组 ID，同一组只统计最后一次尝试
+	CPU          time.Duration
+	PeakRSS      int64 // rusage 记录的单个进程最大内存
+	GroupPeakRSS int64 // 运行期间采样到的进程组内存峰值
+	Duration     time.Duration
+	Date         string
+	StreamLines  map[string]int
+	Context      execctx.Info // 日志头部记录的执行上下文
 }
 
+// HistoryLookup 按日志路径查找对应的命令历史，找不到时返回 nil
+type HistoryLookup func(logPath string) *model.CommandHistory
+
 // Analyzer 统计分析器
 type Analyzer struct {
-	logDir string
-	stats  *Stats
-	mu     sync.Mutex
+	logDir  string
+	filter  execctx.Filter
+	history HistoryLookup
+	stats   *Stats
+	mu      sync.Mutex
+
+	attempts map[string][]*LogMetadata // 按重试组暂存的尝试，全部读取后再确定最后一次尝试
 }
 
 // New 创建统计分析器
@@ -88,14 +124,29 @@ func New(logDir string) *Analyzer {
 			CommandCounts: make(map[string]int),
 			ExitCodes:     make(map[int]int),
 			DailyStats:    make(map[string]*DayStats),
+			StreamLines:   make(map[string]int),
+
+			CommandResources: make(map[string]*model.CommandResource),
 		},
+		attempts: make(map[string][]*LogMetadata),
 	}
 }
 
+// SetFilter 只统计执行上下文满足条件的日志
+func (a *Analyzer) SetFilter(filter execctx.Filter) {
+	a.filter = filter
+}
+
+// SetHistory 设置命令历史查询，没有 JSON 元数据的旧日志从命令历史读取资源占用
+func (a *Analyzer) SetHistory(lookup HistoryLookup) {
+	a.history = lookup
+}
+
 // Analyze 执行统计分析
 func (a *Analyzer) Analyze(ctx context.Context) (*Stats, error) {
 	fileWalker, err := walker.New(walker.Options{
-		Root: a.logDir,
+		Root:     a.logDir,
+		SkipDirs: []string{artifact.DirName},
 		FileFilter: func(path string, info os.FileInfo) bool {
 			return strings.HasSuffix(path, ".log")
 		},
@@ -118,45 +169,142 @@ func (a *Analyzer) Analyze(ctx context.Context) (*Stats, error) {
 		return nil, fmt.Errorf("遍历日志目录失败: %w", err)
 	}
 
+	a.finish()
+	return a.stats, nil
+}
+
+// finish 统计每个重试组的最后一次尝试，并汇总平均与最短执行时长
+// 之前的尝试只计入重试次数和资源占用，使一次重试后成功的运行计为一条成功的命令
+func (a *Analyzer) finish() {
+	for _, attempts := range a.attempts {
+		last := attempts[0]
+		for _, meta := range attempts[1:] {
+			if meta.Attempt > last.Attempt {
+				last = meta
+			}
+		}
+		for _, meta := range attempts {
+			if meta != last {
+				a.stats.RetriedAttempts++
+				a.addResources(meta)
+			}
+		}
+		a.count(last)
+	}
+	a.attempts = make(map[string][]*LogMetadata)
+
 	if a.stats.TotalCommands > 0 {
 		a.stats.AvgDuration = a.stats.TotalDuration / time.Duration(a.stats.TotalCommands)
 		if a.stats.MinDuration == 0 {
 			a.stats.MinDuration = a.stats.MaxDuration
 		}
 	}
-
-	return a.stats, nil
 }
 
-// analyzeFile 分析单个日志文件
+// analyzeFile 分析单个日志文件，优先读取 JSON 元数据文件，旧日志回退到解析头部和尾部
 func (a *Analyzer) analyzeFile(ctx context.Context, filePath string) error {
-	file, err := os.Open(filePath)
+	var metadata *LogMetadata
+	sidecar, err := logmeta.Read(filePath)
+	if err == nil {
+		metadata = metadataFromResult(sidecar.Result)
+	} else {
+		if !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "警告: %s 的元数据文件无法使用，改为解析日志: %v\n", filePath, err)
+		}
+		if metadata, err = parseLogFile(ctx, filePath); err != nil {
+			return err
+		}
+		a.applyHistory(filePath, metadata)
+	}
+
+	if metadata.Command == "" {
+		fmt.Fprintf(os.Stderr, "跳过缺少元数据的日志: %s\n", filePath)
+		return nil
+	}
+
+	if !a.filter.Match(&metadata.Context) {
+		return nil
+	}
+
+	if metadata.Date == "" {
+		fmt.Fprintf(os.Stderr, "警告: 日志缺少时间信息，仅统计命令: %s\n", filePath)
+	}
+
+	if logrecord.Exists(filePath) {
+		lines, err := countStreamLines(logrecord.PathFor(filePath))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "警告: 读取结构化记录失败: %v\n", err)
+		}
+		metadata.StreamLines = lines
+	}
+
+	a.updateStats(metadata)
+	return nil
+}
+
+// parseLogFile 从日志头部和尾部文本解析元数据，raw 日志的头部和尾部从 .meta 文件读取
+func parseLogFile(ctx context.Context, filePath string) (*LogMetadata, error) {
+	file, err := os.Open(logmeta.Source(filePath))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer file.Close()
 
 	metadata := &LogMetadata{}
 
 	if err := parseLogHeader(ctx, file, metadata); err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := parseLogFooter(ctx, file, metadata); err != nil {
-		return err
+		return nil, err
 	}
+	return metadata, nil
+}
 
-	if metadata.Command == "" {
-		fmt.Fprintf(os.Stderr, "跳过缺少元数据的日志: %s\n", filePath)
-		return nil
+// applyHistory 用命令历史中的资源占用替换从日志尾部文本解析的近似值
+// 尾部的内存经过 FormatBytes 格式化，精度有限；找不到历史记录时保留解析结果
+func (a *Analyzer) applyHistory(filePath string, meta *LogMetadata) {
+	if a.history == nil {
+		return
 	}
-
-	if metadata.Date == "" {
-		fmt.Fprintf(os.Stderr, "警告: 日志缺少时间信息，仅统计命令: %s\n", filePath)
+	record := a.history(filePath)
+	if record == nil {
+		return
 	}
+	meta.CPU = time.Duration(record.CPUUserMs+record.CPUSystemMs) * time.Millisecond
+	meta.PeakRSS = record.MaxRSSBytes
+	meta.GroupPeakRSS = record.GroupPeakRSSBytes
+}
 
-	a.updateStats(metadata)
-	return nil
+// metadataFromResult 从 JSON 元数据中的执行结果构造统计所需的元数据
+func metadataFromResult(result *executor.Result) *LogMetadata {
+	meta := &LogMetadata{
+		Command:  result.Command,
+		ExitCode: result.ExitCode,
+		Success:  result.Success,
+		TimedOut: result.Status == executor.StatusTimeout,
+		Attempt:  result.Attempt,
+		Duration: result.Duration,
+
+		AttemptGroup: result.AttemptGroup,
+		GroupPeakRSS: executor.PeakRSS(result.Samples),
+	}
+	// shell 模式下按脚本中第一个执行的程序统计，与解析日志尾部时一致
+	if result.Script != "" {
+		meta.Command = shellcmd.ProgramName(result.Script)
+	}
+	if !result.StartTime.IsZero() {
+		meta.Date = result.StartTime.Format("2006-01-02")
+	}
+	if result.Usage != nil {
+		meta.CPU = result.Usage.CPU()
+		meta.PeakRSS = result.Usage.MaxRSS
+	}
+	if result.Context != nil {
+		meta.Context = *result.Context
+	}
+	return meta
 }
 
 func parseLogHeader(ctx context.Context, file io.Reader, meta *LogMetadata) error {
@@ -178,8 +326,13 @@ func parseLogHeader(ctx context.Context, file io.Reader, meta *LogMetadata) erro
 			if t, err := time.Parse("2006-01-02 15:04:05", matches[1]); err == nil {
 				meta.Date = t.Format("2006-01-02")
 			}
+			continue
+		}
+		// 时间与执行上下文之后的分隔线表示头部结束
+		if meta.Date != "" && strings.HasPrefix(line, "####") {
 			break
 		}
+		execctx.ParseHeaderLine(line, &meta.Context)
 		if lines >= maxHeaderScanLines {
 			break
 		}
@@ -226,6 +379,9 @@ func parseLogFooter(ctx context.Context, file *os.File, meta *LogMetadata) error
 }
 
 func processFooterBuffer(buf []byte, meta *LogMetadata) {
+	var commandLine string
+	shellMode := false
+
 	lines := bytes.Split(buf, []byte{'\n'})
 	for _, line := range lines {
 		lineStr := string(bytes.TrimSpace(line))
@@ -234,18 +390,46 @@ func processFooterBuffer(buf []byte, meta *LogMetadata) {
 		}
 
 		if matches := cmdRegex.FindStringSubmatch(lineStr); matches != nil {
+			commandLine = matches[1]
 			parts := strings.Fields(matches[1])
 			if len(parts) > 0 {
 				meta.Command = parts[0]
 			}
 		}
 
+		if shellRegex.MatchString(lineStr) {
+			shellMode = true
+		}
+
 		if matches := exitCodeRegex.FindStringSubmatch(lineStr); matches != nil {
 			fmt.Sscanf(matches[1], "%d", &meta.ExitCode)
 		}
 
 		if matches := statusRegex.FindStringSubmatch(lineStr); matches != nil {
 			meta.Success = matches[1] == "成功"
+			meta.TimedOut = matches[1] == "超时"
+		}
+
+		if matches := attemptRegex.FindStringSubmatch(lineStr); matches != nil {
+			fmt.Sscanf(matches[1], "%d", &meta.Attempt)
+		}
+
+		if matches := retryRegex.FindStringSubmatch(lineStr); matches != nil {
+			meta.AttemptGroup = matches[1]
+		}
+
+		if matches := cpuRegex.FindStringSubmatch(lineStr); matches != nil {
+			user, _ := time.ParseDuration(matches[1])
+			sys, _ := time.ParseDuration(matches[2])
+			meta.CPU = user + sys
+		}
+
+		if matches := maxRSSRegex.FindStringSubmatch(lineStr); matches != nil {
+			meta.PeakRSS = parseBytes(matches[1], matches[2])
+		}
+
+		if matches := groupRSSRegex.FindStringSubmatch(lineStr); matches != nil {
+			meta.GroupPeakRSS = parseBytes(matches[1], matches[2])
 		}
 
 		if matches := durationRegex.FindStringSubmatch(lineStr); matches != nil {
@@ -253,18 +437,64 @@ func processFooterBuffer(buf []byte, meta *LogMetadata) {
 			meta.Duration = duration
 		}
 	}
+
+	// shell 模式下命令行是完整的脚本，按其中第一个执行的程序统计
+	if shellMode && commandLine != "" {
+		meta.Command = shellcmd.ProgramName(commandLine)
+	}
 }
 
-// updateStats 更新统计数据
+// parseBytes 解析 executor.FormatBytes 生成的内存大小（如 12.3 MiB）
+func parseBytes(value, unit string) int64 {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	multipliers := map[string]float64{"B": 1, "KiB": 1 << 10, "MiB": 1 << 20, "GiB": 1 << 30, "TiB": 1 << 40}
+	return int64(v * multipliers[unit])
+}
+
+// countStreamLines 统计结构化记录中各输出流的行数
+func countStreamLines(path string) (map[string]int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	counts := make(map[string]int)
+	scanner := logrecord.NewScanner(file)
+	for scanner.Scan() {
+		counts[scanner.Record().Stream]++
+	}
+	return counts, scanner.Err()
+}
+
+// updateStats 更新统计数据，启用重试的运行在 finish 中按重试组统计
 func (a *Analyzer) updateStats(meta *LogMetadata) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
+	if meta.AttemptGroup != "" {
+		a.attempts[meta.AttemptGroup] = append(a.attempts[meta.AttemptGroup], meta)
+		return
+	}
+	a.count(meta)
+}
+
+// count 把一次运行计入命令数、执行时长和各项分布
+func (a *Analyzer) count(meta *LogMetadata) {
 	a.stats.TotalCommands++
 
-	if meta.Success {
+	switch {
+	case meta.Success:
 		a.stats.SuccessCommands++
-	} else {
+		if meta.Attempt > 1 {
+			a.stats.RetriedSuccess++
+		}
+	case meta.TimedOut:
+		a.stats.TimeoutCommands++
+	default:
 		a.stats.FailedCommands++
 	}
 
@@ -277,6 +507,10 @@ func (a *Analyzer) updateStats(meta *LogMetadata) {
 	}
 	a.stats.CommandCounts[meta.Command]++
 	a.stats.ExitCodes[meta.ExitCode]++
+	for stream, count := range meta.StreamLines {
+		a.stats.StreamLines[stream] += count
+	}
+	a.addResources(meta)
 
 	// 更新每日统计
 	if meta.Date != "" {
@@ -286,15 +520,35 @@ func (a *Analyzer) updateStats(meta *LogMetadata) {
 			a.stats.DailyStats[meta.Date] = dayStats
 		}
 		dayStats.Commands++
-		if meta.Success {
+		switch {
+		case meta.Success:
 			dayStats.Success++
-		} else {
+		case meta.TimedOut:
+			dayStats.Timeout++
+		default:
 			dayStats.Failed++
 		}
 		dayStats.Duration += meta.Duration
 	}
 }
 
+// addResources 把一次运行的 CPU 时间与峰值内存计入对应命令的资源占用
+func (a *Analyzer) addResources(meta *LogMetadata) {
+	if meta.CPU <= 0 && meta.PeakRSS <= 0 && meta.GroupPeakRSS <= 0 {
+		return
+	}
+	resource, ok := a.stats.CommandResources[meta.Command]
+	if !ok {
+		resource = &model.CommandResource{}
+		a.stats.CommandResources[meta.Command] = resource
+	}
+	resource.Merge(&model.CommandResource{
+		CPUMs:             meta.CPU.Milliseconds(),
+		PeakRSSBytes:      meta.PeakRSS,
+		GroupPeakRSSBytes: meta.GroupPeakRSS,
+	})
+}
+
 // PrintStats 打印统计结果
 func PrintStats(stats *Stats) {
 	fmt.Println(strings.Repeat("=", 60))
@@ -318,12 +572,17 @@ func PrintStats(stats *Stats) {
 
 	// 总体统计
 	fmt.Printf("总命令数: %d\n", stats.TotalCommands)
-	successRate := 0.0
-	if stats.TotalCommands > 0 {
-		successRate = float64(stats.SuccessCommands) / float64(stats.TotalCommands) * 100
+	fmt.Printf("成功: %d (%.1f%%)\n", stats.SuccessCommands, percentOf(stats.SuccessCommands, stats.TotalCommands))
+	fmt.Printf("失败: %d (%.1f%%)\n", stats.FailedCommands, percentOf(stats.FailedCommands, stats.TotalCommands))
+	if stats.TimeoutCommands > 0 {
+		fmt.Printf("超时: %d (%.1f%%)\n", stats.TimeoutCommands, percentOf(stats.TimeoutCommands, stats.TotalCommands))
+	}
+	if stats.RetriedSuccess > 0 {
+		fmt.Printf("重试后成功: %d (占成功 %.1f%%)\n", stats.RetriedSuccess, percentOf(stats.RetriedSuccess, stats.SuccessCommands))
+	}
+	if stats.RetriedAttempts > 0 {
+		fmt.Printf("被重试的尝试: %d (不计入命令数)\n", stats.RetriedAttempts)
 	}
-	fmt.Printf("成功: %d (%.1f%%)\n", stats.SuccessCommands, successRate)
-	fmt.Printf("失败: %d (%.1f%%)\n", stats.FailedCommands, 100-successRate)
 	fmt.Printf("总执行时长: %v\n", stats.TotalDuration)
 	if stats.AvgDuration > 0 {
 		fmt.Printf("平均执行时长: %v\n", stats.AvgDuration)
@@ -383,11 +642,36 @@ func PrintStats(stats *Stats) {
 		fmt.Println()
 	}
 
+	// 资源占用
+	if len(stats.CommandResources) > 0 {
+		printCommandResources(stats.CommandResources)
+	}
+
+	// 输出流分布
+	if len(stats.StreamLines) > 0 {
+		fmt.Println("输出流行数:")
+		fmt.Println(strings.Repeat("-", 40))
+		var streams []string
+		for stream := range stats.StreamLines {
+			streams = append(streams, stream)
+		}
+		sort.Strings(streams)
+		for _, stream := range streams {
+			fmt.Printf("  %s: %d 行\n", stream, stats.StreamLines[stream])
+		}
+		fmt.Println()
+	}
+
 	// 每日统计
 	if len(stats.DailyStats) > 0 {
 		fmt.Println("每日统计:")
 		fmt.Println(strings.Repeat("-", 40))
 		for date, dayStats := range stats.DailyStats {
+			if dayStats.Timeout > 0 {
+				fmt.Printf("  %s: %d 个命令 (成功: %d, 失败: %d, 超时: %d, 总时长: %v)\n",
+					date, dayStats.Commands, dayStats.Success, dayStats.Failed, dayStats.Timeout, dayStats.Duration)
+				continue
+			}
 			fmt.Printf("  %s: %d 个命令 (成功: %d, 失败: %d, 总时长: %v)\n",
 				date, dayStats.Commands, dayStats.Success, dayStats.Failed, dayStats.Duration)
 		}
@@ -397,6 +681,47 @@ func PrintStats(stats *Stats) {
 	fmt.Println(strings.Repeat("=", 60))
 }
 
+// printCommandResources 按 CPU 时间从高到低打印各命令的资源占用（Top 10）
+func printCommandResources(resources map[string]*model.CommandResource) {
+	fmt.Println("资源占用 (按 CPU 时间, Top 10):")
+	fmt.Println(strings.Repeat("-", 40))
+
+	var names []string
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := resources[names[i]], resources[names[j]]
+		if a.CPUMs == b.CPUMs {
+			return names[i] < names[j]
+		}
+		return a.CPUMs > b.CPUMs
+	})
+	if len(names) > 10 {
+		names = names[:10]
+	}
+
+	for i, name := range names {
+		resource := resources[name]
+		cpu := time.Duration(resource.CPUMs) * time.Millisecond
+		line := fmt.Sprintf("  %d. %s: CPU %.4f 小时 (%v), 峰值内存 %s",
+			i+1, name, cpu.Hours(), cpu, executor.FormatBytes(resource.PeakRSSBytes))
+		if resource.GroupPeakRSSBytes > 0 {
+			line += fmt.Sprintf(", 进程组峰值 %s", executor.FormatBytes(resource.GroupPeakRSSBytes))
+		}
+		fmt.Println(line)
+	}
+	fmt.Println()
+}
+
+// percentOf 计算百分比，总数为 0 时返回 0
+func percentOf(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) / float64(total) * 100
+}
+
 func sourceLabel(source SourceType) string {
 	switch source {
 	case SourceDatabase:
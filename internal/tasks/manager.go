@@ -48,9 +48,9 @@ func (m *Manager) Create(task *model.Task) (*model.Task, error) {
 	task.UpdatedAt = now
 
 	result, err := m.db.Exec(`
-		INSERT INTO tasks (command, command_args, working_dir, log_dir, status, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, task.Command, task.ArgsJSON, task.WorkingDir, task.LogDir, task.Status, task.CreatedAt, task.UpdatedAt)
+		INSERT INTO tasks (command, command_args, working_dir, log_dir, run_options, execution_context, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, task.Command, task.ArgsJSON, task.WorkingDir, task.LogDir, task.OptionsJSON, task.ContextJSON, task.Status, task.CreatedAt, task.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("创建任务失败: %w", err)
 	}
@@ -71,8 +71,8 @@ func (m *Manager) Get(id int) (*model.Task, error) {
 	}
 
 	row := m.db.QueryRow(`
-		SELECT id, command, command_args, working_dir, log_dir, status,
-		       pid, IFNULL(log_file_path, ''), exit_code, IFNULL(error_message, ''), created_at, updated_at,
+		SELECT id, command, command_args, working_dir, log_dir, IFNULL(run_options, ''), IFNULL(execution_context, ''), status,
+		       pid, process_group, IFNULL(log_file_path, ''), exit_code, IFNULL(error_message, ''), IFNULL(termination_reason, ''), created_at, updated_at,
 		       started_at, completed_at
 		FROM tasks
 		WHERE id = ?
@@ -96,8 +96,8 @@ func (m *Manager) ListActive() ([]*model.Task, error) {
 	}
 
 	rows, err := m.db.Query(`
-		SELECT id, command, command_args, working_dir, log_dir, status,
-		       pid, IFNULL(log_file_path, ''), exit_code, IFNULL(error_message, ''), created_at, updated_at,
+		SELECT id, command, command_args, working_dir, log_dir, IFNULL(run_options, ''), IFNULL(execution_context, ''), status,
+		       pid, process_group, IFNULL(log_file_path, ''), exit_code, IFNULL(error_message, ''), IFNULL(termination_reason, ''), created_at, updated_at,
 		       started_at, completed_at
 		FROM tasks
 		WHERE status IN (?, ?)
@@ -127,6 +127,7 @@ func (m *Manager) ListActive() ([]*model.Task, error) {
 func scanTask(scanner rowScanner) (*model.Task, error) {
 	var (
 		pid         sql.NullInt64
+		pgid        sql.NullInt64
 		exitCode    sql.NullInt64
 		startedAt   sql.NullTime
 		completedAt sql.NullTime
@@ -139,11 +140,15 @@ func scanTask(scanner rowScanner) (*model.Task, error) {
 		&task.ArgsJSON,
 		&task.WorkingDir,
 		&task.LogDir,
+		&task.OptionsJSON,
+		&task.ContextJSON,
 		&task.Status,
 		&pid,
+		&pgid,
 		&task.LogFilePath,
 		&exitCode,
 		&task.ErrorMessage,
+		&task.Reason,
 		&task.CreatedAt,
 		&task.UpdatedAt,
 		&startedAt,
@@ -153,6 +158,7 @@ func scanTask(scanner rowScanner) (*model.Task, error) {
 	}
 
 	task.PID = nullInt64Ptr(pid)
+	task.ProcessGroup = nullInt64Ptr(pgid)
 	task.ExitCode = nullInt64Ptr(exitCode)
 	task.StartedAt = nullTimePtr(startedAt)
 	task.CompletedAt = nullTimePtr(completedAt)
@@ -190,6 +196,26 @@ func (m *Manager) UpdatePID(id int, pid int) error {
 	return err
 }
 
+// UpdateProcessGroup 记录任务命令所在的进程组
+func (m *Manager) UpdateProcessGroup(id int, pgid int) error {
+	if m == nil || m.db == nil {
+		return fmt.Errorf("任务管理器未初始化")
+	}
+	now := time.Now()
+	_, err := m.db.Exec(`UPDATE tasks SET process_group = ?, updated_at = ? WHERE id = ?`, pgid, now, id)
+	return err
+}
+
+// UpdateTerminationReason 记录任务命令被 logcmd 主动终止的原因
+func (m *Manager) UpdateTerminationReason(id int, reason string) error {
+	if m == nil || m.db == nil {
+		return fmt.Errorf("任务管理器未初始化")
+	}
+	now := time.Now()
+	_, err := m.db.Exec(`UPDATE tasks SET termination_reason = ?, updated_at = ? WHERE id = ?`, reason, now, id)
+	return err
+}
+
 // UpdateLogFilePath 更新任务的日志文件路径
 func (m *Manager) UpdateLogFilePath(id int, path string) error {
 	if m == nil || m.db == nil {
@@ -239,7 +265,8 @@ func (m *Manager) MarkCompletion(id int, status string, exitCode int, logFilePat
 			error_message = ?,
 			completed_at = ?,
 			updated_at = ?,
-			pid = NULL
+			pid = NULL,
+			process_group = NULL
 		WHERE id = ?
 	`, status, exitCode, logFilePath, errMsg, now, now, id)
 	if err != nil {
@@ -265,7 +292,8 @@ func (m *Manager) MarkStopped(id int, status string, errMsg string) error {
 			error_message = ?,
 			completed_at = ?,
 			updated_at = ?,
-			pid = NULL
+			pid = NULL,
+			process_group = NULL
 		WHERE id = ? AND status IN (?, ?)
 	`, status, errMsg, now, now, id, model.TaskStatusPending, model.TaskStatusRunning)
 	if err != nil {
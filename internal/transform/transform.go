@@ -0,0 +1,248 @@
+package transform
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aliancn/logcmd/internal/executor"
+)
+
+// 可用的转换，按配置中的顺序串联
+const (
+	StripANSI      = "strip_ansi"      // 去除 ANSI 颜色与光标控制序列
+	CollapseCR     = "collapse_cr"     // \r 重绘的进度行只保留最终状态
+	CompactRepeats = "compact_repeats" // 连续重复的行压缩为一条提示
+	DropLines      = "drop_lines"      // 丢弃匹配 drop_patterns 的行
+	DetectBinary   = "detect_binary"   // 二进制输出替换为字节数提示
+)
+
+// Names 返回所有可用的转换名称
+func Names() []string {
+	return []string{StripANSI, CollapseCR, CompactRepeats, DropLines, DetectBinary}
+}
+
+// maxLine 没有换行时最多缓存的字节数，超过后按一行处理
+const maxLine = 64 * 1024
+
+// Options 转换的参数
+type Options struct {
+	DropPatterns []string // drop_lines 使用的正则
+}
+
+// Chain 按名称顺序构造作用于日志输出的过滤器
+func Chain(names []string, opts Options) ([]executor.LogFilter, error) {
+	var dropRegexes []*regexp.Regexp
+	for _, pattern := range opts.DropPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("丢弃规则 %q 无效: %w", pattern, err)
+		}
+		dropRegexes = append(dropRegexes, re)
+	}
+
+	filters := make([]executor.LogFilter, 0, len(names))
+	for _, name := range names {
+		var newProcessor func() lineProcessor
+		switch name {
+		case StripANSI:
+			newProcessor = func() lineProcessor { return stripANSI{} }
+		case CollapseCR:
+			newProcessor = func() lineProcessor { return collapseCR{} }
+		case CompactRepeats:
+			newProcessor = func() lineProcessor { return &compactRepeats{} }
+		case DropLines:
+			newProcessor = func() lineProcessor { return dropLines{regexes: dropRegexes} }
+		case DetectBinary:
+			newProcessor = func() lineProcessor { return &detectBinary{} }
+		default:
+			return nil, fmt.Errorf("未知的日志转换 %q，可选值: %s", name, strings.Join(Names(), ", "))
+		}
+		filters = append(filters, func(dst executor.FlushWriter) executor.FlushWriter {
+			return &lineWriter{dst: dst, proc: newProcessor()}
+		})
+	}
+	return filters, nil
+}
+
+// lineProcessor 逐行转换输出，line 包含行尾的换行符（如果有）
+type lineProcessor interface {
+	line(l []byte) []byte
+	// flush 返回尚未写出的提示（如重复次数），在输出结束和输出中途同步时调用，之后仍可继续处理后续的行
+	flush() []byte
+}
+
+// lineWriter 将输出切分为行交给 lineProcessor，并把结果写入下游
+type lineWriter struct {
+	dst  executor.FlushWriter
+	proc lineProcessor
+	buf  []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	var out []byte
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			if len(w.buf) < maxLine {
+				break
+			}
+			i = maxLine - 1
+		}
+		out = append(out, w.proc.line(w.buf[:i+1])...)
+		w.buf = w.buf[i+1:]
+	}
+	// 避免底层数组随输出无限增长
+	w.buf = append([]byte(nil), w.buf...)
+
+	if len(out) > 0 {
+		if _, err := w.dst.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) Flush() error {
+	var out []byte
+	if len(w.buf) > 0 {
+		out = append(out, w.proc.line(w.buf)...)
+		w.buf = nil
+	}
+	out = append(out, w.proc.flush()...)
+	if len(out) > 0 {
+		if _, err := w.dst.Write(out); err != nil {
+			return err
+		}
+	}
+	return w.dst.Flush()
+}
+
+// Sync 写出等待中的提示并同步下游，未结束的行继续缓存
+func (w *lineWriter) Sync() error {
+	if out := w.proc.flush(); len(out) > 0 {
+		if _, err := w.dst.Write(out); err != nil {
+			return err
+		}
+	}
+	if s, ok := w.dst.(executor.Syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// ansiRegex 匹配 CSI（颜色、光标移动）、OSC（窗口标题、超链接）及其他两字节转义序列
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+type stripANSI struct{}
+
+func (stripANSI) line(l []byte) []byte { return ansiRegex.ReplaceAll(l, nil) }
+func (stripANSI) flush() []byte        { return nil }
+
+type collapseCR struct{}
+
+func (collapseCR) line(l []byte) []byte {
+	content, newline := splitNewline(l)
+	// Windows 换行的 \r 不是重绘
+	content = bytes.TrimRight(content, "\r")
+	if i := bytes.LastIndexByte(content, '\r'); i >= 0 {
+		content = content[i+1:]
+	}
+	return append(content, newline...)
+}
+
+func (collapseCR) flush() []byte { return nil }
+
+// compactRepeats 记住上一行，后续相同的行只计数，出现不同的行或输出结束时写出提示
+type compactRepeats struct {
+	last    []byte
+	repeats int
+}
+
+func (c *compactRepeats) line(l []byte) []byte {
+	content, _ := splitNewline(l)
+	if c.last != nil && bytes.Equal(content, c.last) {
+		c.repeats++
+		return nil
+	}
+	out := c.flush()
+	c.last = append([]byte(nil), content...)
+	return append(out, l...)
+}
+
+func (c *compactRepeats) flush() []byte {
+	if c.repeats == 0 {
+		return nil
+	}
+	marker := fmt.Sprintf("[logcmd] 上一行重复了 %d 次\n", c.repeats)
+	c.repeats = 0
+	return []byte(marker)
+}
+
+type dropLines struct {
+	regexes []*regexp.Regexp
+}
+
+func (d dropLines) line(l []byte) []byte {
+	content, _ := splitNewline(l)
+	for _, re := range d.regexes {
+		if re.Match(content) {
+			return nil
+		}
+	}
+	return l
+}
+
+func (dropLines) flush() []byte { return nil }
+
+// detectBinary 连续的二进制内容合并为一条提示，只记录字节数
+type detectBinary struct {
+	skipped int
+}
+
+func (d *detectBinary) line(l []byte) []byte {
+	if isBinary(l) {
+		d.skipped += len(l)
+		return nil
+	}
+	return append(d.flush(), l...)
+}
+
+func (d *detectBinary) flush() []byte {
+	if d.skipped == 0 {
+		return nil
+	}
+	marker := fmt.Sprintf("[logcmd] 省略了 %d 字节二进制输出\n", d.skipped)
+	d.skipped = 0
+	return []byte(marker)
+}
+
+// isBinary 包含 NUL 或控制字符比例过高时认为是二进制内容
+func isBinary(l []byte) bool {
+	if bytes.IndexByte(l, 0) >= 0 {
+		return true
+	}
+	if len(l) < 32 {
+		return false
+	}
+	control := 0
+	for _, b := range l {
+		switch {
+		case b == '\t' || b == '\n' || b == '\r' || b == '\f' || b == '\b' || b == 0x1b:
+		case b < 0x20 || b == 0x7f:
+			control++
+		}
+	}
+	return control*20 > len(l)
+}
+
+// splitNewline 拆分行内容和行尾的换行符
+func splitNewline(l []byte) ([]byte, []byte) {
+	if n := len(l); n > 0 && l[n-1] == '\n' {
+		return l[:n-1], l[n-1:]
+	}
+	return l, nil
+}
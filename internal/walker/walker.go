@@ -20,13 +20,15 @@ type Options struct {
 	Root       string
 	Workers    int
 	FileFilter FileFilter
+	SkipDirs   []string // 跳过的目录名，其下的文件都不处理
 }
 
 // Walker 封装通用的并行文件遍历
 type Walker struct {
-	root    string
-	workers int
-	filter  FileFilter
+	root     string
+	workers  int
+	filter   FileFilter
+	skipDirs map[string]bool
 }
 
 // New 创建 Walker
@@ -43,10 +45,16 @@ func New(opts Options) (*Walker, error) {
 		}
 	}
 
+	skipDirs := make(map[string]bool, len(opts.SkipDirs))
+	for _, name := range opts.SkipDirs {
+		skipDirs[name] = true
+	}
+
 	return &Walker{
-		root:    opts.Root,
-		workers: workers,
-		filter:  opts.FileFilter,
+		root:     opts.Root,
+		workers:  workers,
+		filter:   opts.FileFilter,
+		skipDirs: skipDirs,
 	}, nil
 }
 
@@ -102,6 +110,9 @@ func (w *Walker) Walk(ctx context.Context, processor FileProcessor) error {
 		}
 
 		if info.IsDir() {
+			if path != w.root && w.skipDirs[info.Name()] {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
@@ -0,0 +1,134 @@
+package artifact_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aliancn/logcmd/internal/artifact"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirFor(t *testing.T) {
+	got := artifact.DirFor("/logs/2024-01-01/build_120000.log")
+	if want := "/logs/2024-01-01/artifacts/build_120000"; got != want {
+		t.Errorf("DirFor() = %q, want %q", got, want)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "coverage/index.html"), "<html>")
+	writeFile(t, filepath.Join(root, "coverage/raw.out"), "raw")
+	writeFile(t, filepath.Join(root, "dist/app.tar.gz"), "tar")
+	writeFile(t, filepath.Join(root, "dist/sub/readme"), "readme")
+	dest := filepath.Join(t.TempDir(), "artifacts")
+
+	collected, unmatched, err := artifact.Collect(root, []string{"coverage/*.html", "dist/", "missing/*"}, dest)
+	if err != nil {
+		t.Fatalf("Collect() 失败: %v", err)
+	}
+	if !reflect.DeepEqual(unmatched, []string{"missing/*"}) {
+		t.Errorf("unmatched = %v", unmatched)
+	}
+
+	var names []string
+	for _, a := range collected {
+		names = append(names, a.Name)
+		if a.Size == 0 || len(a.SHA256) != 64 {
+			t.Errorf("产物 %s 应记录大小和校验和: %+v", a.Name, a)
+		}
+		if _, err := os.Stat(a.Path); err != nil {
+			t.Errorf("产物 %s 未保存: %v", a.Name, err)
+		}
+	}
+	want := []string{"coverage/index.html", "dist/app.tar.gz", "dist/sub/readme"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+
+	// 小文件按复制保存，之后原地修改源文件不影响产物
+	writeFile(t, filepath.Join(root, "dist/app.tar.gz"), "changed")
+	if err := artifact.Verify(collected[1]); err != nil {
+		t.Errorf("修改源文件后产物应保持不变: %v", err)
+	}
+}
+
+func TestCollectOutsideRoot(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	writeFile(t, filepath.Join(outside, "a/report.txt"), "a")
+	writeFile(t, filepath.Join(outside, "b/report.txt"), "b")
+	dest := filepath.Join(t.TempDir(), "artifacts")
+
+	collected, _, err := artifact.Collect(root, []string{filepath.Join(outside, "*/report.txt")}, dest)
+	if err != nil {
+		t.Fatalf("Collect() 失败: %v", err)
+	}
+	if len(collected) != 2 || collected[0].Name == collected[1].Name {
+		t.Fatalf("不同目录中的同名文件应保存为不同的产物: %+v", collected)
+	}
+	for _, a := range collected {
+		if !strings.HasPrefix(a.Name, "_external/") || filepath.Base(a.Name) != "report.txt" {
+			t.Errorf("工作目录之外的产物名称 = %q", a.Name)
+		}
+		if err := artifact.Verify(a); err != nil {
+			t.Errorf("产物 %s 被覆盖: %v", a.Name, err)
+		}
+	}
+}
+
+func TestCollectExcludesLogDir(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "build/app"), "app")
+	writeFile(t, filepath.Join(root, "build/logs/2024-01-01/build.log"), "log")
+	writeFile(t, filepath.Join(root, "build/.logcmd/2024-01-01/build.log"), "log")
+	dest := filepath.Join(t.TempDir(), "artifacts")
+
+	// 配置的日志目录与默认的 .logcmd 都不应作为产物收集
+	collected, unmatched, err := artifact.Collect(root, []string{"build/", "build/logs/*/*.log"}, dest, filepath.Join(root, "build/logs"))
+	if err != nil {
+		t.Fatalf("Collect() 失败: %v", err)
+	}
+	if len(collected) != 1 || collected[0].Name != "build/app" {
+		t.Errorf("collected = %+v", collected)
+	}
+	if !reflect.DeepEqual(unmatched, []string{"build/logs/*/*.log"}) {
+		t.Errorf("unmatched = %v", unmatched)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "report/result.xml"), "<ok/>")
+	collected, _, err := artifact.Collect(root, []string{"report"}, filepath.Join(t.TempDir(), "artifacts"))
+	if err != nil || len(collected) != 1 {
+		t.Fatalf("Collect() = %v, %v", collected, err)
+	}
+
+	out := t.TempDir()
+	dest, err := artifact.Extract(collected[0], out)
+	if err != nil {
+		t.Fatalf("Extract() 失败: %v", err)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "<ok/>" {
+		t.Errorf("取出的内容 = %q", data)
+	}
+
+	// 保存的产物被修改后拒绝取出
+	writeFile(t, collected[0].Path, "tampered")
+	if _, err := artifact.Extract(collected[0], out); err == nil {
+		t.Error("校验和不一致时应返回错误")
+	}
+}
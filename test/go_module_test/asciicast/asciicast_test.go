@@ -0,0 +1,151 @@
+package asciicast_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aliancn/logcmd/internal/asciicast"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := asciicast.NewWriter(&buf, asciicast.Header{Width: 120, Height: 40, Command: "make build"})
+	if err := w.Start(time.Now()); err != nil {
+		t.Fatalf("Start() 失败: %v", err)
+	}
+
+	// "中" 的 UTF-8 编码被拆到两次写入中，应合并为一个完整字符
+	stream := w.Stream()
+	stream.Write([]byte("hello \xe4\xb8"))
+	stream.Write([]byte("\xad\n"))
+	w.Marker("phase 2")
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() 失败: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("期望 1 行文件头和 3 个事件, got %d: %q", len(lines), buf.String())
+	}
+
+	var header asciicast.Header
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		t.Fatalf("解析文件头失败: %v", err)
+	}
+	if header.Version != 2 || header.Width != 120 || header.Height != 40 || header.Command != "make build" {
+		t.Errorf("文件头不正确: %+v", header)
+	}
+	if header.Timestamp == 0 {
+		t.Error("文件头应包含开始时间")
+	}
+
+	var event []interface{}
+	if err := json.Unmarshal([]byte(lines[2]), &event); err != nil {
+		t.Fatalf("解析事件失败: %v", err)
+	}
+	if event[1] != "o" || event[2] != "中\n" {
+		t.Errorf("跨写入的字符应合并输出, got %v", event)
+	}
+	if !strings.Contains(lines[3], `"m", "phase 2"`) {
+		t.Errorf("标记事件不正确: %s", lines[3])
+	}
+}
+
+func TestWriter_Limit(t *testing.T) {
+	var buf bytes.Buffer
+	w := asciicast.NewWriter(&buf, asciicast.Header{})
+	w.Limit(10)
+	w.Start(time.Now())
+
+	stream := w.Stream()
+	stream.Write([]byte("12345\n"))
+	stream.Write([]byte("67890\n"))
+	stream.Write([]byte("more\n"))
+	w.Marker("done")
+	w.Flush()
+
+	cast := buf.String()
+	if !strings.Contains(cast, `"12345\n"`) || strings.Contains(cast, "67890") || strings.Contains(cast, "more") {
+		t.Errorf("超过上限后不应再录制输出: %q", cast)
+	}
+	if !strings.Contains(cast, "输出超过大小上限") || !strings.Contains(cast, `"m", "done"`) {
+		t.Errorf("超过上限时应写入提示，标记不受限制: %q", cast)
+	}
+}
+
+func TestWriter_DefaultSize(t *testing.T) {
+	var buf bytes.Buffer
+	w := asciicast.NewWriter(&buf, asciicast.Header{})
+	w.Start(time.Now())
+	w.Flush()
+
+	var header asciicast.Header
+	if err := json.Unmarshal(buf.Bytes(), &header); err != nil {
+		t.Fatalf("解析文件头失败: %v", err)
+	}
+	if header.Width != asciicast.DefaultWidth || header.Height != asciicast.DefaultHeight {
+		t.Errorf("未指定终端大小时应使用默认值, got %dx%d", header.Width, header.Height)
+	}
+}
+
+func TestPlay(t *testing.T) {
+	cast := `{"version": 2, "width": 80, "height": 24}
+[0.1, "o", "hello "]
+[0.5, "m", "phase"]
+[2.0, "o", "world\r\n"]
+`
+	var out bytes.Buffer
+	start := time.Now()
+	header, err := asciicast.Play(context.Background(), strings.NewReader(cast), &out, asciicast.PlayOptions{
+		Speed:     4,
+		IdleLimit: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Play() 失败: %v", err)
+	}
+	if header.Width != 80 {
+		t.Errorf("Width = %d, want 80", header.Width)
+	}
+	if out.String() != "hello world\r\n" {
+		t.Errorf("回放输出 = %q", out.String())
+	}
+	// 4 倍速下原始间隔为 0.025s、0.1s、0.375s，最后一段被 idle limit 限制为 0.1s
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("回放耗时 %v 不符合速度与等待上限", elapsed)
+	}
+}
+
+func TestPlay_Cancel(t *testing.T) {
+	cast := `{"version": 2, "width": 80, "height": 24}
+[0.0, "o", "first"]
+[30.0, "o", "never"]
+`
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var out bytes.Buffer
+	_, err := asciicast.Play(ctx, strings.NewReader(cast), &out, asciicast.PlayOptions{})
+	if err != context.DeadlineExceeded {
+		t.Errorf("取消后应返回 ctx.Err(), got %v", err)
+	}
+	if out.String() != "first" {
+		t.Errorf("取消前的输出 = %q", out.String())
+	}
+}
+
+func TestPlay_InvalidVersion(t *testing.T) {
+	_, err := asciicast.Play(context.Background(), strings.NewReader(`{"version": 1}`+"\n"), &bytes.Buffer{}, asciicast.PlayOptions{})
+	if err == nil {
+		t.Error("不支持的版本应返回错误")
+	}
+}
+
+func TestPathFor(t *testing.T) {
+	if got := asciicast.PathFor("/logs/build.log"); got != "/logs/build.log.cast" {
+		t.Errorf("PathFor() = %q", got)
+	}
+}
@@ -0,0 +1,150 @@
+package charset_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aliancn/logcmd/internal/charset"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func encodeGBK(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatalf("GBK 编码失败: %v", err)
+	}
+	return data
+}
+
+// decode 按 chunk 大小分多次写入，返回转换后的内容
+func decode(t *testing.T, d *charset.Decoder, input []byte, chunk int) string {
+	t.Helper()
+	var out bytes.Buffer
+	w := d.NewWriter(&out)
+	for len(input) > 0 {
+		n := chunk
+		if n > len(input) {
+			n = len(input)
+		}
+		if _, err := w.Write(input[:n]); err != nil {
+			t.Fatalf("Write() 失败: %v", err)
+		}
+		input = input[n:]
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() 失败: %v", err)
+	}
+	return out.String()
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"GBK":        charset.GBK,
+		"cp936":      charset.GBK,
+		"UTF8":       charset.UTF8,
+		"ISO-8859-1": charset.Latin1,
+		"auto":       charset.Auto,
+	}
+	for input, want := range tests {
+		got, err := charset.Normalize(input)
+		if err != nil || got != want {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+	if _, err := charset.Normalize("ebcdic"); err == nil {
+		t.Error("不支持的编码应返回错误")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	latin1, _ := charmap.ISO8859_1.NewEncoder().Bytes([]byte("Café déjà vu, naïve façade"))
+	gb18030, _ := simplifiedchinese.GB18030.NewEncoder().Bytes([]byte("表情 😀"))
+
+	tests := []struct {
+		name   string
+		sample []byte
+		want   string
+	}{
+		{"ascii", []byte("plain text"), charset.UTF8},
+		{"utf-8", []byte("编译成功"), charset.UTF8},
+		{"utf-8 截断", []byte("编译成功")[:7], charset.UTF8},
+		{"gbk", encodeGBK(t, "编译失败：找不到文件"), charset.GBK},
+		{"gb18030", gb18030, charset.GB18030},
+		{"latin1", latin1, charset.Latin1},
+	}
+	for _, tt := range tests {
+		if got := charset.Detect(tt.sample); got != tt.want {
+			t.Errorf("%s: Detect() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriterExplicitEncoding(t *testing.T) {
+	input := encodeGBK(t, "开始构建\n错误: 找不到模块 foo\n")
+	for _, chunk := range []int{1, 3, 4096} {
+		d, err := charset.NewDecoder("gbk")
+		if err != nil {
+			t.Fatalf("NewDecoder() 失败: %v", err)
+		}
+		if got, want := decode(t, d, input, chunk), "开始构建\n错误: 找不到模块 foo\n"; got != want {
+			t.Errorf("chunk=%d: got %q, want %q", chunk, got, want)
+		}
+		if d.Encoding() != charset.GBK {
+			t.Errorf("Encoding() = %q, want gbk", d.Encoding())
+		}
+	}
+}
+
+func TestWriterAutoDetect(t *testing.T) {
+	text := "step 1\n编译失败：找不到头文件 stdio.h，请检查包含路径\n"
+	input := encodeGBK(t, text)
+
+	d, err := charset.NewDecoder(charset.Auto)
+	if err != nil {
+		t.Fatalf("NewDecoder() 失败: %v", err)
+	}
+	if got := decode(t, d, input, 5); got != text {
+		t.Errorf("got %q, want %q", got, text)
+	}
+	if d.Encoding() != charset.GBK {
+		t.Errorf("Encoding() = %q, want gbk", d.Encoding())
+	}
+
+	// UTF-8 输出原样保留
+	d, _ = charset.NewDecoder(charset.Auto)
+	if got := decode(t, d, []byte(text), 5); got != text {
+		t.Errorf("UTF-8 输出被修改: %q", got)
+	}
+	if d.Encoding() != charset.UTF8 {
+		t.Errorf("Encoding() = %q, want utf-8", d.Encoding())
+	}
+
+	// 全是 ASCII 时不做判断
+	d, _ = charset.NewDecoder(charset.Auto)
+	if got := decode(t, d, []byte("ok\n"), 1); got != "ok\n" {
+		t.Errorf("got %q", got)
+	}
+	if d.Encoding() != "" {
+		t.Errorf("Encoding() = %q, want empty", d.Encoding())
+	}
+}
+
+func TestWriterSharedDetection(t *testing.T) {
+	d, _ := charset.NewDecoder(charset.Auto)
+
+	var stdout, stderr bytes.Buffer
+	out := d.NewWriter(&stdout)
+	errw := d.NewWriter(&stderr)
+
+	out.Write(encodeGBK(t, "输出"))
+	out.Flush()
+	// 另一个输出流的样本很短，沿用已识别的编码
+	errw.Write(encodeGBK(t, "错"))
+	errw.Flush()
+
+	if stdout.String() != "输出" || stderr.String() != "错" {
+		t.Errorf("stdout=%q stderr=%q", stdout.String(), stderr.String())
+	}
+}
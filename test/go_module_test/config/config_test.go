@@ -171,3 +171,25 @@ func TestConfigWithNilTimeZone(t *testing.T) {
 
 	cfg.GetLogFilePath()
 }
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"0":      0,
+		"4096":   4096,
+		"512K":   512 << 10,
+		"100MB":  100 << 20,
+		"1.5GiB": 3 << 29,
+		" 2 m ":  2 << 20,
+	}
+	for input, want := range tests {
+		got, err := config.ParseSize(input)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "MB", "-1K", "ten"} {
+		if _, err := config.ParseSize(input); err == nil {
+			t.Errorf("ParseSize(%q) 应返回错误", input)
+		}
+	}
+}
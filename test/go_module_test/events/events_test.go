@@ -0,0 +1,92 @@
+package events_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aliancn/logcmd/internal/events"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    events.Event
+		wantErr bool
+	}{
+		{line: "compile started\n", want: events.Event{Type: events.TypeMark, Text: "compile started"}},
+		{line: `{"type":"metric","key":"tests","value":"42"}`, want: events.Event{Type: events.TypeMetric, Key: "tests", Value: "42"}},
+		{line: `{"type":"warning","text":"slow"}`, want: events.Event{Type: events.TypeWarning, Text: "slow"}},
+		{line: `{"text":"no type"}`, want: events.Event{Type: events.TypeMark, Text: "no type"}},
+		{line: `{"type":"metric"}`, wantErr: true},
+		{line: `{"type":"unknown","text":"x"}`, wantErr: true},
+		{line: "   ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := events.Parse([]byte(tt.line))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q) 应返回错误", tt.line)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v", tt.line, got, err, tt.want)
+		}
+	}
+}
+
+func TestEventString(t *testing.T) {
+	tests := map[string]events.Event{
+		"标记: build":          {Type: events.TypeMark, Text: "build"},
+		"指标: tests=42":       {Type: events.TypeMetric, Key: "tests", Value: "42"},
+		"指标: rss=10 (MiB)":   {Type: events.TypeMetric, Key: "rss", Value: "10", Text: "MiB"},
+		"警告: cache disabled": {Type: events.TypeWarning, Text: "cache disabled"},
+	}
+	for want, ev := range tests {
+		if got := ev.String(); got != want {
+			t.Errorf("String() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestListenAndSend(t *testing.T) {
+	var mu sync.Mutex
+	var received []events.Event
+	listener, err := events.Listen(time.Now(), func(ev events.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, ev)
+	})
+	if err != nil {
+		t.Fatalf("Listen() 失败: %v", err)
+	}
+
+	if err := events.Send(listener.Path(), events.Event{Type: events.TypeMark, Text: "phase 1"}); err != nil {
+		t.Fatalf("Send() 失败: %v", err)
+	}
+	if err := events.Send(listener.Path(), events.Event{Type: events.TypeMetric, Key: "n", Value: "1"}); err != nil {
+		t.Fatalf("Send() 失败: %v", err)
+	}
+	if err := events.Send(listener.Path(), events.Event{Type: events.TypeMetric}); err == nil {
+		t.Error("无效事件应在发送前返回错误")
+	}
+
+	// Close 会处理已经发出但尚未读取的事件
+	if err := listener.Close(); err != nil {
+		t.Fatalf("Close() 失败: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 {
+		t.Fatalf("期望收到 2 条事件, got %d: %+v", len(received), received)
+	}
+	if received[0].Text != "phase 1" || received[1].Key != "n" {
+		t.Errorf("事件内容不正确: %+v", received)
+	}
+	if err := events.Send(listener.Path(), events.Event{Type: events.TypeMark, Text: "late"}); err == nil {
+		t.Error("关闭后发送应返回错误")
+	}
+}
@@ -0,0 +1,138 @@
+package execctx_test
+
+import (
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aliancn/logcmd/internal/execctx"
+)
+
+func TestHeaderLinesRoundTrip(t *testing.T) {
+	info := &execctx.Info{
+		User:          "alice",
+		UID:           "1000",
+		Hostname:      "build-01",
+		TTY:           "/dev/pts/3",
+		ParentProcess: "bash",
+		GitBranch:     "feature/login",
+		GitCommit:     "0123456789abcdef0123456789abcdef01234567",
+		GitDirty:      true,
+	}
+
+	header := "\n" + strings.Repeat("#", 80) + "\n# LogCmd - 命令执行日志\n# 时间: 2024-01-15 10:00:00\n# 命令: make [build]\n"
+	header += strings.Join(info.HeaderLines(), "\n") + "\n" + strings.Repeat("#", 80) + "\n\n# 用户: mallory (uid 0)\n"
+
+	parsed, err := execctx.ReadHeader(strings.NewReader(header))
+	if err != nil {
+		t.Fatalf("ReadHeader() 失败: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, info) {
+		t.Errorf("ReadHeader() = %+v, want %+v（不应读取头部之后的输出）", parsed, info)
+	}
+}
+
+func TestHeaderLinesOmitEmptyFields(t *testing.T) {
+	lines := (&execctx.Info{User: "cron", UID: "0", GitCommit: "abc"}).HeaderLines()
+	want := []string{"# 用户: cron (uid 0)", "# Git 提交: abc"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("HeaderLines() = %q, want %q", lines, want)
+	}
+
+	var nilInfo *execctx.Info
+	if lines := nilInfo.HeaderLines(); lines != nil {
+		t.Errorf("nil Info 不应输出头部, got %q", lines)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	info := &execctx.Info{User: "alice", GitBranch: "main", GitDirty: true}
+	data, err := execctx.Encode(info)
+	if err != nil {
+		t.Fatalf("Encode() 失败: %v", err)
+	}
+	decoded, err := execctx.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() 失败: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, info) {
+		t.Errorf("Decode(Encode()) = %+v, want %+v", decoded, info)
+	}
+
+	if decoded, err := execctx.Decode(""); err != nil || decoded != nil {
+		t.Errorf("空字符串应返回 nil, got %+v, %v", decoded, err)
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	info := &execctx.Info{User: "alice", Hostname: "build-01", GitBranch: "main"}
+
+	tests := []struct {
+		filter execctx.Filter
+		want   bool
+	}{
+		{execctx.Filter{}, true},
+		{execctx.Filter{Branch: "main"}, true},
+		{execctx.Filter{Branch: "main", User: "alice", Host: "build-01"}, true},
+		{execctx.Filter{Branch: "dev"}, false},
+		{execctx.Filter{User: "bob", Branch: "main"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(info); got != tt.want {
+			t.Errorf("%+v.Match() = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	if (execctx.Filter{Branch: "main"}).Match(nil) {
+		t.Error("没有执行上下文的记录不应匹配非空条件")
+	}
+	if got := (execctx.Filter{User: "alice", Branch: "main"}).String(); got != "用户 alice, 分支 main" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestCaptureGitState(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("未安装 git")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v 失败: %v\n%s", args, err, out)
+		}
+	}
+
+	info := execctx.Capture(dir)
+	if info.User == "" || info.Hostname == "" {
+		t.Errorf("应记录用户和主机名, got %+v", info)
+	}
+	if info.GitBranch != "" || info.GitCommit != "" {
+		t.Errorf("非 git 仓库不应记录 git 状态, got %+v", info)
+	}
+
+	git("init", "-q", "-b", "trunk")
+	git("commit", "-q", "--allow-empty", "-m", "init")
+	info = execctx.Capture(dir)
+	if info.GitBranch != "trunk" || len(info.GitCommit) != 40 || info.GitDirty {
+		t.Errorf("干净仓库的 git 状态 = %+v", info)
+	}
+
+	if err := os.WriteFile(dir+"/file.txt", []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "file.txt")
+	if info = execctx.Capture(dir); !info.GitDirty {
+		t.Error("暂存了修改的仓库应标记为 dirty")
+	}
+
+	git("checkout", "-q", "--detach")
+	if info = execctx.Capture(dir); info.GitBranch != "" || info.GitCommit == "" {
+		t.Errorf("分离 HEAD 时分支应为空, got %+v", info)
+	}
+}
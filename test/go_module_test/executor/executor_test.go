@@ -3,14 +3,30 @@ package executor_test
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"os"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/aliancn/logcmd/internal/asciicast"
+	"github.com/aliancn/logcmd/internal/envsnap"
+	"github.com/aliancn/logcmd/internal/events"
 	"github.com/aliancn/logcmd/internal/executor"
+	"github.com/aliancn/logcmd/internal/logrecord"
+	"github.com/aliancn/logcmd/internal/redact"
+	"github.com/aliancn/logcmd/internal/sandbox"
 )
 
+// TestMain 让测试二进制可以作为沙箱辅助进程被重新执行
+func TestMain(m *testing.M) {
+	sandbox.Init()
+	os.Exit(m.Run())
+}
+
 func newTestExecutor(logFile io.Writer) *executor.Executor {
 	return executor.New(logFile, io.Discard, io.Discard)
 }
@@ -299,3 +315,818 @@ func TestResult_Fields(t *testing.T) {
 		t.Error("Success 应该为 true")
 	}
 }
+
+func TestExecute_PTY(t *testing.T) {
+	if _, err := os.Stat("/dev/ptmx"); err != nil {
+		t.Skip("当前环境不支持伪终端")
+	}
+
+	var buf bytes.Buffer
+	exec := executor.NewWithOptions(&buf, io.Discard, io.Discard, executor.Options{PTY: true})
+
+	result, err := exec.Execute(context.Background(), "sh", "-c", "test -t 1 && echo is-tty")
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+
+	if !result.PTY {
+		t.Error("PTY 应该为 true")
+	}
+	if !result.Success {
+		t.Errorf("命令应该在伪终端中成功执行, ExitCode = %d", result.ExitCode)
+	}
+	if !strings.Contains(buf.String(), "is-tty") {
+		t.Errorf("日志应该包含伪终端中的输出, got %q", buf.String())
+	}
+}
+
+func TestExecute_StructuredRecords(t *testing.T) {
+	var logBuf, records bytes.Buffer
+	exec := executor.NewWithOptions(&logBuf, io.Discard, io.Discard, executor.Options{Records: &records})
+
+	_, err := exec.Execute(context.Background(), "sh", "-c", "echo out; echo err >&2")
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+
+	streams := make(map[string]string)
+	scanner := logrecord.NewScanner(&records)
+	for scanner.Scan() {
+		record := scanner.Record()
+		if record.Seq <= 0 {
+			t.Errorf("记录序号应该为正数, got %d", record.Seq)
+		}
+		streams[record.Stream] = record.Line
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("读取结构化记录失败: %v", err)
+	}
+
+	if streams[logrecord.StreamStdout] != "out" {
+		t.Errorf("stdout 记录不正确: %q", streams[logrecord.StreamStdout])
+	}
+	if streams[logrecord.StreamStderr] != "err" {
+		t.Errorf("stderr 记录不正确: %q", streams[logrecord.StreamStderr])
+	}
+	if !strings.Contains(logBuf.String(), "out") || !strings.Contains(logBuf.String(), "err") {
+		t.Errorf("日志文件仍应包含原始输出, got %q", logBuf.String())
+	}
+}
+
+// upperFilter 将日志输出转换为大写，用于验证过滤器只作用于日志
+type upperFilter struct {
+	dst executor.FlushWriter
+}
+
+func (u *upperFilter) Write(p []byte) (int, error) {
+	return u.dst.Write(bytes.ToUpper(p))
+}
+
+func (u *upperFilter) Flush() error {
+	return u.dst.Flush()
+}
+
+func TestExecute_LogFilters(t *testing.T) {
+	var logBuf, records, stdout bytes.Buffer
+	exec := executor.NewWithOptions(&logBuf, &stdout, io.Discard, executor.Options{
+		Records: &records,
+		LogFilters: []executor.LogFilter{
+			func(dst executor.FlushWriter) executor.FlushWriter { return &upperFilter{dst: dst} },
+		},
+	})
+
+	if _, err := exec.Execute(context.Background(), "echo", "secret"); err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+
+	if stdout.String() != "secret\n" {
+		t.Errorf("终端输出不应经过过滤器, got %q", stdout.String())
+	}
+	if !strings.Contains(logBuf.String(), "SECRET") || strings.Contains(logBuf.String(), "secret") {
+		t.Errorf("日志应经过过滤器, got %q", logBuf.String())
+	}
+	if !strings.Contains(records.String(), "SECRET") {
+		t.Errorf("结构化记录应经过过滤器, got %q", records.String())
+	}
+}
+
+func TestExecute_MaxLogSize(t *testing.T) {
+	var logBuf, stdout bytes.Buffer
+	exec := executor.NewWithOptions(&logBuf, &stdout, io.Discard, executor.Options{
+		MaxLogSize:  1000,
+		LogTailSize: 200,
+	})
+
+	// 输出 1..2000，每行至少 2 字节，总长远超上限
+	result, err := exec.Execute(context.Background(), "seq", "1", "2000")
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+	exec.WriteMetadata(result)
+
+	if stdout.Len() != 8893 {
+		t.Errorf("终端输出不应被截断, got %d 字节", stdout.Len())
+	}
+	if result.TruncatedBytes <= 0 {
+		t.Fatalf("TruncatedBytes = %d, want > 0", result.TruncatedBytes)
+	}
+
+	log := logBuf.String()
+	head, tail, ok := strings.Cut(log, "[logcmd] 日志超过大小上限")
+	if !ok {
+		t.Fatalf("日志缺少截断提示: %q", log)
+	}
+	if !strings.HasPrefix(head, "1\n2\n3\n") || len(head) != 800+1 {
+		t.Errorf("应保留开头 800 字节, got %d 字节", len(head))
+	}
+	if !strings.Contains(tail, "\n1999\n2000\n") {
+		t.Errorf("应保留末尾输出: %q", tail)
+	}
+	if !strings.Contains(log, fmt.Sprintf("截断输出: %d 字节", result.TruncatedBytes)) || !strings.Contains(log, "退出码: 0") {
+		t.Errorf("截断后仍应写入完整的元数据: %q", log)
+	}
+
+	// 输出字节数 = 保留的开头 + 截断部分 + 保留的末尾
+	_, kept, _ := strings.Cut(tail, "\n")
+	kept, _, _ = strings.Cut(kept, "\n====")
+	if got := int64(800) + result.TruncatedBytes + int64(len(kept)); got != 8893 {
+		t.Errorf("保留与截断的字节数之和 = %d, want 8893", got)
+	}
+}
+
+func TestExecute_MaxLogSizeNotReached(t *testing.T) {
+	var logBuf bytes.Buffer
+	exec := executor.NewWithOptions(&logBuf, io.Discard, io.Discard, executor.Options{MaxLogSize: 1000})
+
+	result, err := exec.Execute(context.Background(), "seq", "1", "200")
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+	if result.TruncatedBytes != 0 || logBuf.Len() != 692 {
+		t.Errorf("未超过上限时应完整写入, truncated=%d len=%d", result.TruncatedBytes, logBuf.Len())
+	}
+}
+
+func TestExecute_OutputPreview(t *testing.T) {
+	exec := executor.NewWithOptions(io.Discard, io.Discard, io.Discard, executor.Options{PreviewLength: 8})
+
+	script := `printf 'start\n'; seq 1 50; printf 'warning: slow\nError: disk full\nfatal: later\n' >&2; exit 3`
+	result, err := exec.Execute(context.Background(), "sh", "-c", script)
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+
+	if !strings.HasPrefix(result.StdoutPreview, "start\n1\n") || !strings.HasSuffix(result.StdoutPreview, "\n49\n50\n") {
+		t.Errorf("StdoutPreview 应包含开头和末尾: %q", result.StdoutPreview)
+	}
+	if !strings.Contains(result.StdoutPreview, "\n...\n") {
+		t.Errorf("超过预览长度时应以 ... 分隔开头和末尾: %q", result.StdoutPreview)
+	}
+	if result.StderrPreview == "" {
+		t.Error("StderrPreview 不应为空")
+	}
+	if result.ErrorExcerpt != "Error: disk full" {
+		t.Errorf("ErrorExcerpt = %q, want 第一条错误行", result.ErrorExcerpt)
+	}
+}
+
+func TestExecute_ErrorExcerptFallback(t *testing.T) {
+	exec := executor.NewWithOptions(io.Discard, io.Discard, io.Discard, executor.Options{})
+
+	result, err := exec.Execute(context.Background(), "sh", "-c", `echo building; printf '\033[31mno such target\033[0m\n' >&2; exit 2`)
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+	if result.StdoutPreview != "" || result.StderrPreview != "" {
+		t.Errorf("PreviewLength 为 0 时不应记录预览: %q %q", result.StdoutPreview, result.StderrPreview)
+	}
+	if result.ErrorExcerpt != "no such target" {
+		t.Errorf("失败且没有错误行时应使用标准错误最后一行, got %q", result.ErrorExcerpt)
+	}
+
+	result, err = exec.Execute(context.Background(), "sh", "-c", "echo done")
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+	if result.ErrorExcerpt != "" {
+		t.Errorf("成功且没有错误行时不应有摘要, got %q", result.ErrorExcerpt)
+	}
+}
+
+func TestExecute_OutputModes(t *testing.T) {
+	script := `echo out; echo err >&2; exit "$1"`
+	tests := []struct {
+		mode       string
+		exitCode   string
+		wantStdout string
+		wantStderr string
+	}{
+		{executor.OutputQuiet, "1", "", ""},
+		{executor.OutputSummary, "1", "", ""},
+		{executor.OutputOnFailure, "0", "", ""},
+		{executor.OutputOnFailure, "1", "out\n", "err\n"},
+	}
+
+	for _, tt := range tests {
+		var logBuf, stdout, stderr bytes.Buffer
+		exec := executor.NewWithOptions(&logBuf, &stdout, &stderr, executor.Options{Output: tt.mode})
+		if _, err := exec.Execute(context.Background(), "sh", "-c", script, "sh", tt.exitCode); err != nil {
+			t.Fatalf("%s: Execute() 失败: %v", tt.mode, err)
+		}
+		if stdout.String() != tt.wantStdout || stderr.String() != tt.wantStderr {
+			t.Errorf("%s (exit %s): stdout=%q stderr=%q, want %q %q",
+				tt.mode, tt.exitCode, stdout.String(), stderr.String(), tt.wantStdout, tt.wantStderr)
+		}
+		if !strings.Contains(logBuf.String(), "out") || !strings.Contains(logBuf.String(), "err") {
+			t.Errorf("%s: 日志应完整记录输出: %q", tt.mode, logBuf.String())
+		}
+	}
+}
+
+func TestParseOutputMode(t *testing.T) {
+	for input, want := range map[string]string{
+		"":           executor.OutputNormal,
+		"normal":     executor.OutputNormal,
+		"QUIET":      executor.OutputQuiet,
+		"on_failure": executor.OutputOnFailure,
+		"summary":    executor.OutputSummary,
+	} {
+		got, err := executor.ParseOutputMode(input)
+		if err != nil || got != want {
+			t.Errorf("ParseOutputMode(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+	if _, err := executor.ParseOutputMode("silent"); err == nil {
+		t.Error("未知的输出模式应返回错误")
+	}
+}
+
+func TestCapture(t *testing.T) {
+	var logBuf, stdout bytes.Buffer
+	exec := executor.NewWithOptions(&logBuf, &stdout, io.Discard, executor.Options{PreviewLength: 100})
+
+	input := strings.NewReader("connecting\nerror: permission denied\n")
+	result, err := exec.Capture(context.Background(), "deploy", input, 2)
+	if err != nil {
+		t.Fatalf("Capture() 失败: %v", err)
+	}
+	exec.WriteMetadata(result)
+
+	if stdout.String() != "connecting\nerror: permission denied\n" {
+		t.Errorf("输入应回显到 stdout, got %q", stdout.String())
+	}
+	if result.Command != "deploy" || result.ExitCode != 2 || result.Success || result.Status != executor.StatusFailed {
+		t.Errorf("结果不正确: command=%q exit=%d success=%v status=%s", result.Command, result.ExitCode, result.Success, result.Status)
+	}
+	if result.ErrorExcerpt != "error: permission denied" {
+		t.Errorf("ErrorExcerpt = %q", result.ErrorExcerpt)
+	}
+	log := logBuf.String()
+	if !strings.Contains(log, "connecting\n") || !strings.Contains(log, "退出码: 2") {
+		t.Errorf("日志应包含输入和元数据: %q", log)
+	}
+
+	result, err = exec.Capture(context.Background(), "deploy", strings.NewReader("ok\n"), 0)
+	if err != nil {
+		t.Fatalf("Capture() 失败: %v", err)
+	}
+	if !result.Success || result.Status != executor.StatusSuccess {
+		t.Errorf("退出码为 0 时应记录为成功, got %s", result.Status)
+	}
+}
+
+// eventSender 收到命令输出的 LOGCMD_EVENTS 路径后发送一条事件
+type eventSender struct {
+	t     *testing.T
+	sent  bool
+	delay time.Duration // 收到路径后延迟发送，让输出先写入日志过滤器
+}
+
+func (s *eventSender) Write(p []byte) (int, error) {
+	line, _, _ := strings.Cut(string(p), "\n")
+	path := strings.TrimSpace(line)
+	if !s.sent && strings.HasPrefix(path, "/") {
+		s.sent = true
+		send := func() {
+			if err := events.Send(path, events.Event{Type: events.TypeMark, Text: "phase 2"}); err != nil {
+				s.t.Errorf("Send() 失败: %v", err)
+			}
+		}
+		if s.delay > 0 {
+			time.AfterFunc(s.delay, send)
+		} else {
+			send()
+		}
+	}
+	return len(p), nil
+}
+
+// holdFilter 缓存全部日志输出，Sync 时只写出完整的行，Flush 时全部写出，模拟等待完整行的脱敏和转换
+type holdFilter struct {
+	dst executor.FlushWriter
+	buf bytes.Buffer
+}
+
+func (h *holdFilter) Write(p []byte) (int, error) {
+	return h.buf.Write(p)
+}
+
+func (h *holdFilter) Flush() error {
+	if _, err := h.dst.Write(h.buf.Bytes()); err != nil {
+		return err
+	}
+	h.buf.Reset()
+	return h.dst.Flush()
+}
+
+func (h *holdFilter) Sync() error {
+	end := bytes.LastIndexByte(h.buf.Bytes(), '\n') + 1
+	if _, err := h.dst.Write(h.buf.Next(end)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func TestExecute_Events(t *testing.T) {
+	var logBuf bytes.Buffer
+	sender := &eventSender{t: t}
+	exec := executor.NewWithOptions(&logBuf, sender, io.Discard, executor.Options{Events: true})
+
+	// 第一行输出事件通道路径，测试在收到后发送事件，命令等待后再输出下一行
+	result, err := exec.Execute(context.Background(), "sh", "-c", `echo "$LOGCMD_EVENTS"; sleep 0.3; echo after`)
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+
+	if len(result.Events) != 1 || result.Events[0].Text != "phase 2" {
+		t.Fatalf("Events = %+v, want 一条 phase 2 标记", result.Events)
+	}
+	if result.Events[0].Offset <= 0 {
+		t.Errorf("事件偏移应大于 0, got %v", result.Events[0].Offset)
+	}
+	log := logBuf.String()
+	mark := strings.Index(log, "标记: phase 2")
+	if mark < 0 || mark > strings.Index(log, "after") {
+		t.Errorf("事件应按到达时间插入日志: %q", log)
+	}
+}
+
+func TestExecute_EventsFlushOutput(t *testing.T) {
+	var logBuf bytes.Buffer
+	sender := &eventSender{t: t, delay: 100 * time.Millisecond}
+	exec := executor.NewWithOptions(&logBuf, sender, io.Discard, executor.Options{
+		Events: true,
+		LogFilters: []executor.LogFilter{
+			func(dst executor.FlushWriter) executor.FlushWriter { return &holdFilter{dst: dst} },
+		},
+	})
+
+	_, err := exec.Execute(context.Background(), "sh", "-c", `echo "$LOGCMD_EVENTS"; printf 'pass'; sleep 0.5; echo word; echo after`)
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+
+	// 事件之前的完整行仍缓存在过滤器中，写入事件前应先写出；未结束的行留到换行后写在事件之后
+	log := logBuf.String()
+	mark := strings.Index(log, "标记: phase 2")
+	if mark < 0 || mark < strings.Index(log, "/") || mark > strings.Index(log, "after") {
+		t.Errorf("事件应位于之前的输出之后: %q", log)
+	}
+	if !strings.Contains(log, "\npassword\n") || strings.Index(log, "password") < mark {
+		t.Errorf("未结束的行不应被事件拆开: %q", log)
+	}
+}
+
+func TestExecute_EventsStartNewLine(t *testing.T) {
+	var logBuf bytes.Buffer
+	sender := &eventSender{t: t, delay: 100 * time.Millisecond}
+	exec := executor.NewWithOptions(&logBuf, sender, io.Discard, executor.Options{Events: true})
+
+	_, err := exec.Execute(context.Background(), "sh", "-c", `echo "$LOGCMD_EVENTS"; printf 'pass'; sleep 0.5; echo word`)
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+
+	// 没有过滤器时未结束的行已经写入日志，事件应另起一行
+	if log := logBuf.String(); !strings.Contains(log, "pass\n[logcmd] +") {
+		t.Errorf("事件应单独成行: %q", log)
+	}
+}
+
+func TestExecute_Environment(t *testing.T) {
+	t.Setenv("LOGCMD_TEST_TOKEN", "secret")
+	t.Setenv("LOGCMD_TEST_MODE", "ci")
+	exec := executor.NewWithOptions(io.Discard, io.Discard, io.Discard, executor.Options{
+		Events:      true,
+		Environment: &envsnap.Options{Allow: []string{"LOGCMD_*"}},
+	})
+
+	result, err := exec.Execute(context.Background(), "true")
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+
+	// 快照来自传给命令的环境变量，但不包含 logcmd 为本次运行注入的事件通道
+	want := map[string]string{"LOGCMD_TEST_TOKEN": envsnap.Masked, "LOGCMD_TEST_MODE": "ci"}
+	if !reflect.DeepEqual(result.Environment, want) {
+		t.Errorf("Environment = %v, want %v", result.Environment, want)
+	}
+}
+
+func TestExecute_Recording(t *testing.T) {
+	var logBuf, castBuf bytes.Buffer
+	recording := asciicast.NewWriter(&castBuf, asciicast.Header{Width: 100, Height: 30})
+	exec := executor.NewWithOptions(&logBuf, io.Discard, io.Discard, executor.Options{
+		Output:    executor.OutputQuiet,
+		Recording: recording,
+	})
+
+	// quiet 模式不回显输出，录制仍应包含命令的全部终端输出
+	_, err := exec.Execute(context.Background(), "sh", "-c", "printf 'out\\n'; printf 'err\\n' >&2")
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+	if err := recording.Flush(); err != nil {
+		t.Fatalf("Flush() 失败: %v", err)
+	}
+
+	cast := castBuf.String()
+	if !strings.HasPrefix(cast, `{"version":2,"width":100,"height":30`) {
+		t.Errorf("录制应以文件头开始: %q", cast)
+	}
+	if !strings.Contains(cast, `"o", "out\n"`) || !strings.Contains(cast, `"o", "err\n"`) {
+		t.Errorf("录制应包含 stdout 与 stderr 输出: %q", cast)
+	}
+}
+
+func TestExecute_StdinPassthrough(t *testing.T) {
+	var logBuf, recordBuf bytes.Buffer
+	exec := executor.NewWithOptions(&logBuf, io.Discard, io.Discard, executor.Options{
+		Output:      executor.OutputQuiet,
+		Stdin:       strings.NewReader("hello\nworld\n"),
+		StdinRecord: &recordBuf,
+	})
+
+	result, err := exec.Execute(context.Background(), "cat")
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("输入结束后命令应正常退出，退出码 %d", result.ExitCode)
+	}
+
+	if !strings.Contains(logBuf.String(), "hello\nworld\n") {
+		t.Errorf("命令应读到转发的标准输入: %q", logBuf.String())
+	}
+	if recordBuf.String() != "hello\nworld\n" {
+		t.Errorf("应记录转发的全部输入，实际为 %q", recordBuf.String())
+	}
+}
+
+func TestExecute_StdinStopsReading(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	var recordBuf bytes.Buffer
+	exec := executor.NewWithOptions(io.Discard, io.Discard, io.Discard, executor.Options{
+		Stdin:       r,
+		StdinRecord: &recordBuf,
+	})
+	if _, err := exec.Execute(context.Background(), "true"); err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+
+	// 命令结束后转发 goroutine 应已退出，不会再读走之后的输入
+	if _, err := w.Write([]byte("later\n")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := r.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline() 失败: %v", err)
+	}
+	buf := make([]byte, 16)
+	n, err := r.Read(buf)
+	if err != nil || string(buf[:n]) != "later\n" {
+		t.Errorf("命令结束后的输入应留给调用方读取, got %q, %v", buf[:n], err)
+	}
+	if recordBuf.Len() != 0 {
+		t.Errorf("命令结束后的输入不应被记录: %q", recordBuf.String())
+	}
+}
+
+func TestExecute_NoStdin(t *testing.T) {
+	var buf bytes.Buffer
+	exec := newTestExecutor(&buf)
+
+	// 未设置 Stdin 时命令读取到的是空输入，不会阻塞
+	result, err := exec.Execute(context.Background(), "sh", "-c", "cat; echo done")
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+	if !result.Success || !strings.Contains(buf.String(), "done") {
+		t.Errorf("命令应在空输入下立即结束: %q", buf.String())
+	}
+}
+
+func TestExecute_SandboxDenials(t *testing.T) {
+	if err := sandbox.Supported(); err != nil {
+		t.Skipf("当前环境不支持沙箱: %v", err)
+	}
+
+	outside := t.TempDir()
+	t.Chdir(t.TempDir())
+
+	var logBuf bytes.Buffer
+	profile := &sandbox.Profile{Name: "test"}
+	exec := executor.NewWithOptions(&logBuf, io.Discard, io.Discard, executor.Options{
+		Output:  executor.OutputQuiet,
+		Sandbox: profile,
+	})
+
+	result, err := exec.Execute(context.Background(), "sh", "-c", fmt.Sprintf("echo ok > local.txt && touch %s/a %s/b", outside, outside))
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+	if result.Success {
+		t.Fatal("写入工作目录之外应失败")
+	}
+	if _, err := os.Stat("local.txt"); err != nil {
+		t.Errorf("工作目录应可写: %v", err)
+	}
+	if result.Sandbox != profile || result.SandboxDenialCount != 2 || len(result.SandboxDenials) != 2 {
+		t.Fatalf("应记录沙箱配置和 2 处拒绝，实际为 %v/%d/%v", result.Sandbox, result.SandboxDenialCount, result.SandboxDenials)
+	}
+
+	exec.WriteMetadata(result)
+	if !strings.Contains(logBuf.String(), "沙箱拒绝: 2 处\n  ! touch:") {
+		t.Errorf("日志尾部应列出被拒绝的操作: %q", logBuf.String())
+	}
+}
+
+func TestExecute_MetadataWriter(t *testing.T) {
+	var logBuf, metaBuf bytes.Buffer
+	exec := executor.NewWithOptions(&logBuf, io.Discard, io.Discard, executor.Options{
+		Output:      executor.OutputQuiet,
+		Metadata:    &metaBuf,
+		IdleTimeout: 200 * time.Millisecond,
+	})
+
+	// 看门狗的提示与尾部元数据都不应写入日志
+	result, err := exec.Execute(context.Background(), "sh", "-c", "printf '{\"n\":1}\\n'; sleep 5")
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+	exec.WriteMetadata(result)
+
+	if got := logBuf.String(); got != "{\"n\":1}\n" {
+		t.Errorf("日志应只包含命令输出, got %q", got)
+	}
+	meta := metaBuf.String()
+	if !strings.Contains(meta, "[logcmd] 看门狗") || !strings.Contains(meta, "执行状态: 超时") {
+		t.Errorf("提示和尾部元数据应写入元数据文件: %q", meta)
+	}
+}
+
+func TestExecute_MetadataWriterSkipsLogFilters(t *testing.T) {
+	var logBuf, metaBuf, records bytes.Buffer
+	exec := executor.NewWithOptions(&logBuf, io.Discard, io.Discard, executor.Options{
+		Output:   executor.OutputQuiet,
+		Metadata: &metaBuf,
+		Records:  &records,
+		LogFilters: []executor.LogFilter{
+			func(dst executor.FlushWriter) executor.FlushWriter { return &upperFilter{dst: dst} },
+		},
+	})
+
+	if _, err := exec.Execute(context.Background(), "echo", "secret"); err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+
+	if got := logBuf.String(); got != "secret\n" {
+		t.Errorf("raw 日志应与命令输出一致, got %q", got)
+	}
+	if !strings.Contains(records.String(), "SECRET") {
+		t.Errorf("结构化记录仍应经过过滤器, got %q", records.String())
+	}
+}
+
+func TestExecute_MetadataWriterAppliesRawLogFilters(t *testing.T) {
+	redactor, err := redact.New(nil)
+	if err != nil {
+		t.Fatalf("redact.New() 失败: %v", err)
+	}
+	var logBuf, metaBuf bytes.Buffer
+	exec := executor.NewWithOptions(&logBuf, io.Discard, io.Discard, executor.Options{
+		Output:   executor.OutputQuiet,
+		Metadata: &metaBuf,
+		LogFilters: []executor.LogFilter{
+			func(dst executor.FlushWriter) executor.FlushWriter { return &upperFilter{dst: dst} },
+		},
+		RawLogFilters: []executor.LogFilter{
+			func(dst executor.FlushWriter) executor.FlushWriter { return redactor.NewWriter(dst) },
+		},
+	})
+
+	if _, err := exec.Execute(context.Background(), "echo", "token: Bearer abcdefghijk"); err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+
+	got := logBuf.String()
+	if strings.Contains(got, "abcdefghijk") {
+		t.Errorf("raw 日志仍应脱敏, got %q", got)
+	}
+	if !strings.HasPrefix(got, "token: ") {
+		t.Errorf("raw 日志不应经过 LogFilters, got %q", got)
+	}
+}
+
+func TestExecute_CancelTerminatesProcessGroup(t *testing.T) {
+	var buf bytes.Buffer
+	exec := newTestExecutor(&buf)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// 后台的孙进程持有输出管道，只有整个进程组被终止时命令才会尽快返回
+	start := time.Now()
+	result, err := exec.Execute(ctx, "sh", "-c", "sleep 30 & sleep 30")
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("取消后应终止整个进程组, 实际耗时 %v", elapsed)
+	}
+	if result.Success {
+		t.Error("被取消的命令不应标记为成功")
+	}
+	if result.Signal != "SIGTERM" {
+		t.Errorf("Signal = %q, want SIGTERM", result.Signal)
+	}
+}
+
+func TestExecute_GracePeriodEscalatesToKill(t *testing.T) {
+	var buf bytes.Buffer
+	exec := executor.NewWithOptions(&buf, io.Discard, io.Discard, executor.Options{GracePeriod: 200 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	result, err := exec.Execute(ctx, "sh", "-c", "trap '' TERM; sleep 30")
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+
+	if result.Signal != "SIGKILL" {
+		t.Errorf("忽略 SIGTERM 的命令应在宽限期后被 SIGKILL 终止, Signal = %q", result.Signal)
+	}
+
+	var meta bytes.Buffer
+	executor.New(&meta, io.Discard, io.Discard).WriteMetadata(result)
+	if !strings.Contains(meta.String(), "终止信号: SIGKILL") {
+		t.Errorf("元数据应包含终止信号, got %q", meta.String())
+	}
+}
+
+func TestExecute_Timeout(t *testing.T) {
+	var buf bytes.Buffer
+	exec := executor.NewWithOptions(&buf, io.Discard, io.Discard, executor.Options{Timeout: 100 * time.Millisecond})
+
+	result, err := exec.Execute(context.Background(), "sleep", "30")
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+
+	if result.Status != executor.StatusTimeout {
+		t.Errorf("Status = %q, want %q", result.Status, executor.StatusTimeout)
+	}
+	if result.Success {
+		t.Error("超时的命令不应标记为成功")
+	}
+	if result.Signal != "SIGTERM" {
+		t.Errorf("Signal = %q, want SIGTERM", result.Signal)
+	}
+	if !strings.Contains(buf.String(), "命令执行超过") {
+		t.Errorf("日志应包含超时提示, got %q", buf.String())
+	}
+
+	exec.WriteMetadata(result)
+	if !strings.Contains(buf.String(), "执行状态: 超时") {
+		t.Errorf("元数据应记录超时状态, got %q", buf.String())
+	}
+}
+
+func TestExecute_StatusWithinTimeout(t *testing.T) {
+	exec := executor.NewWithOptions(io.Discard, io.Discard, io.Discard, executor.Options{Timeout: 10 * time.Second})
+
+	result, err := exec.Execute(context.Background(), "true")
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+	if result.Status != executor.StatusSuccess {
+		t.Errorf("Status = %q, want %q", result.Status, executor.StatusSuccess)
+	}
+}
+
+func TestExecute_IdleTimeout(t *testing.T) {
+	var buf bytes.Buffer
+	exec := executor.NewWithOptions(&buf, io.Discard, io.Discard, executor.Options{IdleTimeout: 200 * time.Millisecond})
+
+	// 持续输出期间不应触发看门狗，输出停止后才终止
+	start := time.Now()
+	result, err := exec.Execute(context.Background(), "sh", "-c", "for i in 1 2 3 4; do echo tick; sleep 0.1; done; sleep 30")
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+
+	if result.Reason != executor.ReasonIdleTimeout {
+		t.Errorf("Reason = %q, want %q", result.Reason, executor.ReasonIdleTimeout)
+	}
+	if result.Status != executor.StatusTimeout {
+		t.Errorf("Status = %q, want %q", result.Status, executor.StatusTimeout)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("看门狗在命令仍有输出时触发, elapsed = %v", elapsed)
+	}
+	if strings.Count(buf.String(), "tick") != 4 {
+		t.Errorf("日志应包含全部输出, got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "看门狗") {
+		t.Errorf("日志应包含看门狗提示, got %q", buf.String())
+	}
+
+	exec.WriteMetadata(result)
+	if !strings.Contains(buf.String(), "终止原因: idle-timeout") {
+		t.Errorf("元数据应记录终止原因, got %q", buf.String())
+	}
+}
+
+func TestExecute_ResourceUsage(t *testing.T) {
+	var buf bytes.Buffer
+	exec := executor.NewWithOptions(&buf, io.Discard, io.Discard, executor.Options{})
+
+	result, err := exec.Execute(context.Background(), "sh", "-c", "i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done")
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+	if result.Usage == nil {
+		t.Fatal("Usage 不应为 nil")
+	}
+	if result.Usage.CPU() <= 0 {
+		t.Errorf("CPU 时间应大于 0, got %v", result.Usage.CPU())
+	}
+	if result.Usage.MaxRSS <= 0 {
+		t.Errorf("MaxRSS 应大于 0, got %d", result.Usage.MaxRSS)
+	}
+
+	exec.WriteMetadata(result)
+	for _, want := range []string{"CPU 时间: 用户", "最大内存:", "块 I/O:", "上下文切换:"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("元数据应包含 %q, got %q", want, buf.String())
+		}
+	}
+}
+
+func TestExecute_SampleInterval(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("资源采样依赖 /proc")
+	}
+
+	exec := executor.NewWithOptions(io.Discard, io.Discard, io.Discard, executor.Options{SampleInterval: 50 * time.Millisecond})
+	result, err := exec.Execute(context.Background(), "sh", "-c", "sleep 0.5 & sleep 0.5; wait")
+	if err != nil {
+		t.Fatalf("Execute() 失败: %v", err)
+	}
+
+	if len(result.Samples) < 3 {
+		t.Fatalf("应至少采样 3 次, got %d", len(result.Samples))
+	}
+	sample := result.Samples[0]
+	if sample.Processes < 2 {
+		t.Errorf("采样应覆盖整个进程组, Processes = %d", sample.Processes)
+	}
+	if sample.RSS <= 0 || sample.Offset <= 0 {
+		t.Errorf("采样数据无效: %+v", sample)
+	}
+	if executor.PeakRSS(result.Samples) < sample.RSS {
+		t.Error("PeakRSS 应不小于任一样本")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		512:             "512 B",
+		2048:            "2.0 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 30:         "3.0 GiB",
+	}
+	for n, want := range tests {
+		if got := executor.FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
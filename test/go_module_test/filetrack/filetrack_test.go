@@ -0,0 +1,112 @@
+package filetrack_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aliancn/logcmd/internal/filetrack"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshotDiff(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "src/main.go"), "package main")
+	writeFile(t, filepath.Join(root, "old.txt"), "old")
+	writeFile(t, filepath.Join(root, "touched.txt"), "same")
+	writeFile(t, filepath.Join(root, ".git/HEAD"), "ref")
+	writeFile(t, filepath.Join(root, "logs/run.log"), "log")
+
+	tracker, err := filetrack.New(root, filetrack.ParsePatterns(""), filepath.Join(root, "logs"))
+	if err != nil {
+		t.Fatalf("New() 失败: %v", err)
+	}
+	before, err := tracker.Snapshot(nil)
+	if err != nil {
+		t.Fatalf("Snapshot() 失败: %v", err)
+	}
+	if _, ok := before[".git/HEAD"]; ok {
+		t.Error("快照应跳过 .git 目录")
+	}
+	if _, ok := before["logs/run.log"]; ok {
+		t.Error("快照应跳过排除的目录")
+	}
+
+	writeFile(t, filepath.Join(root, "src/main.go"), "package main\n\nfunc main() {}")
+	writeFile(t, filepath.Join(root, "dist/app"), "binary")
+	os.Remove(filepath.Join(root, "old.txt"))
+	// 只修改时间、内容不变的文件不算修改
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(root, "touched.txt"), future, future)
+	writeFile(t, filepath.Join(root, "logs/run.log"), "more log")
+
+	after, err := tracker.Snapshot(before)
+	if err != nil {
+		t.Fatalf("Snapshot() 失败: %v", err)
+	}
+
+	var got []string
+	for _, c := range filetrack.Diff(before, after) {
+		got = append(got, c.Type+" "+c.Path)
+	}
+	want := []string{"created dist/app", "deleted old.txt", "modified src/main.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		patterns string
+		path     string
+		want     bool
+	}{
+		{"", "a/b/c.txt", true},
+		{"*.go", "cmd/main.go", true},
+		{"*.go", "README.md", false},
+		{"dist/**", "dist/js/app.js", true},
+		{"dist/**", "src/dist.js", false},
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/a/b/main.go", true},
+		{"src/*.go", "src/a/main.go", false},
+		{"*.md, dist/**", "docs/guide.md", true},
+	}
+
+	for _, tt := range tests {
+		tracker, err := filetrack.New(".", filetrack.ParsePatterns(tt.patterns))
+		if err != nil {
+			t.Fatalf("New(%q) 失败: %v", tt.patterns, err)
+		}
+		if got := tracker.Match(tt.path); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.patterns, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestNew_InvalidPattern(t *testing.T) {
+	if _, err := filetrack.New(".", []string{"["}); err == nil {
+		t.Error("无效的规则应返回错误")
+	}
+}
+
+func TestSummary(t *testing.T) {
+	changes := []filetrack.Change{
+		{Path: "a", Type: filetrack.ChangeCreated},
+		{Path: "b", Type: filetrack.ChangeCreated},
+		{Path: "c", Type: filetrack.ChangeDeleted},
+	}
+	if got := filetrack.Summary(changes); got != "新建 2, 修改 0, 删除 1" {
+		t.Errorf("Summary() = %q", got)
+	}
+}
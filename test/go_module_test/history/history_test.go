@@ -406,3 +406,517 @@ func TestDeleteByProject(t *testing.T) {
 		t.Errorf("DeleteByProject 后 Count = %d, want 0", count)
 	}
 }
+
+func TestRecordTerminationSignal(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	manager := history.NewManager(db)
+
+	cmd := &model.CommandHistory{
+		ProjectID:   1,
+		Command:     "make build",
+		StartTime:   time.Now(),
+		EndTime:     time.Now().Add(time.Second),
+		DurationMs:  1000,
+		ExitCode:    -1,
+		Signal:      "SIGTERM",
+		Reason:      "idle-timeout",
+		LogFilePath: "/path/to/log.log",
+		LogDate:     "2024-01-01",
+		CreatedAt:   time.Now(),
+	}
+	if err := manager.Record(cmd); err != nil {
+		t.Fatalf("Record() 失败: %v", err)
+	}
+
+	results, err := manager.GetRecent(1, 1)
+	if err != nil {
+		t.Fatalf("GetRecent() 失败: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("应该返回 1 条记录, got %d", len(results))
+	}
+	if results[0].Signal != "SIGTERM" {
+		t.Errorf("Signal = %q, want SIGTERM", results[0].Signal)
+	}
+	if results[0].Reason != "idle-timeout" {
+		t.Errorf("Reason = %q, want idle-timeout", results[0].Reason)
+	}
+	if results[0].IsSuccess() {
+		t.Error("被信号终止的命令不应标记为成功")
+	}
+}
+
+func TestGetAttempts(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	manager := history.NewManager(db)
+	now := time.Now()
+	for attempt := 3; attempt >= 1; attempt-- {
+		status := "failed"
+		if attempt == 3 {
+			status = "success"
+		}
+		cmd := &model.CommandHistory{
+			ProjectID:    1,
+			Command:      "npm install",
+			StartTime:    now.Add(time.Duration(attempt) * time.Second),
+			EndTime:      now.Add(time.Duration(attempt+1) * time.Second),
+			Status:       status,
+			AttemptGroup: "group-1",
+			Attempt:      attempt,
+			LogFilePath:  "/path/to/log.log",
+			LogDate:      "2024-01-01",
+			CreatedAt:    now,
+		}
+		if err := manager.Record(cmd); err != nil {
+			t.Fatalf("Record() 失败: %v", err)
+		}
+	}
+	if err := manager.Record(&model.CommandHistory{ProjectID: 1, Command: "ls", StartTime: now, EndTime: now, Status: "success", LogDate: "2024-01-01", CreatedAt: now}); err != nil {
+		t.Fatalf("Record() 失败: %v", err)
+	}
+
+	attempts, err := manager.GetAttempts("group-1")
+	if err != nil {
+		t.Fatalf("GetAttempts() 失败: %v", err)
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("应该返回 3 次尝试, got %d", len(attempts))
+	}
+	for i, cmd := range attempts {
+		if cmd.Attempt != i+1 || cmd.AttemptGroup != "group-1" {
+			t.Errorf("第 %d 条记录 attempt=%d group=%q", i, cmd.Attempt, cmd.AttemptGroup)
+		}
+	}
+	if !attempts[2].IsSuccess() {
+		t.Error("最后一次尝试应为成功")
+	}
+}
+
+func TestRecordResourceUsageAndSamples(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	manager := history.NewManager(db)
+	cmd := &model.CommandHistory{
+		ProjectID:            1,
+		Command:              "make build",
+		StartTime:            time.Now(),
+		EndTime:              time.Now().Add(time.Second),
+		CPUUserMs:            1200,
+		CPUSystemMs:          300,
+		MaxRSSBytes:          64 << 20,
+		BlockOutput:          8,
+		CtxSwitchesVoluntary: 10,
+		LogFilePath:          "/path/to/log.log",
+		LogDate:              "2024-01-01",
+		CreatedAt:            time.Now(),
+	}
+	if err := manager.Record(cmd); err != nil {
+		t.Fatalf("Record() 失败: %v", err)
+	}
+	if cmd.ID == 0 {
+		t.Fatal("Record() 应回填记录 ID")
+	}
+
+	samples := []model.ResourceSample{
+		{OffsetMs: 2000, CPUMs: 900, RSSBytes: 60 << 20, Processes: 3},
+		{OffsetMs: 1000, CPUMs: 400, RSSBytes: 30 << 20, Processes: 2},
+	}
+	if err := manager.RecordSamples(cmd.ID, samples); err != nil {
+		t.Fatalf("RecordSamples() 失败: %v", err)
+	}
+
+	loaded, err := manager.GetByID(cmd.ID)
+	if err != nil {
+		t.Fatalf("GetByID() 失败: %v", err)
+	}
+	if loaded.CPUUserMs != 1200 || loaded.CPUSystemMs != 300 || loaded.MaxRSSBytes != 64<<20 {
+		t.Errorf("资源占用未正确保存: %+v", loaded)
+	}
+
+	got, err := manager.GetSamples(cmd.ID)
+	if err != nil {
+		t.Fatalf("GetSamples() 失败: %v", err)
+	}
+	if len(got) != 2 || got[0].OffsetMs != 1000 || got[1].Processes != 3 {
+		t.Fatalf("采样应按时间顺序返回: %+v", got)
+	}
+
+	if err := manager.Delete(cmd.ID); err != nil {
+		t.Fatalf("Delete() 失败: %v", err)
+	}
+	got, err = manager.GetSamples(cmd.ID)
+	if err != nil {
+		t.Fatalf("GetSamples() 失败: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("删除历史后应清理采样, got %d", len(got))
+	}
+}
+
+func TestGetByLogPathReturnsEnvironment(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	manager := history.NewManager(db)
+	now := time.Now()
+	for i, env := range []string{`{"FOO":"1"}`, `{"FOO":"2"}`} {
+		cmd := &model.CommandHistory{
+			ProjectID:       1,
+			Command:         "make",
+			StartTime:       now.Add(time.Duration(i) * time.Second),
+			EndTime:         now.Add(time.Duration(i+1) * time.Second),
+			Status:          "success",
+			LogFilePath:     "/path/to/make.log",
+			LogDate:         "2024-01-01",
+			EnvironmentJSON: env,
+			Encoding:        "gbk",
+			CreatedAt:       now,
+		}
+		if err := manager.Record(cmd); err != nil {
+			t.Fatalf("Record() 失败: %v", err)
+		}
+	}
+
+	cmd, err := manager.GetByLogPath("/path/to/make.log")
+	if err != nil {
+		t.Fatalf("GetByLogPath() 失败: %v", err)
+	}
+	if cmd.EnvironmentJSON != `{"FOO":"2"}` {
+		t.Errorf("同一日志有多条记录时应返回最新一条, got %q", cmd.EnvironmentJSON)
+	}
+	if cmd.Encoding != "gbk" {
+		t.Errorf("Encoding = %q, want gbk", cmd.Encoding)
+	}
+
+	if _, err := manager.GetByLogPath("/path/to/missing.log"); err == nil {
+		t.Error("日志路径不存在时应返回错误")
+	}
+}
+
+func TestRecordOutputPreview(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	manager := history.NewManager(db)
+	now := time.Now()
+	cmd := &model.CommandHistory{
+		ProjectID:     1,
+		Command:       "go test",
+		StartTime:     now,
+		EndTime:       now.Add(time.Second),
+		ExitCode:      1,
+		Status:        "failed",
+		LogFilePath:   "/path/to/test.log",
+		LogDate:       "2024-01-01",
+		StdoutPreview: "=== RUN TestA\n...\nFAIL",
+		StderrPreview: "panic: boom",
+		ErrorExcerpt:  "panic: boom",
+		HasError:      true,
+		CreatedAt:     now,
+	}
+	if err := manager.Record(cmd); err != nil {
+		t.Fatalf("Record() 失败: %v", err)
+	}
+
+	failed, err := manager.GetFailed(1, 1)
+	if err != nil {
+		t.Fatalf("GetFailed() 失败: %v", err)
+	}
+	if len(failed) != 1 {
+		t.Fatalf("期望 1 条失败记录, got %d", len(failed))
+	}
+	got := failed[0]
+	if got.StdoutPreview != cmd.StdoutPreview || got.StderrPreview != cmd.StderrPreview {
+		t.Errorf("预览读写不一致: %q %q", got.StdoutPreview, got.StderrPreview)
+	}
+	if got.ErrorExcerpt != "panic: boom" || !got.HasError {
+		t.Errorf("ErrorExcerpt = %q, HasError = %v", got.ErrorExcerpt, got.HasError)
+	}
+}
+
+func TestRecordRecordingPath(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	manager := history.NewManager(db)
+	now := time.Now()
+	cmd := &model.CommandHistory{
+		ProjectID:   1,
+		Command:     "npm test",
+		StartTime:   now,
+		EndTime:     now.Add(time.Second),
+		Status:      "success",
+		LogFilePath: "/path/to/test.log",
+		LogDate:     "2024-01-01",
+		Recording:   "/path/to/test.log.cast",
+		CreatedAt:   now,
+	}
+	if err := manager.Record(cmd); err != nil {
+		t.Fatalf("Record() 失败: %v", err)
+	}
+
+	got, err := manager.GetByLogPath("/path/to/test.log")
+	if err != nil {
+		t.Fatalf("GetByLogPath() 失败: %v", err)
+	}
+	if got.Recording != "/path/to/test.log.cast" {
+		t.Errorf("Recording = %q, want /path/to/test.log.cast", got.Recording)
+	}
+}
+
+func TestRecordSandboxProfile(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	manager := history.NewManager(db)
+	now := time.Now()
+	cmd := &model.CommandHistory{
+		ProjectID:      1,
+		Command:        "sh install.sh",
+		StartTime:      now,
+		EndTime:        now.Add(time.Second),
+		Status:         "failed",
+		LogFilePath:    "/path/to/install.log",
+		LogDate:        "2024-01-01",
+		SandboxProfile: `{"name":"offline","no_network":true}`,
+		SandboxDenials: 2,
+		CreatedAt:      now,
+	}
+	if err := manager.Record(cmd); err != nil {
+		t.Fatalf("Record() 失败: %v", err)
+	}
+
+	got, err := manager.GetByLogPath("/path/to/install.log")
+	if err != nil {
+		t.Fatalf("GetByLogPath() 失败: %v", err)
+	}
+	if got.SandboxProfile != cmd.SandboxProfile || got.SandboxDenials != 2 {
+		t.Errorf("沙箱信息 = %q/%d, want %q/2", got.SandboxProfile, got.SandboxDenials, cmd.SandboxProfile)
+	}
+}
+
+func TestRecordResourceColumns(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	manager := history.NewManager(db)
+	now := time.Now()
+	cmd := &model.CommandHistory{
+		ProjectID:         1,
+		Command:           "make -j8",
+		StartTime:         now,
+		EndTime:           now.Add(time.Second),
+		Status:            "success",
+		LogFilePath:       "/path/to/make.log",
+		LogDate:           "2024-01-01",
+		MaxRSSBytes:       100 << 20,
+		GroupPeakRSSBytes: 800 << 20,
+		CreatedAt:         now,
+	}
+	if err := manager.Record(cmd); err != nil {
+		t.Fatalf("Record() 失败: %v", err)
+	}
+
+	got, err := manager.GetByLogPath("/path/to/make.log")
+	if err != nil {
+		t.Fatalf("GetByLogPath() 失败: %v", err)
+	}
+	// rusage 单进程峰值与采样的进程组峰值分开保存
+	if got.MaxRSSBytes != 100<<20 || got.GroupPeakRSSBytes != 800<<20 {
+		t.Errorf("内存 = %d/%d, want %d/%d", got.MaxRSSBytes, got.GroupPeakRSSBytes, int64(100<<20), int64(800<<20))
+	}
+}
+
+func TestRecordFileChanges(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	manager := history.NewManager(db)
+	now := time.Now()
+	cmd := &model.CommandHistory{
+		ProjectID:   1,
+		Command:     "make",
+		StartTime:   now,
+		EndTime:     now.Add(time.Second),
+		Status:      "success",
+		LogFilePath: "/path/to/make.log",
+		LogDate:     "2024-01-01",
+		CreatedAt:   now,
+	}
+	if err := manager.Record(cmd); err != nil {
+		t.Fatalf("Record() 失败: %v", err)
+	}
+
+	changes := []model.FileChange{
+		{Path: "dist/app", Type: "created", SizeAfter: 10, HashAfter: "b"},
+		{Path: "Makefile", Type: "modified", SizeBefore: 5, SizeAfter: 6, HashBefore: "a", HashAfter: "c"},
+	}
+	if err := manager.RecordFileChanges(cmd.ID, changes); err != nil {
+		t.Fatalf("RecordFileChanges() 失败: %v", err)
+	}
+
+	got, err := manager.GetFileChanges(cmd.ID)
+	if err != nil {
+		t.Fatalf("GetFileChanges() 失败: %v", err)
+	}
+	if len(got) != 2 || got[0].Path != "Makefile" || got[1].Path != "dist/app" {
+		t.Fatalf("文件变化应按路径排序, got %+v", got)
+	}
+	if got[0].SizeBefore != 5 || got[0].SizeAfter != 6 || got[0].HashAfter != "c" {
+		t.Errorf("文件变化读写不一致: %+v", got[0])
+	}
+
+	if err := manager.DeleteByProject(1); err != nil {
+		t.Fatalf("DeleteByProject() 失败: %v", err)
+	}
+	if got, _ := manager.GetFileChanges(cmd.ID); len(got) != 0 {
+		t.Errorf("删除历史后应清理文件变化, got %d", len(got))
+	}
+}
+
+func TestRecordArtifacts(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	manager := history.NewManager(db)
+	now := time.Now()
+	cmd := &model.CommandHistory{
+		ProjectID:   1,
+		Command:     "make dist",
+		StartTime:   now,
+		EndTime:     now.Add(time.Second),
+		Status:      "success",
+		LogFilePath: "/path/to/dist.log",
+		LogDate:     "2024-01-01",
+		CreatedAt:   now,
+	}
+	if err := manager.Record(cmd); err != nil {
+		t.Fatalf("Record() 失败: %v", err)
+	}
+
+	artifacts := []model.Artifact{
+		{Name: "dist/app.tar.gz", SourcePath: "/src/dist/app.tar.gz", StoredPath: "/logs/artifacts/dist/app.tar.gz", SizeBytes: 1024, SHA256: "abc"},
+		{Name: "coverage/index.html", SourcePath: "/src/coverage/index.html", StoredPath: "/logs/artifacts/coverage/index.html", SizeBytes: 10, SHA256: "def"},
+	}
+	if err := manager.RecordArtifacts(cmd.ID, artifacts); err != nil {
+		t.Fatalf("RecordArtifacts() 失败: %v", err)
+	}
+
+	got, err := manager.GetArtifacts(cmd.ID)
+	if err != nil {
+		t.Fatalf("GetArtifacts() 失败: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "coverage/index.html" {
+		t.Fatalf("产物应按名称排序, got %+v", got)
+	}
+	if got[1].SizeBytes != 1024 || got[1].SHA256 != "abc" || got[1].StoredPath != "/logs/artifacts/dist/app.tar.gz" {
+		t.Errorf("产物读写不一致: %+v", got[1])
+	}
+}
+
+func TestRecordEvents(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	manager := history.NewManager(db)
+	now := time.Now()
+	cmd := &model.CommandHistory{
+		ProjectID:   1,
+		Command:     "make",
+		StartTime:   now,
+		EndTime:     now.Add(time.Second),
+		Status:      "success",
+		LogFilePath: "/path/to/make.log",
+		LogDate:     "2024-01-01",
+		CreatedAt:   now,
+	}
+	if err := manager.Record(cmd); err != nil {
+		t.Fatalf("Record() 失败: %v", err)
+	}
+
+	events := []model.CommandEvent{
+		{OffsetMs: 900, Type: "metric", Key: "tests", Value: "42"},
+		{OffsetMs: 100, Type: "mark", Text: "compile started"},
+	}
+	if err := manager.RecordEvents(cmd.ID, events); err != nil {
+		t.Fatalf("RecordEvents() 失败: %v", err)
+	}
+
+	got, err := manager.GetEvents(cmd.ID)
+	if err != nil {
+		t.Fatalf("GetEvents() 失败: %v", err)
+	}
+	if len(got) != 2 || got[0].Text != "compile started" || got[1].Key != "tests" || got[1].Value != "42" {
+		t.Errorf("事件应按时间顺序返回: %+v", got)
+	}
+
+	if err := manager.Delete(cmd.ID); err != nil {
+		t.Fatalf("Delete() 失败: %v", err)
+	}
+	if got, _ := manager.GetEvents(cmd.ID); len(got) != 0 {
+		t.Errorf("删除命令历史后应清理事件, got %d", len(got))
+	}
+}
+
+func TestQueryByExecutionContext(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	manager := history.NewManager(db)
+	now := time.Now()
+	runs := []struct {
+		user   string
+		branch string
+	}{
+		{"alice", "main"},
+		{"alice", "feature"},
+		{"bob", "main"},
+	}
+	for i, run := range runs {
+		cmd := &model.CommandHistory{
+			ProjectID:     1,
+			Command:       "go test",
+			StartTime:     now.Add(time.Duration(i) * time.Second),
+			EndTime:       now.Add(time.Duration(i+1) * time.Second),
+			Status:        "success",
+			LogDate:       "2024-01-01",
+			OSUser:        run.user,
+			OSUID:         "1000",
+			Hostname:      "build-01",
+			TTY:           "/dev/pts/1",
+			ParentProcess: "zsh",
+			GitBranch:     run.branch,
+			GitCommit:     "0123456789abcdef",
+			GitDirty:      i == 1,
+			CreatedAt:     now,
+		}
+		if err := manager.Record(cmd); err != nil {
+			t.Fatalf("Record() 失败: %v", err)
+		}
+	}
+
+	results, err := manager.Query(history.QueryOptions{GitBranch: "main"})
+	if err != nil {
+		t.Fatalf("Query() 失败: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("分支 main 应有 2 条记录, got %d", len(results))
+	}
+
+	results, err = manager.Query(history.QueryOptions{OSUser: "alice", GitBranch: "feature", Hostname: "build-01"})
+	if err != nil {
+		t.Fatalf("Query() 失败: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("应有 1 条记录, got %d", len(results))
+	}
+	cmd := results[0]
+	if cmd.OSUID != "1000" || cmd.TTY != "/dev/pts/1" || cmd.ParentProcess != "zsh" || cmd.GitCommit != "0123456789abcdef" || !cmd.GitDirty {
+		t.Errorf("执行上下文未正确保存: %+v", cmd)
+	}
+}
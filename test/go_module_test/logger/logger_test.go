@@ -0,0 +1,154 @@
+package logger_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aliancn/logcmd/internal/config"
+	"github.com/aliancn/logcmd/internal/executor"
+	"github.com/aliancn/logcmd/internal/logger"
+	"github.com/aliancn/logcmd/internal/model"
+)
+
+// fakeRepo 记录 RecordRun 的调用
+type fakeRepo struct {
+	runs []*executor.Result
+}
+
+func (r *fakeRepo) RegisterProject(path string) (*model.Project, error) {
+	return &model.Project{ID: 1, Path: path}, nil
+}
+
+func (r *fakeRepo) RecordRun(project *model.Project, result *executor.Result, logFilePath string) error {
+	r.runs = append(r.runs, result)
+	return nil
+}
+
+func (r *fakeRepo) PreviewLength() int {
+	return 0
+}
+
+// fakeStats 记录项目统计的更新
+type fakeStats struct {
+	calls   int
+	success []bool
+}
+
+func (s *fakeStats) UpdateProjectStats(projectID int, command string, success bool, duration time.Duration) error {
+	s.calls++
+	s.success = append(s.success, success)
+	return nil
+}
+
+func newTestConfig(t *testing.T) *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.LogDir = t.TempDir()
+	cfg.Run.Output = executor.OutputQuiet
+	return cfg
+}
+
+func TestRunRetriesUpdateProjectStatsOnce(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Run.Retries = 2
+	cfg.Run.Backoff = "0s"
+
+	repo := &fakeRepo{}
+	stats := &fakeStats{}
+	log, err := logger.New(cfg, repo, stats)
+	if err != nil {
+		t.Fatalf("New() 失败: %v", err)
+	}
+
+	result, _, err := log.Run(context.Background(), "false")
+	if err == nil || result == nil || result.Success {
+		t.Fatalf("命令应失败, result=%+v err=%v", result, err)
+	}
+	if len(repo.runs) != 3 {
+		t.Errorf("每次尝试都应记录历史, got %d 条", len(repo.runs))
+	}
+	if stats.calls != 1 || stats.success[0] {
+		t.Errorf("一次运行只应按最后一次尝试更新一次项目统计, got %d 次 %v", stats.calls, stats.success)
+	}
+}
+
+func TestRunUpdatesProjectStats(t *testing.T) {
+	repo := &fakeRepo{}
+	stats := &fakeStats{}
+	log, err := logger.New(newTestConfig(t), repo, stats)
+	if err != nil {
+		t.Fatalf("New() 失败: %v", err)
+	}
+
+	if _, _, err := log.Run(context.Background(), "true"); err != nil {
+		t.Fatalf("Run() 失败: %v", err)
+	}
+	if len(repo.runs) != 1 || stats.calls != 1 || !stats.success[0] {
+		t.Errorf("history=%d stats=%d %v", len(repo.runs), stats.calls, stats.success)
+	}
+}
+
+func TestRunRedactsRecording(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Run.Record = true
+	cfg.Redact = true
+
+	log, err := logger.New(cfg, &fakeRepo{}, &fakeStats{})
+	if err != nil {
+		t.Fatalf("New() 失败: %v", err)
+	}
+	result, _, err := log.Run(context.Background(), "printf", `password=%s\n`, "hunter2")
+	if err != nil {
+		t.Fatalf("Run() 失败: %v", err)
+	}
+	log.Close()
+
+	cast, err := os.ReadFile(result.Recording)
+	if err != nil {
+		t.Fatalf("读取录制文件失败: %v", err)
+	}
+	if strings.Contains(string(cast), "password=hunter2") || !strings.Contains(string(cast), "password=[REDACTED]") {
+		t.Errorf("录制应经过脱敏: %s", cast)
+	}
+}
+
+func TestRunRetriesReplayPipedStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("hello\n"))
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() {
+		os.Stdin = stdin
+		r.Close()
+	}()
+
+	cfg := newTestConfig(t)
+	cfg.Run.Retries = 1
+	cfg.Run.Backoff = "0s"
+	log, err := logger.New(cfg, &fakeRepo{}, &fakeStats{})
+	if err != nil {
+		t.Fatalf("New() 失败: %v", err)
+	}
+	if _, _, err := log.Run(context.Background(), "sh", "-c", "cat; exit 1"); err == nil {
+		t.Fatal("命令应失败")
+	}
+	log.Close()
+
+	logs, _ := filepath.Glob(filepath.Join(cfg.LogDir, "*", "*.log"))
+	if len(logs) != 2 {
+		t.Fatalf("期望 2 次尝试的日志, got %v", logs)
+	}
+	for _, path := range logs {
+		data, _ := os.ReadFile(path)
+		if !strings.Contains(string(data), "hello") {
+			t.Errorf("每次尝试都应读到管道输入: %s", path)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package logmeta_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aliancn/logcmd/internal/events"
+	"github.com/aliancn/logcmd/internal/execctx"
+	"github.com/aliancn/logcmd/internal/executor"
+	"github.com/aliancn/logcmd/internal/logmeta"
+)
+
+func TestSource(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "app.log")
+	if got := logmeta.Source(logPath); got != logPath {
+		t.Errorf("没有元数据文件时应返回日志本身, got %s", got)
+	}
+	if err := os.WriteFile(logmeta.PathFor(logPath), []byte("header\n"), 0644); err != nil {
+		t.Fatalf("创建元数据文件失败: %v", err)
+	}
+	if got := logmeta.Source(logPath); got != logmeta.PathFor(logPath) {
+		t.Errorf("raw 日志应返回元数据文件, got %s", got)
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "make_100000.log")
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.Local)
+	result := &executor.Result{
+		Command:   "make",
+		Args:      []string{"build", "-j4"},
+		StartTime: start,
+		EndTime:   start.Add(2 * time.Second),
+		Duration:  2 * time.Second,
+		ExitCode:  2,
+		Status:    executor.StatusFailed,
+		Usage:     &executor.Usage{UserCPU: time.Second, MaxRSS: 1 << 20},
+		Context:   &execctx.Info{User: "alice", GitBranch: "main"},
+		Events:    []events.Event{{Type: events.TypeMark, Text: "compile done", Offset: 1500 * time.Millisecond}},
+	}
+
+	err := logmeta.Write(logPath, &logmeta.Metadata{
+		LogFile:          logPath,
+		WorkingDirectory: "/src/app",
+		CommandLine:      executor.CommandLine(result),
+		Result:           result,
+	})
+	if err != nil {
+		t.Fatalf("Write() 失败: %v", err)
+	}
+
+	got, err := logmeta.Read(logPath)
+	if err != nil {
+		t.Fatalf("Read() 失败: %v", err)
+	}
+	if got.Version != logmeta.Version || got.LogFile != logPath || got.WorkingDirectory != "/src/app" {
+		t.Errorf("元数据 = %+v", got)
+	}
+	r := got.Result
+	if r.Command != "make" || len(r.Args) != 2 || r.Args[1] != "-j4" || r.ExitCode != 2 || r.Status != executor.StatusFailed {
+		t.Errorf("执行结果 = %+v", r)
+	}
+	if !r.StartTime.Equal(start) || r.Duration != 2*time.Second {
+		t.Errorf("时间 = %v / %v", r.StartTime, r.Duration)
+	}
+	if r.Usage == nil || r.Usage.CPU() != time.Second || r.Usage.MaxRSS != 1<<20 {
+		t.Errorf("资源占用 = %+v", r.Usage)
+	}
+	if r.Context == nil || r.Context.User != "alice" || r.Context.GitBranch != "main" {
+		t.Errorf("执行上下文 = %+v", r.Context)
+	}
+	if len(r.Events) != 1 || r.Events[0] != result.Events[0] {
+		t.Errorf("事件 = %+v, want %+v", r.Events, result.Events)
+	}
+
+	// 临时文件不应残留
+	entries, _ := os.ReadDir(filepath.Dir(logPath))
+	if len(entries) != 1 || entries[0].Name() != filepath.Base(logmeta.JSONPathFor(logPath)) {
+		t.Errorf("目录中应只有元数据文件: %v", entries)
+	}
+}
+
+func TestReadMissing(t *testing.T) {
+	_, err := logmeta.Read(filepath.Join(t.TempDir(), "old.log"))
+	if !os.IsNotExist(err) {
+		t.Errorf("旧日志没有元数据文件时应返回不存在错误, got %v", err)
+	}
+}
+
+func TestReadUnsupportedVersion(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "app.log")
+	data, _ := json.Marshal(map[string]any{
+		"version": logmeta.Version + 1,
+		"result":  map[string]any{"command": "make"},
+	})
+	if err := os.WriteFile(logmeta.JSONPathFor(logPath), data, 0644); err != nil {
+		t.Fatalf("创建元数据文件失败: %v", err)
+	}
+	if _, err := logmeta.Read(logPath); err == nil || os.IsNotExist(err) {
+		t.Errorf("更新版本的元数据应返回错误, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package logrecord_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/aliancn/logcmd/internal/logrecord"
+)
+
+func readRecords(t *testing.T, data []byte) []*logrecord.Record {
+	t.Helper()
+	var records []*logrecord.Record
+	scanner := logrecord.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		records = append(records, scanner.Record())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("读取记录失败: %v", err)
+	}
+	return records
+}
+
+func TestWriter_SplitsLinesAcrossWrites(t *testing.T) {
+	var buf bytes.Buffer
+	writer := logrecord.NewWriter(&buf, time.Now())
+	stdout := writer.Stream(logrecord.StreamStdout)
+	stderr := writer.Stream(logrecord.StreamStderr)
+
+	stdout.Write([]byte("hel"))
+	stderr.Write([]byte("oops\r\n"))
+	stdout.Write([]byte("lo\nwor"))
+	stdout.Flush()
+	stderr.Flush()
+	writer.Flush()
+
+	records := readRecords(t, buf.Bytes())
+	if len(records) != 3 {
+		t.Fatalf("期望 3 条记录, got %d", len(records))
+	}
+
+	expected := []struct {
+		stream string
+		line   string
+	}{
+		{logrecord.StreamStderr, "oops"},
+		{logrecord.StreamStdout, "hello"},
+		{logrecord.StreamStdout, "wor"},
+	}
+	for i, want := range expected {
+		got := records[i]
+		if got.Seq != int64(i+1) {
+			t.Errorf("记录 %d 序号 = %d, 期望 %d", i, got.Seq, i+1)
+		}
+		if got.Stream != want.stream || got.Line != want.line {
+			t.Errorf("记录 %d = %s/%q, 期望 %s/%q", i, got.Stream, got.Line, want.stream, want.line)
+		}
+	}
+}
+
+func TestWriter_OffsetsAreMonotonic(t *testing.T) {
+	var buf bytes.Buffer
+	writer := logrecord.NewWriter(&buf, time.Now())
+	stream := writer.Stream(logrecord.StreamStdout)
+
+	stream.Write([]byte("first\n"))
+	time.Sleep(5 * time.Millisecond)
+	stream.Write([]byte("second\n"))
+	writer.Flush()
+
+	records := readRecords(t, buf.Bytes())
+	if len(records) != 2 {
+		t.Fatalf("期望 2 条记录, got %d", len(records))
+	}
+	if records[1].Offset() < records[0].Offset() {
+		t.Errorf("时间偏移应该递增: %v -> %v", records[0].Offset(), records[1].Offset())
+	}
+	if records[1].Offset() < 5*time.Millisecond {
+		t.Errorf("第二条记录的偏移过小: %v", records[1].Offset())
+	}
+}
+
+func TestWriter_Limit(t *testing.T) {
+	var buf bytes.Buffer
+	writer := logrecord.NewWriter(&buf, time.Now())
+	writer.Limit(8)
+	stdout := writer.Stream(logrecord.StreamStdout)
+	events := writer.Stream(logrecord.StreamEvent)
+
+	stdout.Write([]byte("first\nsecond\nthird\n"))
+	events.Write([]byte("phase 2\n"))
+	writer.Flush()
+
+	records := readRecords(t, buf.Bytes())
+	if len(records) != 3 {
+		t.Fatalf("期望 3 条记录, got %d", len(records))
+	}
+	if records[0].Line != "first" || records[1].Stream != logrecord.StreamNotice {
+		t.Errorf("超过上限后应写入一条提示并停止记录输出: %+v, %+v", records[0], records[1])
+	}
+	if records[2].Stream != logrecord.StreamEvent {
+		t.Errorf("事件不受上限限制: %+v", records[2])
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	if _, err := logrecord.Decode([]byte("not json")); err == nil {
+		t.Error("无效记录应该返回错误")
+	}
+}
+
+func TestPathFor(t *testing.T) {
+	if got := logrecord.PathFor("/tmp/a.log"); got != "/tmp/a.log.jsonl" {
+		t.Errorf("PathFor() = %q", got)
+	}
+}
@@ -0,0 +1,83 @@
+package retry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aliancn/logcmd/internal/retry"
+)
+
+func TestParseBackoff(t *testing.T) {
+	tests := []struct {
+		spec   string
+		delays []time.Duration
+	}{
+		{"5s", []time.Duration{5 * time.Second, 5 * time.Second}},
+		{"fixed:500ms", []time.Duration{500 * time.Millisecond, 500 * time.Millisecond}},
+		{"exp:2s..1m", []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second, time.Minute, time.Minute}},
+		{"exp:1s", []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		b, err := retry.ParseBackoff(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseBackoff(%q) 失败: %v", tt.spec, err)
+		}
+		for i, want := range tt.delays {
+			if got := b.Delay(i + 1); got != want {
+				t.Errorf("ParseBackoff(%q).Delay(%d) = %v, want %v", tt.spec, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestParseBackoffInvalid(t *testing.T) {
+	for _, spec := range []string{"abc", "exp:0s", "exp:1m..2s", "linear:1s", "fixed:-1s"} {
+		if _, err := retry.ParseBackoff(spec); err == nil {
+			t.Errorf("ParseBackoff(%q) 应该返回错误", spec)
+		}
+	}
+}
+
+func TestParseExitCodes(t *testing.T) {
+	codes, err := retry.ParseExitCodes("1, 137,")
+	if err != nil {
+		t.Fatalf("ParseExitCodes() 失败: %v", err)
+	}
+	if len(codes) != 2 || codes[0] != 1 || codes[1] != 137 {
+		t.Errorf("ParseExitCodes() = %v, want [1 137]", codes)
+	}
+
+	if _, err := retry.ParseExitCodes("1,abc"); err == nil {
+		t.Error("ParseExitCodes() 应拒绝非数字退出码")
+	}
+}
+
+func TestPolicyShouldRetry(t *testing.T) {
+	policy := retry.Policy{Retries: 2, RetryOn: []int{1, 137}}
+
+	if policy.Attempts() != 3 {
+		t.Errorf("Attempts() = %d, want 3", policy.Attempts())
+	}
+	if !policy.ShouldRetry(1, 137) {
+		t.Error("退出码 137 应该重试")
+	}
+	if policy.ShouldRetry(1, 2) {
+		t.Error("退出码 2 不在 retry-on 列表中，不应重试")
+	}
+	if policy.ShouldRetry(3, 1) {
+		t.Error("达到最大尝试次数后不应重试")
+	}
+
+	anyFailure := retry.Policy{Retries: 1}
+	if !anyFailure.ShouldRetry(1, 42) {
+		t.Error("未指定 retry-on 时任何失败都应重试")
+	}
+}
+
+func TestNewGroupID(t *testing.T) {
+	a, b := retry.NewGroupID(), retry.NewGroupID()
+	if a == "" || a == b {
+		t.Errorf("NewGroupID() 应生成唯一 ID: %q %q", a, b)
+	}
+}
@@ -0,0 +1,129 @@
+package sandbox_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aliancn/logcmd/internal/sandbox"
+)
+
+// TestMain 让测试二进制可以作为沙箱辅助进程被 Wrap 重新执行
+func TestMain(m *testing.M) {
+	sandbox.Init()
+	os.Exit(m.Run())
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("SANDBOX_TEST_DIR", "/opt/cache")
+	custom := map[string]sandbox.Profile{
+		"install": {Writable: []string{"$SANDBOX_TEST_DIR", "~/.npm"}, NoNetwork: true},
+		"offline": {Writable: []string{"/tmp"}},
+	}
+
+	p, err := sandbox.Resolve("", custom)
+	if err != nil {
+		t.Fatalf("Resolve() 失败: %v", err)
+	}
+	if p.Name != sandbox.DefaultProfile || p.NoNetwork || len(p.Writable) != 0 {
+		t.Errorf("未指定名称时应使用内置 default 配置，实际为 %+v", p)
+	}
+
+	p, err = sandbox.Resolve("install", custom)
+	if err != nil {
+		t.Fatalf("Resolve() 失败: %v", err)
+	}
+	home, _ := os.UserHomeDir()
+	if !p.NoNetwork || len(p.Writable) != 2 || p.Writable[0] != "/opt/cache" || p.Writable[1] != filepath.Join(home, ".npm") {
+		t.Errorf("应展开环境变量和 ~，实际为 %+v", p)
+	}
+
+	// 配置文件中的同名配置覆盖内置配置
+	p, err = sandbox.Resolve("offline", custom)
+	if err != nil {
+		t.Fatalf("Resolve() 失败: %v", err)
+	}
+	if p.NoNetwork || len(p.Writable) != 1 {
+		t.Errorf("自定义配置应覆盖内置 offline，实际为 %+v", p)
+	}
+
+	if _, err := sandbox.Resolve("missing", custom); err == nil || !strings.Contains(err.Error(), "default, install, offline") {
+		t.Errorf("未知配置应报错并列出可用配置，实际为 %v", err)
+	}
+}
+
+func TestProfileStringAndEncode(t *testing.T) {
+	p := sandbox.Profile{Name: "ci", Writable: []string{"/tmp"}, NoNetwork: true}
+	if got, want := p.String(), "ci (可写: 工作目录, /tmp; 网络: 禁止)"; got != want {
+		t.Errorf("String() = %q, 期望 %q", got, want)
+	}
+
+	decoded, err := sandbox.Decode(p.Encode())
+	if err != nil {
+		t.Fatalf("Decode() 失败: %v", err)
+	}
+	if decoded.Name != p.Name || !decoded.NoNetwork || len(decoded.Writable) != 1 || decoded.Writable[0] != "/tmp" {
+		t.Errorf("编码后应能还原配置，实际为 %+v", decoded)
+	}
+}
+
+func TestIsDenial(t *testing.T) {
+	denied := []string{
+		"touch: cannot touch '/usr/local/bin/tool': Permission denied",
+		"mkdir: cannot create directory '/opt/x': Read-only file system",
+		"curl: (6) Could not resolve host: example.com",
+		"Error: EACCES: permission denied, open '/usr/lib/node_modules'",
+		"touch: 无法创建 '/root/x': 权限不够",
+	}
+	for _, line := range denied {
+		if !sandbox.IsDenial([]byte(line)) {
+			t.Errorf("应识别为沙箱拒绝: %q", line)
+		}
+	}
+	if sandbox.IsDenial([]byte("npm WARN deprecated request@2.88.2")) {
+		t.Error("普通输出不应识别为沙箱拒绝")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	if err := sandbox.Supported(); err != nil {
+		t.Skipf("当前环境不支持沙箱: %v", err)
+	}
+
+	work := t.TempDir()
+	allowed := t.TempDir()
+	outside := t.TempDir()
+
+	cmd := exec.Command("sh", "-c", `echo ok > work.txt && echo ok > "$1/allowed.txt" && cat work.txt; echo no > "$2/outside.txt"`,
+		"sh", allowed, outside)
+	cmd.Dir = work
+	if err := sandbox.Wrap(cmd, sandbox.Profile{Name: "test", Writable: []string{allowed}}); err != nil {
+		t.Fatalf("Wrap() 失败: %v", err)
+	}
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("写入未允许的目录应失败，输出: %s", out)
+	}
+
+	if !strings.HasPrefix(string(out), "ok\n") || !sandbox.IsDenial(out) {
+		t.Errorf("工作目录可写、其他目录应被拒绝，输出: %s", out)
+	}
+	if _, err := os.Stat(filepath.Join(allowed, "allowed.txt")); err != nil {
+		t.Errorf("配置允许的路径应可写: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "outside.txt")); err == nil {
+		t.Error("沙箱外的目录不应被写入")
+	}
+
+	// 辅助进程传递配置的环境变量不应出现在命令的环境中
+	cmd = exec.Command("sh", "-c", "echo ${"+sandbox.EnvVar+":-unset}")
+	cmd.Dir = work
+	if err := sandbox.Wrap(cmd, sandbox.Profile{Name: "test"}); err != nil {
+		t.Fatalf("Wrap() 失败: %v", err)
+	}
+	if out, err := cmd.Output(); err != nil || string(out) != "unset\n" {
+		t.Errorf("命令环境中不应包含 %s，输出: %q, 错误: %v", sandbox.EnvVar, out, err)
+	}
+}
@@ -1,6 +1,7 @@
 package search_test
 
 import (
+	"bytes"
 	"context"
 	"os"
 	"path/filepath"
@@ -8,7 +9,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aliancn/logcmd/internal/charset"
+	"github.com/aliancn/logcmd/internal/execctx"
+	"github.com/aliancn/logcmd/internal/executor"
+	"github.com/aliancn/logcmd/internal/logmeta"
 	"github.com/aliancn/logcmd/internal/search"
+	"golang.org/x/text/encoding/simplifiedchinese"
 )
 
 func collectResults(t *testing.T, searcher *search.Searcher, ctx context.Context) ([]*search.SearchResult, error) {
@@ -427,3 +433,190 @@ func TestSearchNonExistentDirectory(t *testing.T) {
 		t.Error("Search() 应该对不存在的目录返回错误")
 	}
 }
+
+func TestSearchByStream(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logPath := filepath.Join(tmpDir, "test.log")
+	if err := os.WriteFile(logPath, []byte("build ok\nbuild failed\n"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+	records := `{"seq":1,"stream":"stdout","offset_ms":1.5,"line":"build ok"}
+{"seq":2,"stream":"stderr","offset_ms":2500,"line":"build failed"}
+`
+	if err := os.WriteFile(logPath+".jsonl", []byte(records), 0644); err != nil {
+		t.Fatalf("创建结构化记录失败: %v", err)
+	}
+	// 没有结构化记录的日志在按输出流搜索时应被跳过
+	if err := os.WriteFile(filepath.Join(tmpDir, "plain.log"), []byte("build plain\n"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	options := &search.SearchOptions{
+		LogDir:  tmpDir,
+		Keyword: "build",
+		Stream:  "stderr",
+	}
+
+	searcher, _ := search.New(options)
+	results, err := collectResults(t, searcher, context.Background())
+	if err != nil {
+		t.Fatalf("Search() 失败: %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("应该找到 1 个结果, got %d", len(results))
+	}
+
+	result := results[0]
+	if result.Line != "build failed" || result.Stream != "stderr" {
+		t.Errorf("结果不正确: %s/%q", result.Stream, result.Line)
+	}
+	if result.LineNum != 2 {
+		t.Errorf("LineNum = %d, want 2", result.LineNum)
+	}
+	if result.Offset != 2500*time.Millisecond {
+		t.Errorf("Offset = %v, want 2.5s", result.Offset)
+	}
+}
+
+func TestSearchWithContextFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logWithBranch := func(branch string) string {
+		return "\n" + strings.Repeat("#", 80) + "\n# LogCmd - 命令执行日志\n# 时间: 2024-01-15 10:00:00\n# 命令: make [build]\n" +
+			"# 用户: alice (uid 1000)\n# Git 分支: " + branch + "\n" + strings.Repeat("#", 80) + "\n\nbuild failed\n"
+	}
+	files := map[string]string{
+		"main.log":    logWithBranch("main"),
+		"feature.log": logWithBranch("feature"),
+		"legacy.log":  "build failed\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("创建测试日志文件失败: %v", err)
+		}
+	}
+
+	searcher, err := search.New(&search.SearchOptions{
+		LogDir:  tmpDir,
+		Keyword: "failed",
+		Context: execctx.Filter{Branch: "main"},
+	})
+	if err != nil {
+		t.Fatalf("New() 失败: %v", err)
+	}
+
+	results, err := collectResults(t, searcher, context.Background())
+	if err != nil {
+		t.Fatalf("Search() 失败: %v", err)
+	}
+	if len(results) != 1 || filepath.Base(results[0].FilePath) != "main.log" {
+		t.Errorf("只应在分支 main 的日志中找到结果, got %d 条", len(results))
+	}
+}
+
+func TestSearchRawLogContextFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// raw 日志的执行上下文记录在元数据文件中
+	logPath := filepath.Join(tmpDir, "raw.log")
+	header := "\n" + strings.Repeat("#", 80) + "\n# LogCmd - 命令执行日志\n# 时间: 2024-01-15 10:00:00\n# 命令: make [build]\n" +
+		"# Git 分支: main\n" + strings.Repeat("#", 80) + "\n\n"
+	if err := os.WriteFile(logPath, []byte("build failed\n"), 0644); err != nil {
+		t.Fatalf("创建测试日志文件失败: %v", err)
+	}
+	if err := os.WriteFile(logmeta.PathFor(logPath), []byte(header), 0644); err != nil {
+		t.Fatalf("创建元数据文件失败: %v", err)
+	}
+
+	for branch, want := range map[string]int{"main": 1, "feature": 0} {
+		searcher, err := search.New(&search.SearchOptions{
+			LogDir:  tmpDir,
+			Keyword: "failed",
+			Context: execctx.Filter{Branch: branch},
+		})
+		if err != nil {
+			t.Fatalf("New() 失败: %v", err)
+		}
+		results, err := collectResults(t, searcher, context.Background())
+		if err != nil {
+			t.Fatalf("Search() 失败: %v", err)
+		}
+		if len(results) != want {
+			t.Errorf("分支 %s: got %d 条结果, want %d", branch, len(results), want)
+			continue
+		}
+		if want == 1 && results[0].LineNum != 1 {
+			t.Errorf("raw 日志的行号应与命令输出一致, got %d", results[0].LineNum)
+		}
+	}
+}
+
+func TestSearchContextFilterJSONMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// 日志头部记录的分支与元数据文件不同时以元数据文件为准
+	logPath := filepath.Join(tmpDir, "make.log")
+	header := "\n" + strings.Repeat("#", 80) + "\n# LogCmd - 命令执行日志\n# 时间: 2024-01-15 10:00:00\n# 命令: make [build]\n" +
+		"# Git 分支: feature\n" + strings.Repeat("#", 80) + "\n\nbuild failed\n"
+	if err := os.WriteFile(logPath, []byte(header), 0644); err != nil {
+		t.Fatalf("创建测试日志文件失败: %v", err)
+	}
+	result := &executor.Result{Command: "make", Context: &execctx.Info{GitBranch: "main"}}
+	if err := logmeta.Write(logPath, &logmeta.Metadata{LogFile: logPath, Result: result}); err != nil {
+		t.Fatalf("写入元数据文件失败: %v", err)
+	}
+
+	for branch, want := range map[string]int{"main": 1, "feature": 0} {
+		searcher, err := search.New(&search.SearchOptions{
+			LogDir:  tmpDir,
+			Keyword: "failed",
+			Context: execctx.Filter{Branch: branch},
+		})
+		if err != nil {
+			t.Fatalf("New() 失败: %v", err)
+		}
+		results, err := collectResults(t, searcher, context.Background())
+		if err != nil {
+			t.Fatalf("Search() 失败: %v", err)
+		}
+		if len(results) != want {
+			t.Errorf("分支 %s: got %d 条结果, want %d", branch, len(results), want)
+		}
+	}
+}
+
+func TestSearchTranscodedLog(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// 模拟 --input-encoding auto 下 GBK 输出写入日志的过程
+	gbk, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte("开始构建\n错误: 找不到模块 foo\n构建结束\n"))
+	if err != nil {
+		t.Fatalf("GBK 编码失败: %v", err)
+	}
+	decoder, err := charset.NewDecoder(charset.Auto)
+	if err != nil {
+		t.Fatalf("NewDecoder() 失败: %v", err)
+	}
+	var logContent bytes.Buffer
+	w := decoder.NewWriter(&logContent)
+	w.Write(gbk)
+	w.Flush()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "gbk.log"), logContent.Bytes(), 0644); err != nil {
+		t.Fatalf("创建测试日志文件失败: %v", err)
+	}
+
+	searcher, err := search.New(&search.SearchOptions{LogDir: tmpDir, Keyword: "找不到"})
+	if err != nil {
+		t.Fatalf("New() 失败: %v", err)
+	}
+	results, err := collectResults(t, searcher, context.Background())
+	if err != nil {
+		t.Fatalf("Search() 失败: %v", err)
+	}
+	if len(results) != 1 || results[0].Line != "错误: 找不到模块 foo" {
+		t.Errorf("应该找到转换后的中文行, got %+v", results)
+	}
+}
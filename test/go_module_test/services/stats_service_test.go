@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aliancn/logcmd/internal/history"
 	"github.com/aliancn/logcmd/internal/model"
 	"github.com/aliancn/logcmd/internal/registry"
 	"github.com/aliancn/logcmd/internal/services"
@@ -241,3 +242,115 @@ func insertCommandHistory(t *testing.T, reg *registry.Registry, projectID int, l
 		t.Fatalf("插入 command_history 失败: %v", err)
 	}
 }
+
+func TestStatsServiceCountsTimeouts(t *testing.T) {
+	svc, reg, project, logDir := setupStatsServiceWithRegistry(t)
+	insertCommandHistory(t, reg, project.ID, logDir)
+
+	now := time.Now()
+	err := history.NewManager(reg.GetDB()).Record(&model.CommandHistory{
+		ProjectID:   project.ID,
+		Command:     "make nightly",
+		StartTime:   now,
+		EndTime:     now.Add(time.Minute),
+		DurationMs:  60000,
+		ExitCode:    -1,
+		Status:      "timeout",
+		Signal:      "SIGTERM",
+		LogFilePath: filepath.Join(logDir, "timeout.log"),
+		LogDate:     now.Format("2006-01-02"),
+		CreatedAt:   now,
+	})
+	if err != nil {
+		t.Fatalf("记录命令历史失败: %v", err)
+	}
+
+	report, err := svc.StatsForProject(context.Background(), project)
+	if err != nil {
+		t.Fatalf("StatsForProject() 失败: %v", err)
+	}
+
+	if report.TotalCommands != 2 || report.TimeoutCommands != 1 || report.FailedCommands != 0 {
+		t.Fatalf("超时应单独统计: total=%d timeout=%d failed=%d",
+			report.TotalCommands, report.TimeoutCommands, report.FailedCommands)
+	}
+}
+
+func TestStatsServiceCountsRetriedSuccess(t *testing.T) {
+	svc, reg, project, logDir := setupStatsServiceWithRegistry(t)
+
+	now := time.Now()
+	manager := history.NewManager(reg.GetDB())
+	for attempt, status := range []string{"failed", "success"} {
+		err := manager.Record(&model.CommandHistory{
+			ProjectID:    project.ID,
+			Command:      "npm install",
+			StartTime:    now,
+			EndTime:      now.Add(time.Second),
+			DurationMs:   1000,
+			Status:       status,
+			AttemptGroup: "group-1",
+			Attempt:      attempt + 1,
+			LogFilePath:  filepath.Join(logDir, "npm.log"),
+			LogDate:      now.Format("2006-01-02"),
+			CreatedAt:    now,
+		})
+		if err != nil {
+			t.Fatalf("记录命令历史失败: %v", err)
+		}
+	}
+
+	report, err := svc.StatsForProject(context.Background(), project)
+	if err != nil {
+		t.Fatalf("StatsForProject() 失败: %v", err)
+	}
+
+	if report.TotalCommands != 1 || report.SuccessCommands != 1 || report.FailedCommands != 0 {
+		t.Fatalf("重试组应只按最后一次尝试统计: total=%d success=%d failed=%d",
+			report.TotalCommands, report.SuccessCommands, report.FailedCommands)
+	}
+	if report.RetriedSuccess != 1 || report.RetriedAttempts != 1 {
+		t.Fatalf("应统计重试后成功的命令和被重试的尝试: retried=%d attempts=%d", report.RetriedSuccess, report.RetriedAttempts)
+	}
+}
+
+func TestStatsServiceCommandResources(t *testing.T) {
+	svc, reg, project, logDir := setupStatsServiceWithRegistry(t)
+	insertCommandHistory(t, reg, project.ID, logDir)
+
+	now := time.Now()
+	manager := history.NewManager(reg.GetDB())
+	for _, rss := range []int64{100 << 20, 300 << 20} {
+		err := manager.Record(&model.CommandHistory{
+			ProjectID:   project.ID,
+			Command:     "make build",
+			StartTime:   now,
+			EndTime:     now.Add(time.Second),
+			Status:      "success",
+			CPUUserMs:   1000,
+			CPUSystemMs: 500,
+			MaxRSSBytes: rss,
+			LogFilePath: filepath.Join(logDir, "make.log"),
+			LogDate:     now.Format("2006-01-02"),
+			CreatedAt:   now,
+
+			GroupPeakRSSBytes: 2 * rss,
+		})
+		if err != nil {
+			t.Fatalf("记录命令历史失败: %v", err)
+		}
+	}
+
+	report, err := svc.StatsForProject(context.Background(), project)
+	if err != nil {
+		t.Fatalf("StatsForProject() 失败: %v", err)
+	}
+
+	resource := report.CommandResources["make"]
+	if resource == nil || resource.CPUMs != 3000 || resource.PeakRSSBytes != 300<<20 || resource.GroupPeakRSSBytes != 600<<20 {
+		t.Fatalf("资源占用统计错误: %+v", resource)
+	}
+	if _, ok := report.CommandResources["echo"]; ok {
+		t.Error("没有资源数据的命令不应出现在资源占用中")
+	}
+}
@@ -0,0 +1,61 @@
+package shellcmd_test
+
+import (
+	"testing"
+
+	"github.com/aliancn/logcmd/internal/shellcmd"
+)
+
+func TestProgramName(t *testing.T) {
+	tests := []struct {
+		script string
+		want   string
+	}{
+		{"make", "make"},
+		{"make && make test | tee x", "make"},
+		{"cd sub && npm run build", "npm"},
+		{"export GOFLAGS=-mod=mod; go test ./...", "go"},
+		{"FOO=1 BAR='a b' ./build.sh --fast", "./build.sh"},
+		{"2>&1 >out.txt python3 train.py", "python3"},
+		{"> out.txt cargo build", "cargo"},
+		{"sudo -E env CI=1 timeout 10m nice -n 5 pytest -x", "pytest"},
+		{"time { make clean; make; }", "make"},
+		{"# 构建\ncd web\nyarn install", "yarn"},
+		{`echo "a && b" | grep a`, "echo"},
+		{"(cd sub; cmake ..) && ninja", "cmake"},
+		{"if true; then docker build .; fi", "docker"},
+		{"cd /tmp", "cd"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := shellcmd.ProgramName(tt.script); got != tt.want {
+			t.Errorf("ProgramName(%q) = %q, want %q", tt.script, got, tt.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("SHELL", "/bin/zsh")
+	if got := shellcmd.Resolve("/bin/bash"); got != "/bin/bash" {
+		t.Errorf("配置的 shell 应优先, got %q", got)
+	}
+	if got := shellcmd.Resolve(""); got != "/bin/zsh" {
+		t.Errorf("未配置时应使用 $SHELL, got %q", got)
+	}
+	t.Setenv("SHELL", "")
+	if got := shellcmd.Resolve(""); got != shellcmd.DefaultShell {
+		t.Errorf("没有 $SHELL 时应使用 %s, got %q", shellcmd.DefaultShell, got)
+	}
+}
+
+func TestScript(t *testing.T) {
+	if got := shellcmd.Script("make && make test", nil); got != "make && make test" {
+		t.Errorf("Script() = %q", got)
+	}
+	if got := shellcmd.Script("make", []string{"&&", "make", "test"}); got != "make && make test" {
+		t.Errorf("Script() = %q", got)
+	}
+	if got := shellcmd.DisplayLine("cd a\nmake"); got != `cd a\nmake` {
+		t.Errorf("DisplayLine() = %q", got)
+	}
+}
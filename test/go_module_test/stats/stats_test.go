@@ -2,11 +2,17 @@ package stats_test
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/aliancn/logcmd/internal/artifact"
+	"github.com/aliancn/logcmd/internal/execctx"
+	"github.com/aliancn/logcmd/internal/executor"
+	"github.com/aliancn/logcmd/internal/logmeta"
+	"github.com/aliancn/logcmd/internal/model"
 	"github.com/aliancn/logcmd/internal/stats"
 )
 
@@ -330,6 +336,51 @@ func TestAnalyzeSkipNonLogFiles(t *testing.T) {
 	}
 }
 
+func TestAnalyzeSkipsArtifacts(t *testing.T) {
+	logContent := func(exitCode int, status string) string {
+		return fmt.Sprintf(`
+################################################################################
+# LogCmd - 命令执行日志
+# 时间: 2024-01-15 10:00:00
+# 命令: make
+################################################################################
+
+================================================================================
+命令: make
+执行时长: 1s
+退出码: %d
+执行状态: %s
+================================================================================
+`, exitCode, status)
+	}
+
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "2024-01-15", "build.log")
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	if err := os.WriteFile(logPath, []byte(logContent(0, "成功")), 0644); err != nil {
+		t.Fatalf("创建测试日志文件失败: %v", err)
+	}
+
+	// 收集为产物的 .log 文件不是 logcmd 的日志
+	copied := filepath.Join(artifact.DirFor(logPath), "dist", "build.log")
+	if err := os.MkdirAll(filepath.Dir(copied), 0755); err != nil {
+		t.Fatalf("创建产物目录失败: %v", err)
+	}
+	if err := os.WriteFile(copied, []byte(logContent(2, "失败")), 0644); err != nil {
+		t.Fatalf("创建产物文件失败: %v", err)
+	}
+
+	result, err := stats.New(tmpDir).Analyze(context.Background())
+	if err != nil {
+		t.Fatalf("Analyze() 失败: %v", err)
+	}
+	if result.TotalCommands != 1 || result.FailedCommands != 0 {
+		t.Errorf("产物目录中的文件不应计入统计: total=%d failed=%d", result.TotalCommands, result.FailedCommands)
+	}
+}
+
 func TestPrintStats(t *testing.T) {
 	// 创建测试统计数据
 	testStats := &stats.Stats{
@@ -383,3 +434,445 @@ func TestPrintStatsWithEmptyData(t *testing.T) {
 
 	stats.PrintStats(emptyStats)
 }
+
+func TestAnalyzeCountsTimeoutsSeparately(t *testing.T) {
+	tmpDir := t.TempDir()
+	dateDir := filepath.Join(tmpDir, "2024-01-15")
+	os.MkdirAll(dateDir, 0755)
+
+	logContent := `
+################################################################################
+# LogCmd - 命令执行日志
+# 时间: 2024-01-15 10:00:00
+# 命令: make [nightly]
+################################################################################
+
+[logcmd] 命令执行超过 30m0s，发送 SIGTERM 终止命令
+
+================================================================================
+命令: make [nightly]
+开始时间: 2024-01-15 10:00:00
+结束时间: 2024-01-15 10:30:01
+执行时长: 30m1s
+退出码: -1
+执行状态: 超时
+终止信号: SIGTERM
+================================================================================
+`
+	if err := os.WriteFile(filepath.Join(dateDir, "timeout.log"), []byte(logContent), 0644); err != nil {
+		t.Fatalf("创建测试日志文件失败: %v", err)
+	}
+
+	result, err := stats.New(tmpDir).Analyze(context.Background())
+	if err != nil {
+		t.Fatalf("Analyze() 失败: %v", err)
+	}
+
+	if result.TotalCommands != 1 || result.TimeoutCommands != 1 {
+		t.Errorf("TotalCommands = %d, TimeoutCommands = %d, want 1/1", result.TotalCommands, result.TimeoutCommands)
+	}
+	if result.FailedCommands != 0 {
+		t.Errorf("超时不应计入失败, FailedCommands = %d", result.FailedCommands)
+	}
+	if day := result.DailyStats["2024-01-15"]; day == nil || day.Timeout != 1 {
+		t.Errorf("每日统计应记录超时: %+v", day)
+	}
+}
+
+func TestAnalyzeShellModeCommandName(t *testing.T) {
+	tmpDir := t.TempDir()
+	dateDir := filepath.Join(tmpDir, "2024-01-15")
+	os.MkdirAll(dateDir, 0755)
+
+	logContent := `
+################################################################################
+# LogCmd - 命令执行日志
+# 时间: 2024-01-15 10:00:00
+# 命令: cd web && npm run build | tee build.txt
+# Shell: /bin/bash
+################################################################################
+
+done
+
+================================================================================
+命令: cd web && npm run build | tee build.txt
+开始时间: 2024-01-15 10:00:00
+结束时间: 2024-01-15 10:00:05
+执行时长: 5s
+退出码: 0
+执行状态: 成功
+Shell: /bin/bash
+================================================================================
+`
+	if err := os.WriteFile(filepath.Join(dateDir, "npm.log"), []byte(logContent), 0644); err != nil {
+		t.Fatalf("创建测试日志文件失败: %v", err)
+	}
+
+	result, err := stats.New(tmpDir).Analyze(context.Background())
+	if err != nil {
+		t.Fatalf("Analyze() 失败: %v", err)
+	}
+	if result.CommandCounts["npm"] != 1 {
+		t.Errorf("shell 模式应按脚本中第一个执行的程序统计: %v", result.CommandCounts)
+	}
+}
+
+func TestAnalyzeCountsRetriedSuccess(t *testing.T) {
+	tmpDir := t.TempDir()
+	dateDir := filepath.Join(tmpDir, "2024-01-15")
+	os.MkdirAll(dateDir, 0755)
+
+	attemptLog := func(attempt int, status string, exitCode int) string {
+		return fmt.Sprintf(`
+################################################################################
+# LogCmd - 命令执行日志
+# 时间: 2024-01-15 10:00:00
+# 命令: npm [install]
+# 尝试: %d/3 (重试组 abc)
+################################################################################
+
+================================================================================
+命令: npm [install]
+开始时间: 2024-01-15 10:00:00
+结束时间: 2024-01-15 10:00:05
+执行时长: 5s
+退出码: %d
+执行状态: %s
+尝试: %d/3
+重试组: abc
+================================================================================
+`, attempt, exitCode, status, attempt)
+	}
+
+	files := map[string]string{
+		"npm.log":          attemptLog(1, "失败", 1),
+		"npm.attempt2.log": attemptLog(2, "成功", 0),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dateDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("创建测试日志文件失败: %v", err)
+		}
+	}
+
+	result, err := stats.New(tmpDir).Analyze(context.Background())
+	if err != nil {
+		t.Fatalf("Analyze() 失败: %v", err)
+	}
+
+	if result.TotalCommands != 1 || result.SuccessCommands != 1 || result.FailedCommands != 0 {
+		t.Errorf("重试组应只按最后一次尝试统计: total=%d success=%d failed=%d",
+			result.TotalCommands, result.SuccessCommands, result.FailedCommands)
+	}
+	if result.RetriedSuccess != 1 || result.RetriedAttempts != 1 {
+		t.Errorf("RetriedSuccess = %d, RetriedAttempts = %d, want 1, 1", result.RetriedSuccess, result.RetriedAttempts)
+	}
+	if result.CommandCounts["npm"] != 1 || result.ExitCodes[1] != 0 {
+		t.Errorf("被重试的尝试不应计入分布: %v %v", result.CommandCounts, result.ExitCodes)
+	}
+}
+
+func TestAnalyzeCommandResources(t *testing.T) {
+	tmpDir := t.TempDir()
+	dateDir := filepath.Join(tmpDir, "2024-01-15")
+	os.MkdirAll(dateDir, 0755)
+
+	footer := func(cpuUser, cpuSys, maxRSS string) string {
+		return fmt.Sprintf(`
+################################################################################
+# LogCmd - 命令执行日志
+# 时间: 2024-01-15 10:00:00
+# 命令: make [build]
+################################################################################
+
+================================================================================
+命令: make [build]
+开始时间: 2024-01-15 10:00:00
+结束时间: 2024-01-15 10:00:05
+执行时长: 5s
+退出码: 0
+执行状态: 成功
+CPU 时间: 用户 %s, 系统 %s
+最大内存: %s
+块 I/O: 读 0, 写 8
+上下文切换: 自愿 10, 非自愿 2
+================================================================================
+`, cpuUser, cpuSys, maxRSS)
+	}
+
+	files := map[string]string{
+		"a.log": footer("1h", "30m0s", "512.0 MiB"),
+		"b.log": footer("20m0s", "10m0s", "1.5 GiB"),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dateDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("创建测试日志文件失败: %v", err)
+		}
+	}
+
+	result, err := stats.New(tmpDir).Analyze(context.Background())
+	if err != nil {
+		t.Fatalf("Analyze() 失败: %v", err)
+	}
+
+	resource := result.CommandResources["make"]
+	if resource == nil {
+		t.Fatal("应统计 make 的资源占用")
+	}
+	if cpu := time.Duration(resource.CPUMs) * time.Millisecond; cpu.Hours() != 2 {
+		t.Errorf("CPU 小时 = %v, want 2", cpu.Hours())
+	}
+	if resource.PeakRSSBytes != 1536<<20 {
+		t.Errorf("PeakRSSBytes = %d, want %d", resource.PeakRSSBytes, int64(1536<<20))
+	}
+}
+
+func TestAnalyzeResourcesFromHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	dateDir := filepath.Join(tmpDir, "2024-01-15")
+	os.MkdirAll(dateDir, 0755)
+
+	// 旧日志没有 JSON 元数据，尾部的内存是格式化后的近似值
+	logPath := filepath.Join(dateDir, "make.log")
+	content := `
+================================================================================
+命令: make [build]
+执行时长: 5s
+退出码: 0
+执行状态: 成功
+CPU 时间: 用户 1s, 系统 0s
+最大内存: 1.5 GiB
+采样峰值内存: 3.0 GiB
+================================================================================
+`
+	if err := os.WriteFile(logPath, []byte(content), 0644); err != nil {
+		t.Fatalf("创建测试日志文件失败: %v", err)
+	}
+
+	analyzer := stats.New(tmpDir)
+	result, err := analyzer.Analyze(context.Background())
+	if err != nil {
+		t.Fatalf("Analyze() 失败: %v", err)
+	}
+	resource := result.CommandResources["make"]
+	if resource == nil || resource.PeakRSSBytes != 1536<<20 || resource.GroupPeakRSSBytes != 3072<<20 {
+		t.Fatalf("没有命令历史时应解析尾部: %+v", resource)
+	}
+
+	analyzer = stats.New(tmpDir)
+	analyzer.SetHistory(func(path string) *model.CommandHistory {
+		if path != logPath {
+			return nil
+		}
+		return &model.CommandHistory{CPUUserMs: 1234, CPUSystemMs: 6, MaxRSSBytes: 1610612737, GroupPeakRSSBytes: 3221225473}
+	})
+	result, err = analyzer.Analyze(context.Background())
+	if err != nil {
+		t.Fatalf("Analyze() 失败: %v", err)
+	}
+	resource = result.CommandResources["make"]
+	if resource == nil || resource.CPUMs != 1240 || resource.PeakRSSBytes != 1610612737 || resource.GroupPeakRSSBytes != 3221225473 {
+		t.Errorf("资源占用应来自命令历史: %+v", resource)
+	}
+}
+
+func TestAnalyzeWithContextFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	dateDir := filepath.Join(tmpDir, "2024-01-15")
+	os.MkdirAll(dateDir, 0755)
+
+	branchLog := func(branch string) string {
+		return fmt.Sprintf(`
+################################################################################
+# LogCmd - 命令执行日志
+# 时间: 2024-01-15 10:00:00
+# 命令: go [test]
+# 用户: alice (uid 1000)
+# 主机: build-01
+# Git 分支: %s
+# Git 提交: 0123456789abcdef (有未提交修改)
+################################################################################
+
+================================================================================
+命令: go [test]
+开始时间: 2024-01-15 10:00:00
+结束时间: 2024-01-15 10:00:05
+执行时长: 5s
+退出码: 0
+执行状态: 成功
+================================================================================
+`, branch)
+	}
+
+	files := map[string]string{
+		"main1.log":   branchLog("main"),
+		"main2.log":   branchLog("main"),
+		"feature.log": branchLog("feature/x"),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dateDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("创建测试日志文件失败: %v", err)
+		}
+	}
+
+	analyzer := stats.New(tmpDir)
+	analyzer.SetFilter(execctx.Filter{Branch: "main", User: "alice"})
+	result, err := analyzer.Analyze(context.Background())
+	if err != nil {
+		t.Fatalf("Analyze() 失败: %v", err)
+	}
+	if result.TotalCommands != 2 {
+		t.Errorf("按分支筛选后 TotalCommands = %d, want 2", result.TotalCommands)
+	}
+	if result.DailyStats["2024-01-15"] == nil {
+		t.Error("解析执行上下文后仍应识别日志日期")
+	}
+
+	analyzer = stats.New(tmpDir)
+	analyzer.SetFilter(execctx.Filter{Host: "build-02"})
+	result, err = analyzer.Analyze(context.Background())
+	if err != nil {
+		t.Fatalf("Analyze() 失败: %v", err)
+	}
+	if result.TotalCommands != 0 {
+		t.Errorf("主机不匹配时 TotalCommands = %d, want 0", result.TotalCommands)
+	}
+}
+
+func TestAnalyzeRawLogWithMetadataFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	dateDir := filepath.Join(tmpDir, "2024-01-15")
+	os.MkdirAll(dateDir, 0755)
+
+	// raw 日志只包含命令输出，输出中类似尾部元数据的行不应被当作元数据
+	logPath := filepath.Join(dateDir, "jq_100000.log")
+	output := "命令: fake [output]\n退出码: 99\n{\"ok\":true}\n"
+	meta := `
+################################################################################
+# LogCmd - 命令执行日志
+# 时间: 2024-01-15 10:00:00
+# 命令: jq [.]
+# 用户: alice (uid 1000)
+################################################################################
+
+
+================================================================================
+命令: jq [.]
+开始时间: 2024-01-15 10:00:00
+结束时间: 2024-01-15 10:00:02
+执行时长: 2s
+退出码: 1
+执行状态: 失败
+================================================================================
+`
+	if err := os.WriteFile(logPath, []byte(output), 0644); err != nil {
+		t.Fatalf("创建测试日志文件失败: %v", err)
+	}
+	if err := os.WriteFile(logmeta.PathFor(logPath), []byte(meta), 0644); err != nil {
+		t.Fatalf("创建元数据文件失败: %v", err)
+	}
+
+	analyzer := stats.New(tmpDir)
+	analyzer.SetFilter(execctx.Filter{User: "alice"})
+	result, err := analyzer.Analyze(context.Background())
+	if err != nil {
+		t.Fatalf("Analyze() 失败: %v", err)
+	}
+	if result.TotalCommands != 1 || result.FailedCommands != 1 {
+		t.Fatalf("应从元数据文件统计 1 条失败命令, got total=%d failed=%d", result.TotalCommands, result.FailedCommands)
+	}
+	if result.CommandCounts["jq"] != 1 || result.ExitCodes[1] != 1 {
+		t.Errorf("命令与退出码应来自元数据文件: %v %v", result.CommandCounts, result.ExitCodes)
+	}
+	if result.DailyStats["2024-01-15"] == nil {
+		t.Error("应从元数据文件的头部识别日志日期")
+	}
+}
+
+func TestAnalyzePrefersJSONMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	dateDir := filepath.Join(tmpDir, "2024-01-15")
+	os.MkdirAll(dateDir, 0755)
+
+	// 命令输出的最后几行与尾部格式相同，解析文本会得到错误的结果
+	logPath := filepath.Join(dateDir, "sh_100000.log")
+	content := `命令: fake
+退出码: 0
+执行状态: 成功
+执行时长: 1h
+`
+	if err := os.WriteFile(logPath, []byte(content), 0644); err != nil {
+		t.Fatalf("创建测试日志文件失败: %v", err)
+	}
+
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.Local)
+	result := &executor.Result{
+		Command:   "/bin/sh",
+		Args:      []string{"-c", "make build && make test"},
+		Script:    "make build && make test",
+		StartTime: start,
+		Duration:  3 * time.Second,
+		ExitCode:  2,
+		Status:    executor.StatusFailed,
+		Attempt:   1,
+		Usage:     &executor.Usage{UserCPU: 2 * time.Second, SystemCPU: time.Second, MaxRSS: 4 << 20},
+		Context:   &execctx.Info{User: "alice"},
+	}
+	if err := logmeta.Write(logPath, &logmeta.Metadata{LogFile: logPath, Result: result}); err != nil {
+		t.Fatalf("写入元数据文件失败: %v", err)
+	}
+
+	analyzer := stats.New(tmpDir)
+	analyzer.SetFilter(execctx.Filter{User: "alice"})
+	got, err := analyzer.Analyze(context.Background())
+	if err != nil {
+		t.Fatalf("Analyze() 失败: %v", err)
+	}
+	if got.TotalCommands != 1 || got.FailedCommands != 1 {
+		t.Fatalf("应从元数据文件统计 1 条失败命令, got total=%d failed=%d", got.TotalCommands, got.FailedCommands)
+	}
+	if got.CommandCounts["make"] != 1 || got.ExitCodes[2] != 1 {
+		t.Errorf("命令与退出码应来自元数据文件: %v %v", got.CommandCounts, got.ExitCodes)
+	}
+	if got.TotalDuration != 3*time.Second {
+		t.Errorf("TotalDuration = %v, want 3s", got.TotalDuration)
+	}
+	if day := got.DailyStats["2024-01-15"]; day == nil || day.Failed != 1 {
+		t.Errorf("每日统计应使用开始时间的日期: %+v", got.DailyStats)
+	}
+	resource := got.CommandResources["make"]
+	if resource == nil || resource.CPUMs != 3000 || resource.PeakRSSBytes != 4<<20 {
+		t.Errorf("资源占用 = %+v", resource)
+	}
+}
+
+func TestFromHistory(t *testing.T) {
+	records := []*model.CommandHistory{
+		{CommandName: "make", Status: "success", DurationMs: 1000, LogDate: "2024-01-15", Attempt: 2, CPUUserMs: 600, CPUSystemMs: 400, MaxRSSBytes: 1 << 20},
+		{CommandName: "make", Status: "failed", ExitCode: 2, DurationMs: 3000, LogDate: "2024-01-15"},
+		{CommandName: "sleep", Status: "timeout", ExitCode: -1, DurationMs: 2000, LogDate: "2024-01-16"},
+	}
+
+	result := stats.FromHistory(records, "demo")
+	if result.ProjectName != "demo" || result.Source != stats.SourceDatabase {
+		t.Errorf("ProjectName/Source = %q/%q", result.ProjectName, result.Source)
+	}
+	if result.TotalCommands != 3 || result.SuccessCommands != 1 || result.FailedCommands != 1 || result.TimeoutCommands != 1 {
+		t.Errorf("total=%d success=%d failed=%d timeout=%d",
+			result.TotalCommands, result.SuccessCommands, result.FailedCommands, result.TimeoutCommands)
+	}
+	if result.RetriedSuccess != 1 {
+		t.Errorf("RetriedSuccess = %d, want 1", result.RetriedSuccess)
+	}
+	if result.CommandCounts["make"] != 2 || result.ExitCodes[2] != 1 {
+		t.Errorf("CommandCounts = %v, ExitCodes = %v", result.CommandCounts, result.ExitCodes)
+	}
+	if result.AvgDuration != 2*time.Second || result.MinDuration != time.Second || result.MaxDuration != 3*time.Second {
+		t.Errorf("avg=%v min=%v max=%v", result.AvgDuration, result.MinDuration, result.MaxDuration)
+	}
+	if resource := result.CommandResources["make"]; resource == nil || resource.CPUMs != 1000 {
+		t.Errorf("make 的资源占用 = %+v", resource)
+	}
+	if len(result.DailyStats) != 2 {
+		t.Errorf("DailyStats 应包含 2 天, got %d", len(result.DailyStats))
+	}
+}
@@ -266,3 +266,110 @@ func TestManager_MarkStopped(t *testing.T) {
 		t.Error("重复停止已结束的任务应该返回错误")
 	}
 }
+
+func TestManager_RunOptionsRoundTrip(t *testing.T) {
+	manager, db := setupTaskManager(t)
+	defer db.Close()
+
+	task := &model.Task{
+		Command:     "npm",
+		CommandArgs: []string{"test"},
+		WorkingDir:  t.TempDir(),
+		LogDir:      t.TempDir(),
+		OptionsJSON: `{"pty":true}`,
+	}
+	created, err := manager.Create(task)
+	if err != nil {
+		t.Fatalf("创建任务失败: %v", err)
+	}
+
+	loaded, err := manager.Get(created.ID)
+	if err != nil {
+		t.Fatalf("Get() 失败: %v", err)
+	}
+	if loaded.OptionsJSON != `{"pty":true}` {
+		t.Errorf("OptionsJSON = %s, want {\"pty\":true}", loaded.OptionsJSON)
+	}
+}
+
+func TestManager_ProcessGroup(t *testing.T) {
+	manager, db := setupTaskManager(t)
+	defer db.Close()
+
+	created, err := manager.Create(&model.Task{Command: "make", WorkingDir: t.TempDir(), LogDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("创建任务失败: %v", err)
+	}
+
+	if err := manager.UpdateProcessGroup(created.ID, 4321); err != nil {
+		t.Fatalf("UpdateProcessGroup() 失败: %v", err)
+	}
+
+	loaded, err := manager.Get(created.ID)
+	if err != nil {
+		t.Fatalf("Get() 失败: %v", err)
+	}
+	if loaded.ProcessGroup == nil || *loaded.ProcessGroup != 4321 {
+		t.Fatalf("ProcessGroup = %v, want 4321", loaded.ProcessGroup)
+	}
+
+	if err := manager.MarkCompletion(created.ID, model.TaskStatusSuccess, 0, "", ""); err != nil {
+		t.Fatalf("MarkCompletion() 失败: %v", err)
+	}
+	loaded, err = manager.Get(created.ID)
+	if err != nil {
+		t.Fatalf("Get() 失败: %v", err)
+	}
+	if loaded.ProcessGroup != nil {
+		t.Errorf("任务结束后应清除进程组, got %d", *loaded.ProcessGroup)
+	}
+}
+
+func TestManager_TerminationReason(t *testing.T) {
+	manager, db := setupTaskManager(t)
+	defer db.Close()
+
+	created := createTask(t, manager, t.TempDir(), t.TempDir(), "make", nil)
+
+	if err := manager.UpdateTerminationReason(created.ID, "idle-timeout"); err != nil {
+		t.Fatalf("UpdateTerminationReason() 失败: %v", err)
+	}
+	if err := manager.MarkCompletion(created.ID, model.TaskStatusTimeout, -1, "", "任务无输出"); err != nil {
+		t.Fatalf("MarkCompletion() 失败: %v", err)
+	}
+
+	loaded, err := manager.Get(created.ID)
+	if err != nil {
+		t.Fatalf("Get() 失败: %v", err)
+	}
+	if loaded.Reason != "idle-timeout" {
+		t.Errorf("Reason = %q, want idle-timeout", loaded.Reason)
+	}
+	if loaded.Status != model.TaskStatusTimeout {
+		t.Errorf("Status = %q, want %q", loaded.Status, model.TaskStatusTimeout)
+	}
+}
+
+func TestManager_ExecutionContext(t *testing.T) {
+	manager, db := setupTaskManager(t)
+	defer db.Close()
+
+	task := &model.Task{
+		Command:     "make",
+		WorkingDir:  t.TempDir(),
+		LogDir:      t.TempDir(),
+		ContextJSON: `{"user":"alice","git_branch":"main"}`,
+	}
+	created, err := manager.Create(task)
+	if err != nil {
+		t.Fatalf("创建任务失败: %v", err)
+	}
+
+	loaded, err := manager.Get(created.ID)
+	if err != nil {
+		t.Fatalf("Get() 失败: %v", err)
+	}
+	if loaded.ContextJSON != task.ContextJSON {
+		t.Errorf("ContextJSON = %q, want %q", loaded.ContextJSON, task.ContextJSON)
+	}
+}
@@ -0,0 +1,159 @@
+package transform_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aliancn/logcmd/internal/executor"
+	"github.com/aliancn/logcmd/internal/transform"
+)
+
+type flushBuffer struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (b *flushBuffer) Flush() error {
+	b.flushes++
+	return nil
+}
+
+// apply 构造转换链，按 chunk 大小分多次写入 input，返回写入日志的内容
+func apply(t *testing.T, names []string, opts transform.Options, input string, chunk int) string {
+	t.Helper()
+	filters, err := transform.Chain(names, opts)
+	if err != nil {
+		t.Fatalf("Chain() 失败: %v", err)
+	}
+
+	out := &flushBuffer{}
+	var w executor.FlushWriter = out
+	for i := len(filters) - 1; i >= 0; i-- {
+		w = filters[i](w)
+	}
+
+	data := []byte(input)
+	for len(data) > 0 {
+		n := chunk
+		if n > len(data) {
+			n = len(data)
+		}
+		if _, err := w.Write(data[:n]); err != nil {
+			t.Fatalf("Write() 失败: %v", err)
+		}
+		data = data[n:]
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() 失败: %v", err)
+	}
+	if out.flushes == 0 {
+		t.Error("Flush 未传递到下游")
+	}
+	return out.String()
+}
+
+func TestStripANSI(t *testing.T) {
+	input := "\x1b[1;32mPASS\x1b[0m ok\n\x1b]0;title\x07\x1b[2Kdone\n"
+	for _, chunk := range []int{1, 3, 1024} {
+		got := apply(t, []string{transform.StripANSI}, transform.Options{}, input, chunk)
+		if want := "PASS ok\ndone\n"; got != want {
+			t.Errorf("chunk=%d: got %q, want %q", chunk, got, want)
+		}
+	}
+}
+
+func TestCollapseCR(t *testing.T) {
+	input := "downloading 10%\rdownloading 50%\rdownloading 100%\nwindows line\r\nlast\r"
+	got := apply(t, []string{transform.CollapseCR}, transform.Options{}, input, 5)
+	if want := "downloading 100%\nwindows line\nlast"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCompactRepeats(t *testing.T) {
+	input := "start\nretrying\nretrying\nretrying\nok\nend\nend\n"
+	got := apply(t, []string{transform.CompactRepeats}, transform.Options{}, input, 4)
+	want := "start\nretrying\n[logcmd] 上一行重复了 2 次\nok\nend\n[logcmd] 上一行重复了 1 次\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDropLines(t *testing.T) {
+	opts := transform.Options{DropPatterns: []string{`^Downloading `, `^\s*$`}}
+	input := "Downloading a.jar\nBuilding\n\nDownloading b.jar\nDone\n"
+	got := apply(t, []string{transform.DropLines}, opts, input, 7)
+	if want := "Building\nDone\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDetectBinary(t *testing.T) {
+	binary := strings.Repeat("\x00\x01\x02\xff", 100)
+	input := "header\n" + binary + "\ntrailer\n"
+	got := apply(t, []string{transform.DetectBinary}, transform.Options{}, input, 64)
+	want := "header\n[logcmd] 省略了 401 字节二进制输出\ntrailer\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	text := "中文输出\t\x1b[31mred\x1b[0m\n"
+	if got := apply(t, []string{transform.DetectBinary}, transform.Options{}, text, 64); got != text {
+		t.Errorf("文本被误判为二进制: %q", got)
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	// 先去掉颜色序列再合并重复行，颜色不同但内容相同的行也会被合并
+	names := []string{transform.StripANSI, transform.CollapseCR, transform.CompactRepeats}
+	input := "\x1b[33mwaiting\x1b[0m\n\x1b[31mwaiting\x1b[0m\n50%\r100%\n"
+	got := apply(t, names, transform.Options{}, input, 2)
+	want := "waiting\n[logcmd] 上一行重复了 1 次\n100%\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestChainInvalid(t *testing.T) {
+	if _, err := transform.Chain([]string{"unknown"}, transform.Options{}); err == nil {
+		t.Error("未知转换应返回错误")
+	}
+	if _, err := transform.Chain([]string{transform.DropLines}, transform.Options{DropPatterns: []string{"("}}); err == nil {
+		t.Error("无效正则应返回错误")
+	}
+}
+
+func TestLongLineWithoutNewline(t *testing.T) {
+	input := strings.Repeat("x", 200*1024)
+	got := apply(t, []string{transform.StripANSI}, transform.Options{}, input, 32*1024)
+	if got != input {
+		t.Errorf("长行内容不一致: len=%d, want %d", len(got), len(input))
+	}
+}
+
+func TestSyncKeepsState(t *testing.T) {
+	filters, err := transform.Chain([]string{transform.CompactRepeats}, transform.Options{})
+	if err != nil {
+		t.Fatalf("Chain() 失败: %v", err)
+	}
+	out := &flushBuffer{}
+	w := filters[0](out)
+
+	w.Write([]byte("a\na\na\npar"))
+	if err := w.(executor.Syncer).Sync(); err != nil {
+		t.Fatalf("Sync() 失败: %v", err)
+	}
+	if got, want := out.String(), "a\n[logcmd] 上一行重复了 2 次\n"; got != want {
+		t.Errorf("Sync() 应写出重复提示但保留未结束的行, got %q, want %q", got, want)
+	}
+	if out.flushes != 0 {
+		t.Error("Sync() 不应结束下游的输出")
+	}
+
+	w.Write([]byte("tial\npartial\n"))
+	w.Flush()
+	if got, want := out.String(), "a\n[logcmd] 上一行重复了 2 次\npartial\n[logcmd] 上一行重复了 1 次\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
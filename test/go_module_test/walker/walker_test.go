@@ -83,6 +83,29 @@ func TestWalkerFilterIsApplied(t *testing.T) {
 	}
 }
 
+func TestWalkerSkipDirs(t *testing.T) {
+	root := t.TempDir()
+	createFiles(t, root, "a.log", "day/b.log", "day/artifacts/build/c.log")
+
+	w, err := walker.New(walker.Options{Root: root, SkipDirs: []string{"artifacts"}})
+	if err != nil {
+		t.Fatalf("New() 失败: %v", err)
+	}
+
+	var count int32
+	err = w.Walk(context.Background(), func(ctx context.Context, path string, info os.FileInfo) error {
+		atomic.AddInt32(&count, 1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() 失败: %v", err)
+	}
+
+	if count != 2 {
+		t.Fatalf("跳过的目录中的文件不应处理: count = %d", count)
+	}
+}
+
 func TestWalkerStopsOnProcessorError(t *testing.T) {
 	root := t.TempDir()
 	createFiles(t, root, "fail.log", "other.log")